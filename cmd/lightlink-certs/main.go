@@ -0,0 +1,215 @@
+// Command lightlink-certs creates the LightLink CA and issues the NATS server
+// and client certificates.
+//
+//	lightlink-certs init   [-ca ./ca] [-name "LightLink CA"] [-days 3650]
+//	lightlink-certs server [-ca ./ca] [-out .] [-name nats-server] [-host nats.example.com,10.0.0.5] [-days 730] [-force]
+//	lightlink-certs client [-ca ./ca] [-out .] [-name math-service] [-days 730] [-force]
+//	lightlink-certs renew  [-ca ./ca] (-name math-service | -within 30) [-days 730]
+//	lightlink-certs list   [-ca ./ca] [-all]
+//
+// Server certificates are written to <out>/nats-server and client
+// certificates to <out>/client, where the SDKs discover them. Use one -out
+// directory per service; replacing the certificate of another name needs -force.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/certs"
+	"github.com/LiteHomeLab/light_link/sdk/go/client"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+)
+
+const usage = `Usage: lightlink-certs <command> [flags]
+
+Commands:
+  init     create the CA
+  server   issue the NATS server certificate into <out>/nats-server
+  client   issue a client certificate into <out>/client
+  renew    reissue certificates by name or expiry
+  list     list issued certificates
+
+Run 'lightlink-certs <command> -h' for the flags of a command.
+`
+
+const day = 24 * time.Hour
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "init":
+		err = runInit(os.Args[2:])
+	case "server":
+		err = runIssue(types.ServerCerts, os.Args[2:])
+	case "client":
+		err = runIssue(types.ClientCerts, os.Args[2:])
+	case "renew":
+		err = runRenew(os.Args[2:])
+	case "list":
+		err = runList(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func runInit(args []string) error {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	caDir := fs.String("ca", "./ca", "CA directory")
+	name := fs.String("name", certs.DefaultCAName, "CA common name")
+	days := fs.Int("days", int(certs.DefaultCAValidity/day), "validity in days")
+	fs.Parse(args)
+
+	ca, err := certs.InitCA(*caDir, *name, time.Duration(*days)*day)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created CA %q in %s, valid until %s\n", ca.Certificate().Subject.CommonName, *caDir, ca.Certificate().NotAfter.Format("2006-01-02"))
+	fmt.Println("Keep ca.key private; it signs every certificate.")
+	return nil
+}
+
+func runIssue(kind types.CertKind, args []string) error {
+	fs := flag.NewFlagSet(string(kind), flag.ExitOnError)
+	caDir := fs.String("ca", "./ca", "CA directory")
+	outDir := fs.String("out", ".", "directory receiving nats-server/ or client/")
+	defaultName := certs.DefaultClientName
+	if kind == types.ServerCerts {
+		defaultName = types.DefaultServerName
+	}
+	name := fs.String("name", defaultName, "common name; for clients the service name used as NATS user")
+	days := fs.Int("days", int(certs.DefaultValidity/day), "validity in days")
+	force := fs.Bool("force", false, "replace the certificate of another name in the output directory")
+	var hosts *string
+	if kind == types.ServerCerts {
+		hosts = fs.String("host", "", "comma separated DNS names and IPs clients connect to")
+	}
+	fs.Parse(args)
+
+	ca, err := certs.LoadCA(*caDir)
+	if err != nil {
+		return err
+	}
+	req := certs.Request{Kind: kind, Name: *name, Validity: time.Duration(*days) * day, OutDir: *outDir, Force: *force}
+	if hosts != nil {
+		req.Hosts = splitList(*hosts)
+	}
+	issued, err := ca.Issue(req)
+	if err != nil {
+		return err
+	}
+	printIssued(issued)
+	return nil
+}
+
+func runRenew(args []string) error {
+	fs := flag.NewFlagSet("renew", flag.ExitOnError)
+	caDir := fs.String("ca", "./ca", "CA directory")
+	name := fs.String("name", "", "renew the certificates with this common name")
+	within := fs.Int("within", 0, "renew the certificates expiring within this many days")
+	days := fs.Int("days", 0, "validity in days; defaults to the validity of the renewed certificate")
+	fs.Parse(args)
+
+	if (*name == "") == (*within == 0) {
+		return errors.New("renew needs either -name or -within")
+	}
+	ca, err := certs.LoadCA(*caDir)
+	if err != nil {
+		return err
+	}
+
+	validity := time.Duration(*days) * day
+	var renewed []*certs.Issued
+	if *name != "" {
+		renewed, err = ca.Renew(*name, validity)
+	} else {
+		renewed, err = ca.RenewExpiring(time.Duration(*within)*day, validity)
+	}
+	for _, issued := range renewed {
+		printIssued(issued)
+	}
+	if err != nil {
+		return err
+	}
+	if len(renewed) == 0 {
+		fmt.Println("No certificates to renew")
+	}
+	return nil
+}
+
+func runList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	caDir := fs.String("ca", "./ca", "CA directory")
+	all := fs.Bool("all", false, "include replaced certificates")
+	fs.Parse(args)
+
+	ca, err := certs.LoadCA(*caDir)
+	if err != nil {
+		return err
+	}
+	entries, err := ca.List()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tKIND\tSERIAL\tEXPIRES\tSTATUS\tDIR")
+	for _, entry := range entries {
+		if !entry.Current() && !*all {
+			continue
+		}
+		status := "valid"
+		switch {
+		case !entry.Current():
+			status = "replaced"
+		case entry.Expired():
+			status = "expired"
+		case time.Until(entry.NotAfter) < client.DefaultCertExpiryWarning:
+			status = "expiring"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", entry.Name, entry.Kind, shortSerial(entry.Serial),
+			entry.NotAfter.Format("2006-01-02"), status, entry.Dir)
+	}
+	return w.Flush()
+}
+
+func printIssued(issued *certs.Issued) {
+	fmt.Printf("Issued %s certificate %q (serial %s) into %s, valid until %s\n",
+		issued.Kind, issued.Name, shortSerial(issued.Serial), issued.Dir, issued.NotAfter.Format("2006-01-02"))
+}
+
+func shortSerial(serial string) string {
+	if len(serial) > 12 {
+		return serial[:12]
+	}
+	return serial
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -1,14 +1,33 @@
 package examples
 
-import "os"
+import (
+    "log"
+    "os"
+
+    "github.com/LiteHomeLab/light_link/sdk/go/types"
+)
 
 // Config holds example configuration
 type Config struct {
     NATSURL string
 }
 
-// GetConfig returns the configuration from environment or default values
+// GetConfig returns the configuration from the config file named by
+// LIGHTLINK_CONFIG, the LIGHTLINK_NATS_URL or NATS_URL environment variables,
+// or default values. See lightlink.example.yaml for the config file format.
+// A config file that does not load ends the program.
 func GetConfig() *Config {
+    if path := os.Getenv("LIGHTLINK_CONFIG"); path != "" {
+        cfg, err := types.LoadConfig(path)
+        if err != nil {
+            log.Fatalf("Failed to load LIGHTLINK_CONFIG: %v", err)
+        }
+        return &Config{NATSURL: cfg.NATSURL}
+    }
+    if cfg, err := types.LoadConfig(""); err == nil {
+        return &Config{NATSURL: cfg.NATSURL}
+    }
+
     url := os.Getenv("NATS_URL")
     if url == "" {
         url = "nats://172.18.200.47:4222"
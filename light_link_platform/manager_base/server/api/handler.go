@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/LiteHomeLab/light_link/light_link_platform/manager_base/server/auth"
 	"github.com/LiteHomeLab/light_link/light_link_platform/manager_base/server/manager"
@@ -52,6 +53,10 @@ func (h *Handler) Routes() http.Handler {
 
 	// Call endpoint
 	mux.HandleFunc("/api/call", h.withAuth(h.handleCall))
+	mux.HandleFunc("/api/call/all", h.withAuth(h.handleCallAll))
+
+	// Trace endpoint
+	mux.HandleFunc("/api/traces/", h.withAuth(h.handleTrace))
 
 	// Instance endpoints
 	mux.HandleFunc("/api/instances", h.withAuth(h.handleInstances))
@@ -413,9 +418,10 @@ func (h *Handler) handleCall(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Service string                 `json:"service"`
-		Method  string                 `json:"method"`
-		Params  map[string]interface{} `json:"params"`
+		Service     string                 `json:"service"`
+		Method      string                 `json:"method"`
+		Params      map[string]interface{} `json:"params"`
+		InstanceKey string                 `json:"instance_key,omitempty"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -423,6 +429,12 @@ func (h *Handler) handleCall(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Call one specific instance if requested
+	if req.InstanceKey != "" {
+		h.handleInstanceCall(w, req.InstanceKey, req.Method, req.Params)
+		return
+	}
+
 	// Check if service is online
 	status, err := h.db.GetServiceStatus(req.Service)
 	if err != nil {
@@ -445,6 +457,107 @@ func (h *Handler) handleCall(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(result)
 }
 
+// handleInstanceCall calls a method on one specific service instance
+func (h *Handler) handleInstanceCall(w http.ResponseWriter, instanceKey, method string, params map[string]interface{}) {
+	instance, err := h.controller.GetInstance(instanceKey)
+	if err != nil {
+		sendJSONError(w, http.StatusServiceUnavailable, "Instance not found")
+		return
+	}
+	if !instance.Online {
+		sendJSONError(w, http.StatusServiceUnavailable, "Instance is offline")
+		return
+	}
+
+	result, err := h.manager.CallInstanceMethod(instanceKey, method, params)
+	if err != nil {
+		sendJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
+
+// handleCallAll calls a method on every online instance of a service and returns all replies
+func (h *Handler) handleCallAll(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !auth.IsAdmin(r) {
+		sendJSONError(w, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	var req struct {
+		Service   string                 `json:"service"`
+		Method    string                 `json:"method"`
+		Params    map[string]interface{} `json:"params"`
+		TimeoutMs int                    `json:"timeout_ms,omitempty"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+
+	// Stop collecting once every online instance has replied
+	instances, err := h.controller.ListInstances(req.Service)
+	if err != nil {
+		sendJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	expected := 0
+	for _, inst := range instances {
+		if inst.Online {
+			expected++
+		}
+	}
+	if expected == 0 {
+		sendJSONError(w, http.StatusServiceUnavailable, "No online instances")
+		return
+	}
+
+	timeout := time.Duration(req.TimeoutMs) * time.Millisecond
+	result, err := h.manager.CallAllInstances(req.Service, req.Method, req.Params, expected, timeout)
+	if err != nil {
+		sendJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	sendJSON(w, result)
+}
+
+// handleTrace returns the span tree of a trace: GET /api/traces/{traceId}
+func (h *Handler) handleTrace(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	traceID := strings.TrimPrefix(r.URL.Path, "/api/traces/")
+	if traceID == "" || strings.Contains(traceID, "/") {
+		sendJSONError(w, http.StatusBadRequest, "Trace ID required")
+		return
+	}
+
+	roots, err := h.manager.GetTrace(traceID)
+	if err != nil {
+		sendJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(roots) == 0 {
+		sendJSONError(w, http.StatusNotFound, "Trace not found")
+		return
+	}
+
+	sendJSON(w, map[string]interface{}{
+		"trace_id": traceID,
+		"spans":    roots,
+	})
+}
+
 // handleWebSocket handles WebSocket connections
 func (h *Handler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	// TODO: Implement WebSocket upgrade
@@ -482,6 +595,10 @@ func (h *Handler) handleInstanceRouter(w http.ResponseWriter, r *http.Request) {
 			h.restartInstance(w, r, instanceKey)
 			return
 		}
+		if action == "drain" || action == "resume" || action == "weight" {
+			h.updateInstanceTraffic(w, r, instanceKey, action)
+			return
+		}
 	}
 
 	// Check if it's a DELETE request for deleting offline instance
@@ -573,6 +690,44 @@ func (h *Handler) restartInstance(w http.ResponseWriter, r *http.Request, instan
 	sendJSON(w, map[string]string{"status": "restarting", "instance": instanceKey})
 }
 
+// updateInstanceTraffic drains, resumes or re-weights an instance
+// (POST /api/instances/{key}/drain, /resume, /weight)
+func (h *Handler) updateInstanceTraffic(w http.ResponseWriter, r *http.Request, instanceKey, action string) {
+	if r.Method != http.MethodPost {
+		sendJSONError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if !auth.IsAdmin(r) {
+		sendJSONError(w, http.StatusForbidden, "Admin access required")
+		return
+	}
+
+	var err error
+	switch action {
+	case "drain":
+		err = h.controller.DrainInstance(instanceKey)
+	case "resume":
+		err = h.controller.ResumeInstance(instanceKey)
+	case "weight":
+		var req struct {
+			Weight int `json:"weight"`
+		}
+		if decodeErr := json.NewDecoder(r.Body).Decode(&req); decodeErr != nil {
+			sendJSONError(w, http.StatusBadRequest, "Invalid request")
+			return
+		}
+		err = h.controller.SetInstanceWeight(instanceKey, req.Weight)
+	}
+	if err != nil {
+		sendJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	sendJSON(w, map[string]string{"status": action, "instance": instanceKey})
+}
+
 // deleteOfflineInstance deletes an offline instance (DELETE /api/instances/{key})
 func (h *Handler) deleteOfflineInstance(w http.ResponseWriter, r *http.Request, instanceKey string) {
 	if !auth.IsAdmin(r) {
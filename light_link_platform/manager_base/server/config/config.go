@@ -37,6 +37,8 @@ type TLSConfig struct {
 	Key        string `yaml:"key"`
 	CA         string `yaml:"ca"`
 	ServerName string `yaml:"server_name,omitempty"`
+	// InsecureSkipVerify skips the server name check, for development certificates without SANs
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify,omitempty"`
 }
 
 // DatabaseConfig represents the database configuration
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"crypto/tls"
 	"log"
 	"net/http"
 	"os"
@@ -16,6 +15,7 @@ import (
 	"github.com/LiteHomeLab/light_link/light_link_platform/manager_base/server/proxy"
 	"github.com/LiteHomeLab/light_link/light_link_platform/manager_base/server/storage"
 	"github.com/LiteHomeLab/light_link/light_link_platform/manager_base/server/ws"
+	"github.com/LiteHomeLab/light_link/sdk/go/client"
 	"github.com/nats-io/nats.go"
 )
 
@@ -113,25 +113,21 @@ func connectNATS(cfg *config.Config) (*nats.Conn, error) {
 	}
 
 	if cfg.NATS.TLS.Enabled {
-		// Configure TLS with client certificate
-		// Note: InsecureSkipVerify is used for development with IP addresses
-		// In production, use proper DNS names with valid certificates
-		tlsConfig := &tls.Config{
+		// Configure TLS with client certificate, verified like the SDK does
+		tlsOpt, err := client.CreateTLSOption(&client.TLSConfig{
+			CaFile:             cfg.NATS.TLS.CA,
+			CertFile:           cfg.NATS.TLS.Cert,
+			KeyFile:            cfg.NATS.TLS.Key,
 			ServerName:         cfg.NATS.TLS.ServerName,
-			MinVersion:         tls.VersionTLS12,
-			InsecureSkipVerify: true, // Only for development with IP addresses
+			InsecureSkipVerify: cfg.NATS.TLS.InsecureSkipVerify,
+		})
+		if err != nil {
+			return nil, err
 		}
-
-		// Load client certificates
-		if cfg.NATS.TLS.Cert != "" && cfg.NATS.TLS.Key != "" {
-			cert, err := tls.LoadX509KeyPair(cfg.NATS.TLS.Cert, cfg.NATS.TLS.Key)
-			if err != nil {
-				return nil, err
-			}
-			tlsConfig.Certificates = []tls.Certificate{cert}
+		if cfg.NATS.TLS.InsecureSkipVerify {
+			log.Println("Warning: NATS server name verification is disabled (insecure_skip_verify)")
 		}
-
-		opts = append(opts, nats.Secure(tlsConfig))
+		opts = append(opts, tlsOpt)
 	}
 
 	return nats.Connect(cfg.NATS.URL, opts...)
@@ -11,6 +11,9 @@ import (
 	"github.com/nats-io/nats.go"
 )
 
+// MaxInstanceWeight is the maximum traffic weight accepted by service instances
+const MaxInstanceWeight = 100
+
 // Controller handles remote control of service instances
 type Controller struct {
 	db *storage.Database
@@ -82,6 +85,61 @@ func (c *Controller) RestartInstance(instanceKey string) error {
 	return c.sendControlMessage(&controlMsg)
 }
 
+// DrainInstance stops a service instance from receiving new RPC requests
+func (c *Controller) DrainInstance(instanceKey string) error {
+	instance, err := c.sendTrafficCommand(instanceKey, "drain", 0)
+	if err != nil {
+		return err
+	}
+	return c.db.UpdateInstanceTraffic(instanceKey, instance.Weight, true)
+}
+
+// ResumeInstance makes a drained service instance receive RPC requests again
+func (c *Controller) ResumeInstance(instanceKey string) error {
+	instance, err := c.sendTrafficCommand(instanceKey, "resume", 0)
+	if err != nil {
+		return err
+	}
+	return c.db.UpdateInstanceTraffic(instanceKey, instance.Weight, false)
+}
+
+// SetInstanceWeight changes the traffic weight of a service instance
+func (c *Controller) SetInstanceWeight(instanceKey string, weight int) error {
+	if weight < 1 || weight > MaxInstanceWeight {
+		return fmt.Errorf("weight must be between 1 and %d", MaxInstanceWeight)
+	}
+	instance, err := c.sendTrafficCommand(instanceKey, "set_weight", weight)
+	if err != nil {
+		return err
+	}
+	return c.db.UpdateInstanceTraffic(instanceKey, weight, instance.Draining)
+}
+
+// sendTrafficCommand sends a traffic command to an online instance
+func (c *Controller) sendTrafficCommand(instanceKey, command string, weight int) (*storage.Instance, error) {
+	instance, err := c.db.GetInstance(instanceKey)
+	if err != nil {
+		return nil, fmt.Errorf("instance not found: %w", err)
+	}
+
+	if !instance.Online {
+		return nil, fmt.Errorf("instance is not online")
+	}
+
+	controlMsg := types.ControlMessage{
+		Service:     instance.ServiceName,
+		InstanceKey: instance.InstanceKey,
+		Command:     command,
+		Weight:      weight,
+		Timestamp:   time.Now().Unix(),
+	}
+
+	if err := c.sendControlMessage(&controlMsg); err != nil {
+		return nil, err
+	}
+	return instance, nil
+}
+
 // StopServiceInstances stops all instances of a service
 func (c *Controller) StopServiceInstances(serviceName string) (int, error) {
 	instances, err := c.db.GetInstancesByService(serviceName)
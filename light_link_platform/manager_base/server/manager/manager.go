@@ -1,12 +1,14 @@
 package manager
 
 import (
+	"context"
 	"log"
 	"sync"
 	"time"
 
 	"github.com/LiteHomeLab/light_link/light_link_platform/manager_base/server/proxy"
 	"github.com/LiteHomeLab/light_link/light_link_platform/manager_base/server/storage"
+	"github.com/LiteHomeLab/light_link/sdk/go/tracing"
 	"github.com/LiteHomeLab/light_link/sdk/go/types"
 	"github.com/nats-io/nats.go"
 )
@@ -18,6 +20,8 @@ type Manager struct {
 	registry *Registry
 	monitor  *HeartbeatMonitor
 	caller   *proxy.Caller
+	traces   *TraceCollector
+	tracer   *tracing.Tracer
 	eventCh  chan *types.ServiceEvent
 	mu       sync.RWMutex
 	stopCh   chan struct{}
@@ -25,10 +29,18 @@ type Manager struct {
 
 // NewManager creates a new service manager
 func NewManager(db *storage.Database, nc *nats.Conn, heartbeatTimeout time.Duration) *Manager {
+	// Calls made from the console are the roots of their traces
+	traces := NewTraceCollector(db, nc)
+	tracer := tracing.NewTracer("light-link-manager", traces)
+	caller := proxy.NewCaller(nc, 30*time.Second)
+	caller.SetTracer(tracer)
+
 	return &Manager{
 		db:      db,
 		nc:      nc,
-		caller:  proxy.NewCaller(nc, 30*time.Second),
+		caller:  caller,
+		traces:  traces,
+		tracer:  tracer,
 		eventCh: make(chan *types.ServiceEvent, 100),
 		stopCh:  make(chan struct{}),
 	}
@@ -54,6 +66,14 @@ func (m *Manager) Start() error {
 	m.monitor.StartChecker()
 	log.Println("[Manager] Heartbeat monitor subscribed to $LL.heartbeat.>")
 
+	// Collect spans reported by services
+	if err := m.traces.Subscribe(); err != nil {
+		m.registry.Unsubscribe()
+		m.monitor.Stop()
+		return err
+	}
+	log.Printf("[Manager] Trace collector subscribed to %s", types.TraceSpansSubject)
+
 	// Start event forwarding
 	go m.forwardEvents()
 
@@ -74,6 +94,11 @@ func (m *Manager) Stop() {
 		m.monitor.Stop()
 	}
 
+	m.traces.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	m.tracer.Shutdown(ctx)
+
 	log.Println("[Manager] Service manager stopped")
 }
 
@@ -190,6 +215,30 @@ func (m *Manager) CallServiceMethod(serviceName, methodName string, params map[s
 	return m.caller.Call(serviceName, methodName, params)
 }
 
+// CallInstanceMethod calls an RPC method on one specific service instance
+func (m *Manager) CallInstanceMethod(instanceKey, methodName string, params map[string]interface{}) (*proxy.CallResult, error) {
+	return m.caller.CallInstance(instanceKey, methodName, params)
+}
+
+// CallAllInstances calls an RPC method on every instance of a service
+func (m *Manager) CallAllInstances(serviceName, methodName string, params map[string]interface{}, expected int, timeout time.Duration) (*proxy.CallAllResult, error) {
+	return m.caller.CallAll(serviceName, methodName, params, expected, timeout)
+}
+
+// GetTrace returns the spans of a trace linked into a tree, root spans first
+func (m *Manager) GetTrace(traceID string) ([]*storage.TraceSpan, error) {
+	// Flush so the manager's own span of a just finished call is included
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	m.tracer.Flush(ctx)
+
+	spans, err := m.db.GetTraceSpans(traceID)
+	if err != nil {
+		return nil, err
+	}
+	return storage.BuildTraceTree(spans), nil
+}
+
 // GetCaller returns the RPC caller instance
 func (m *Manager) GetCaller() *proxy.Caller {
 	return m.caller
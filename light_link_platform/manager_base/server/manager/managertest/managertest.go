@@ -0,0 +1,40 @@
+// Package managertest starts a service manager against a testutil server,
+// for end-to-end tests of services registering with the management platform.
+package managertest
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/light_link_platform/manager_base/server/manager"
+	"github.com/LiteHomeLab/light_link/light_link_platform/manager_base/server/storage"
+	"github.com/LiteHomeLab/light_link/sdk/go/testutil"
+)
+
+// HeartbeatTimeout is the heartbeat timeout of managers started by StartManager
+const HeartbeatTimeout = 90 * time.Second
+
+// StartManager starts a manager with a fresh database connected to srv. It
+// returns once the manager receives registrations and heartbeats; the manager
+// is stopped and its database closed when the test ends.
+func StartManager(t testing.TB, srv *testutil.Server) *manager.Manager {
+	t.Helper()
+	db, err := storage.NewDatabase(filepath.Join(t.TempDir(), "manager.db"))
+	if err != nil {
+		t.Fatalf("open manager database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	nc := srv.Connect(t)
+	m := manager.NewManager(db, nc, HeartbeatTimeout)
+	if err := m.Start(); err != nil {
+		t.Fatalf("start manager: %v", err)
+	}
+	t.Cleanup(m.Stop)
+
+	if err := nc.Flush(); err != nil {
+		t.Fatalf("flush manager subscriptions: %v", err)
+	}
+	return m
+}
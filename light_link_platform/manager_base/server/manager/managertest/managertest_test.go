@@ -0,0 +1,41 @@
+package managertest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/service"
+	"github.com/LiteHomeLab/light_link/sdk/go/testutil"
+	"github.com/LiteHomeLab/light_link/sdk/go/testutil/servicetest"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+)
+
+func TestStartManager(t *testing.T) {
+	srv := testutil.StartNATS(t, testutil.Options{})
+	m := StartManager(t, srv)
+
+	svc := servicetest.StartService(t, srv, "math-service", func(svc *service.Service) {
+		svc.RegisterMethodWithMetadata("add", func(args map[string]interface{}) (map[string]interface{}, error) {
+			return map[string]interface{}{"sum": args["a"].(float64) + args["b"].(float64)}, nil
+		}, &types.MethodMetadata{Name: "add"})
+	})
+	if err := svc.RegisterMetadata(svc.BuildCurrentMetadata("math-service", "v1.0.0", "", "", nil)); err != nil {
+		t.Fatalf("RegisterMetadata failed: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if meta, err := m.GetService("math-service"); err == nil && meta.Version == "v1.0.0" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Service not registered with the manager")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	result, err := m.CallServiceMethod("math-service", "add", map[string]interface{}{"a": 1, "b": 2})
+	if err != nil || !result.Success {
+		t.Fatalf("CallServiceMethod failed: %v, %+v", err, result)
+	}
+}
@@ -7,11 +7,15 @@ import (
 	"time"
 
 	"github.com/LiteHomeLab/light_link/light_link_platform/manager_base/server/storage"
+	"github.com/LiteHomeLab/light_link/sdk/go/client"
 	"github.com/LiteHomeLab/light_link/sdk/go/types"
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
 )
 
+// CertExpiryWarning is how long before a service certificate expires the registry warns about it
+const CertExpiryWarning = client.DefaultCertExpiryWarning
+
 // Registry handles service registration messages
 type Registry struct {
 	db      *storage.Database
@@ -114,10 +118,17 @@ func (r *Registry) handleRegister(msg *nats.Msg) {
 		FirstSeen:     time.Now(),
 		LastHeartbeat: time.Now(),
 		Online:        true,
+		Weight:        register.InstanceInfo.Weight,
+		Draining:      register.InstanceInfo.Draining,
+	}
+	if register.InstanceInfo.CertExpiry > 0 {
+		expiry := time.Unix(register.InstanceInfo.CertExpiry, 0)
+		instance.CertExpiry = &expiry
 	}
 	if err := r.db.SaveInstance(instance); err != nil {
 		log.Printf("[Registry] Failed to save instance: %v", err)
 	}
+	r.checkCertExpiry(instance)
 
 	// Update status to online
 	if err := r.db.UpdateServiceStatus(register.Metadata.Name, true, register.Version); err != nil {
@@ -143,6 +154,34 @@ func (r *Registry) handleRegister(msg *nats.Msg) {
 	}
 }
 
+// checkCertExpiry warns when the TLS certificate of an instance expires soon
+func (r *Registry) checkCertExpiry(instance *storage.Instance) {
+	if instance.CertExpiry == nil {
+		return
+	}
+	remaining := time.Until(*instance.CertExpiry)
+	if remaining > CertExpiryWarning {
+		return
+	}
+
+	if remaining <= 0 {
+		log.Printf("[Registry] WARNING: certificate of %s expired at %s",
+			instance.InstanceKey, instance.CertExpiry.Format(time.RFC3339))
+	} else {
+		log.Printf("[Registry] WARNING: certificate of %s expires at %s (in %d days)",
+			instance.InstanceKey, instance.CertExpiry.Format(time.RFC3339), int(remaining.Hours()/24))
+	}
+	r.eventCh <- &types.ServiceEvent{
+		Type:      "cert_expiring",
+		Service:   instance.ServiceName,
+		Timestamp: time.Now(),
+		Data: map[string]any{
+			"instance_key": instance.InstanceKey,
+			"cert_expiry":  instance.CertExpiry,
+		},
+	}
+}
+
 // storeMetadataToKV stores service metadata to NATS KV store for service discovery
 func (r *Registry) storeMetadataToKV(metadata *types.ServiceMetadata) error {
 	js, err := jetstream.New(r.nc)
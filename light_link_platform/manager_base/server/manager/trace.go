@@ -0,0 +1,104 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/light_link_platform/manager_base/server/storage"
+	"github.com/LiteHomeLab/light_link/sdk/go/tracing"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// TraceRetention is how long collected spans are kept
+	TraceRetention = 24 * time.Hour
+	// tracePruneInterval is how often expired spans are deleted
+	tracePruneInterval = time.Hour
+)
+
+// TraceCollector stores the spans services report on $LL.trace.spans
+type TraceCollector struct {
+	db     *storage.Database
+	nc     *nats.Conn
+	sub    *nats.Subscription
+	stopCh chan struct{}
+
+	stopOnce sync.Once
+}
+
+// NewTraceCollector creates a new trace collector
+func NewTraceCollector(db *storage.Database, nc *nats.Conn) *TraceCollector {
+	return &TraceCollector{
+		db:     db,
+		nc:     nc,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Subscribe subscribes to reported spans and starts pruning expired ones
+func (c *TraceCollector) Subscribe() error {
+	sub, err := c.nc.Subscribe(types.TraceSpansSubject, c.handleSpans)
+	if err != nil {
+		return err
+	}
+	c.sub = sub
+	go c.pruneLoop()
+	return nil
+}
+
+// Stop unsubscribes and stops pruning. It is safe to call more than once.
+func (c *TraceCollector) Stop() {
+	c.stopOnce.Do(func() {
+		if c.sub != nil {
+			c.sub.Unsubscribe()
+			c.sub = nil
+		}
+		close(c.stopCh)
+	})
+}
+
+// ExportSpans implements tracing.Exporter, storing the manager's own spans
+func (c *TraceCollector) ExportSpans(ctx context.Context, spans []tracing.SpanData) error {
+	return c.db.SaveSpans(spans)
+}
+
+// Shutdown implements tracing.Exporter
+func (c *TraceCollector) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// handleSpans stores a batch of reported spans
+func (c *TraceCollector) handleSpans(msg *nats.Msg) {
+	var spans []tracing.SpanData
+	if err := json.Unmarshal(msg.Data, &spans); err != nil {
+		log.Printf("[Trace] Failed to unmarshal spans: %v", err)
+		return
+	}
+	if err := c.db.SaveSpans(spans); err != nil {
+		log.Printf("[Trace] Failed to save %d spans: %v", len(spans), err)
+	}
+}
+
+// pruneLoop deletes spans older than TraceRetention
+func (c *TraceCollector) pruneLoop() {
+	ticker := time.NewTicker(tracePruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			deleted, err := c.db.DeleteTracesBefore(time.Now().Add(-TraceRetention))
+			if err != nil {
+				log.Printf("[Trace] Failed to prune spans: %v", err)
+			} else if deleted > 0 {
+				log.Printf("[Trace] Pruned %d expired spans", deleted)
+			}
+		case <-c.stopCh:
+			return
+		}
+	}
+}
@@ -1,27 +1,52 @@
 package proxy
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"time"
 
+	"github.com/LiteHomeLab/light_link/sdk/go/tracing"
 	"github.com/LiteHomeLab/light_link/sdk/go/types"
 	"github.com/nats-io/nats.go"
 )
 
+// DefaultCallAllTimeout is how long CallAll collects replies when no timeout is given
+const DefaultCallAllTimeout = 5 * time.Second
+
 // Caller handles RPC calls to services
 type Caller struct {
 	nc      *nats.Conn
 	timeout time.Duration
+	tracer  *tracing.Tracer
 }
 
 // CallResult represents the result of an RPC call
 type CallResult struct {
-	Success    bool                   `json:"success"`
-	Data       map[string]interface{} `json:"data,omitempty"`
-	Error      string                 `json:"error,omitempty"`
-	Duration   int64                  `json:"duration"`
+	Success   bool                   `json:"success"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	Duration  int64                  `json:"duration"`
+	TraceID   string                 `json:"trace_id,omitempty"`
+	ErrorInfo *types.RPCError        `json:"error_info,omitempty"`
+}
+
+// InstanceCallResult is the result of a scatter-gather call on one instance
+type InstanceCallResult struct {
+	InstanceKey string `json:"instance_key"`
+	*CallResult
+}
+
+// CallAllResult represents the result of a scatter-gather call
+type CallAllResult struct {
+	Expected  int                   `json:"expected"`
+	Received  int                   `json:"received"`
+	Instances []*InstanceCallResult `json:"instances"`
+	Duration  int64                 `json:"duration"`
+	TraceID   string                `json:"trace_id,omitempty"`
 }
 
 // NewCaller creates a new RPC caller
@@ -35,8 +60,29 @@ func NewCaller(nc *nats.Conn, timeout time.Duration) *Caller {
 	}
 }
 
+// SetTracer records a client span for every call, starting a new trace.
+// The trace ID is returned in the call results.
+func (c *Caller) SetTracer(tracer *tracing.Tracer) {
+	c.tracer = tracer
+}
+
 // Call calls an RPC method on a service
 func (c *Caller) Call(serviceName, methodName string, params map[string]interface{}) (*CallResult, error) {
+	return c.call(types.ServiceRPCSubject(serviceName, methodName), methodName, params, c.timeout)
+}
+
+// CallWithTimeout calls an RPC method with a custom timeout
+func (c *Caller) CallWithTimeout(serviceName, methodName string, params map[string]interface{}, timeout time.Duration) (*CallResult, error) {
+	return c.call(types.ServiceRPCSubject(serviceName, methodName), methodName, params, timeout)
+}
+
+// CallInstance calls an RPC method on one specific service instance
+func (c *Caller) CallInstance(instanceKey, methodName string, params map[string]interface{}) (*CallResult, error) {
+	return c.call(types.InstanceRPCSubject(instanceKey, methodName), methodName, params, c.timeout)
+}
+
+// call sends an RPC request to subject and converts the response into a CallResult
+func (c *Caller) call(subject, methodName string, params map[string]interface{}, timeout time.Duration) (*CallResult, error) {
 	start := time.Now()
 
 	// Build RPC request
@@ -51,90 +97,130 @@ func (c *Caller) Call(serviceName, methodName string, params map[string]interfac
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
+	_, span := c.tracer.Start(context.Background(), subject, tracing.SpanKindClient)
+	span.SetAttribute("rpc.method", methodName)
+	defer span.End()
+
 	// Send request to service
-	subject := fmt.Sprintf("$SRV.%s.%s", serviceName, methodName)
-	respMsg, err := c.nc.Request(subject, requestData, c.timeout)
+	respMsg, err := c.nc.RequestMsg(newRequestMsg(subject, requestData, span), timeout)
 	if err != nil {
+		span.SetError(err)
 		return &CallResult{
 			Success:  false,
 			Error:    err.Error(),
 			Duration: time.Since(start).Milliseconds(),
+			TraceID:  span.TraceID(),
 		}, nil
 	}
 
-	// Parse response
-	var response types.RPCResponse
-	if err := json.Unmarshal(respMsg.Data, &response); err != nil {
-		return &CallResult{
-			Success:  false,
-			Error:    fmt.Sprintf("parse response: %v", err),
-			Duration: time.Since(start).Milliseconds(),
-		}, nil
+	result := parseCallResult(respMsg.Data, start)
+	result.TraceID = span.TraceID()
+	if result.Success {
+		span.SetStatus(tracing.StatusOK, "")
+	} else {
+		span.SetStatus(tracing.StatusError, result.Error)
 	}
+	return result, nil
+}
 
-	// Handle validation error with structured details
-	errorMsg := response.Error
-	if !response.Success && response.Result != nil {
-		// Check if this is a structured validation error
-		if errType, ok := response.Result["type"].(string); ok && errType == types.ValidationErrorType {
-			// Build more detailed error message
-			if paramName, ok := response.Result["parameter_name"].(string); ok {
-				expectedType, _ := response.Result["expected_type"].(string)
-				actualType, _ := response.Result["actual_type"].(string)
-				errorMsg = fmt.Sprintf("参数 '%s' 类型错误: 期望 %s，实际 %s",
-					paramName, expectedType, actualType)
-			}
-		}
+// newRequestMsg builds a request message carrying the trace context of span
+func newRequestMsg(subject string, data []byte, span *tracing.Span) *nats.Msg {
+	msg := nats.NewMsg(subject)
+	msg.Data = data
+	if sc := span.SpanContext(); sc.IsValid() {
+		msg.Header.Set(types.HeaderTraceparent, sc.Traceparent())
 	}
-
-	return &CallResult{
-		Success:  response.Success,
-		Data:     response.Result,
-		Error:    errorMsg,
-		Duration: time.Since(start).Milliseconds(),
-	}, nil
+	return msg
 }
 
-// CallWithTimeout calls an RPC method with a custom timeout
-func (c *Caller) CallWithTimeout(serviceName, methodName string, params map[string]interface{}, timeout time.Duration) (*CallResult, error) {
+// CallAll calls an RPC method on every instance of a service and collects the replies
+// until expected replies arrived (zero waits for the timeout) or the timeout passed
+func (c *Caller) CallAll(serviceName, methodName string, params map[string]interface{}, expected int, timeout time.Duration) (*CallAllResult, error) {
 	start := time.Now()
+	if timeout <= 0 {
+		timeout = DefaultCallAllTimeout
+	}
 
-	request := types.RPCRequest{
+	requestData, err := json.Marshal(types.RPCRequest{
 		ID:     generateID(),
 		Method: methodName,
 		Args:   params,
-	}
-
-	requestData, err := json.Marshal(request)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	subject := fmt.Sprintf("$SRV.%s.%s", serviceName, methodName)
-	respMsg, err := c.nc.Request(subject, requestData, timeout)
+	inbox := c.nc.NewRespInbox()
+	sub, err := c.nc.SubscribeSync(inbox)
 	if err != nil {
-		return &CallResult{
-			Success:  false,
-			Error:    err.Error(),
-			Duration: time.Since(start).Milliseconds(),
-		}, nil
+		return nil, fmt.Errorf("subscribe reply inbox: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	subject := types.BroadcastRPCSubject(serviceName, methodName)
+	_, span := c.tracer.Start(context.Background(), subject, tracing.SpanKindClient)
+	span.SetAttribute("rpc.method", methodName)
+	defer span.End()
+
+	msg := newRequestMsg(subject, requestData, span)
+	msg.Reply = inbox
+	if err := c.nc.PublishMsg(msg); err != nil {
+		span.SetError(err)
+		return nil, fmt.Errorf("publish request: %w", err)
 	}
 
+	result := &CallAllResult{Expected: expected, Instances: []*InstanceCallResult{}, TraceID: span.TraceID()}
+	deadline := start.Add(timeout)
+	for expected <= 0 || len(result.Instances) < expected {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+		msg, err := sub.NextMsg(remaining)
+		if err != nil {
+			break
+		}
+		result.Instances = append(result.Instances, &InstanceCallResult{
+			InstanceKey: msg.Header.Get(types.HeaderInstanceKey),
+			CallResult:  parseCallResult(msg.Data, start),
+		})
+	}
+
+	result.Received = len(result.Instances)
+	span.SetAttribute("rpc.replies", strconv.Itoa(result.Received))
+	result.Duration = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// parseCallResult converts an encoded RPC response into a CallResult
+func parseCallResult(data []byte, start time.Time) *CallResult {
+	// Parse response
 	var response types.RPCResponse
-	if err := json.Unmarshal(respMsg.Data, &response); err != nil {
+	if err := json.Unmarshal(data, &response); err != nil {
 		return &CallResult{
 			Success:  false,
 			Error:    fmt.Sprintf("parse response: %v", err),
 			Duration: time.Since(start).Milliseconds(),
-		}, nil
+		}
 	}
 
-	return &CallResult{
+	result := &CallResult{
 		Success:  response.Success,
 		Data:     response.Result,
 		Error:    response.Error,
 		Duration: time.Since(start).Milliseconds(),
-	}, nil
+	}
+	if err := response.Err(); err != nil {
+		errors.As(err, &result.ErrorInfo)
+
+		// Build a more detailed message for validation errors
+		var validationErr *types.ValidationError
+		if errors.As(err, &validationErr) && validationErr.ParameterName != "" {
+			result.Error = fmt.Sprintf("参数 '%s' 类型错误: 期望 %s，实际 %s",
+				validationErr.ParameterName, validationErr.ExpectedType, validationErr.ActualType)
+		}
+	}
+	return result
 }
 
 // CallAsync calls an RPC method asynchronously
@@ -128,6 +128,22 @@ func (d *Database) init() error {
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
 
+	CREATE TABLE IF NOT EXISTS trace_spans (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		trace_id TEXT NOT NULL,
+		span_id TEXT NOT NULL,
+		parent_span_id TEXT NOT NULL DEFAULT '',
+		name TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		service TEXT NOT NULL,
+		start_time DATETIME NOT NULL,
+		end_time DATETIME NOT NULL,
+		status TEXT NOT NULL,
+		status_message TEXT NOT NULL DEFAULT '',
+		attributes TEXT,
+		UNIQUE(trace_id, span_id)
+	);
+
 	-- Indexes
 	CREATE INDEX IF NOT EXISTS idx_service_status_service_id ON service_status(service_id);
 	CREATE INDEX IF NOT EXISTS idx_service_status_history_service_id ON service_status_history(service_id);
@@ -139,10 +155,70 @@ func (d *Database) init() error {
 	CREATE INDEX IF NOT EXISTS idx_instances_instance_key ON instances(instance_key);
 	CREATE INDEX IF NOT EXISTS idx_instances_service_name ON instances(service_name);
 	CREATE INDEX IF NOT EXISTS idx_instances_online ON instances(online);
+	CREATE INDEX IF NOT EXISTS idx_trace_spans_start_time ON trace_spans(start_time);
 	`
 
-	_, err := d.db.Exec(schema)
-	return err
+	if _, err := d.db.Exec(schema); err != nil {
+		return err
+	}
+
+	return d.migrate()
+}
+
+// columnMigrations lists columns added after the initial schema
+var columnMigrations = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"instances", "weight", "INTEGER NOT NULL DEFAULT 1"},
+	{"instances", "draining", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"instances", "cert_expiry", "DATETIME"},
+}
+
+// migrate adds columns missing from databases created by older versions
+func (d *Database) migrate() error {
+	for _, m := range columnMigrations {
+		exists, err := d.columnExists(m.table, m.column)
+		if err != nil {
+			return fmt.Errorf("check column %s.%s: %w", m.table, m.column, err)
+		}
+		if exists {
+			continue
+		}
+		query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", m.table, m.column, m.definition)
+		if _, err := d.db.Exec(query); err != nil {
+			return fmt.Errorf("add column %s.%s: %w", m.table, m.column, err)
+		}
+	}
+	return nil
+}
+
+// columnExists checks whether a table has a column
+func (d *Database) columnExists(table, column string) (bool, error) {
+	rows, err := d.db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
 }
 
 // Close closes the database connection
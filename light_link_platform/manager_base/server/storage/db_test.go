@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/LiteHomeLab/light_link/sdk/go/tracing"
 	"github.com/LiteHomeLab/light_link/sdk/go/types"
 )
 
@@ -39,7 +40,7 @@ func TestDatabaseInit(t *testing.T) {
 	}
 
 	// Check other tables
-	tables := []string{"methods", "service_status", "events", "users", "call_history", "service_status_history", "instances"}
+	tables := []string{"methods", "service_status", "events", "users", "call_history", "service_status_history", "instances", "trace_spans"}
 	for _, table := range tables {
 		err := db.db.QueryRow(`
 			SELECT name FROM sqlite_master
@@ -443,3 +444,156 @@ func TestGetInstanceNotFound(t *testing.T) {
 		t.Error("Expected error for non-existent instance")
 	}
 }
+
+func TestUpdateInstanceTraffic(t *testing.T) {
+	db := setupTestDB(t)
+
+	instance := &Instance{
+		ServiceName:   "math-service",
+		InstanceKey:   "192.168.1.100:aabbccddeeff:math-service",
+		Language:      "go",
+		HostIP:        "192.168.1.100",
+		HostMAC:       "aabbccddeeff",
+		WorkingDir:    "/home/user/services/math-service",
+		Version:       "v1.0.0",
+		Online:        true,
+		FirstSeen:     time.Now(),
+		LastHeartbeat: time.Now(),
+	}
+	if err := db.SaveInstance(instance); err != nil {
+		t.Fatalf("SaveInstance failed: %v", err)
+	}
+
+	saved, err := db.GetInstance(instance.InstanceKey)
+	if err != nil {
+		t.Fatalf("GetInstance failed: %v", err)
+	}
+	if saved.Weight != 1 || saved.Draining {
+		t.Errorf("expected default weight 1 and not draining, got %d/%v", saved.Weight, saved.Draining)
+	}
+
+	if err := db.UpdateInstanceTraffic(instance.InstanceKey, 3, true); err != nil {
+		t.Fatalf("UpdateInstanceTraffic failed: %v", err)
+	}
+	saved, err = db.GetInstance(instance.InstanceKey)
+	if err != nil {
+		t.Fatalf("GetInstance failed: %v", err)
+	}
+	if saved.Weight != 3 || !saved.Draining {
+		t.Errorf("expected weight 3 and draining, got %d/%v", saved.Weight, saved.Draining)
+	}
+
+	if err := db.UpdateInstanceTraffic("nonexistent:instance:key", 1, false); err == nil {
+		t.Error("Expected error for non-existent instance")
+	}
+}
+
+func TestInstanceCertExpiry(t *testing.T) {
+	db := setupTestDB(t)
+
+	instance := &Instance{
+		ServiceName:   "math-service",
+		InstanceKey:   "192.168.1.100:aabbccddeeff:math-service",
+		Language:      "go",
+		HostIP:        "192.168.1.100",
+		HostMAC:       "aabbccddeeff",
+		WorkingDir:    "/home/user/services/math-service",
+		Version:       "v1.0.0",
+		Online:        true,
+		FirstSeen:     time.Now(),
+		LastHeartbeat: time.Now(),
+	}
+	if err := db.SaveInstance(instance); err != nil {
+		t.Fatalf("SaveInstance failed: %v", err)
+	}
+	saved, err := db.GetInstance(instance.InstanceKey)
+	if err != nil {
+		t.Fatalf("GetInstance failed: %v", err)
+	}
+	if saved.CertExpiry != nil {
+		t.Errorf("expected no cert expiry, got %v", saved.CertExpiry)
+	}
+
+	expiry := time.Now().Add(10 * 24 * time.Hour).Truncate(time.Second)
+	instance.CertExpiry = &expiry
+	if err := db.SaveInstance(instance); err != nil {
+		t.Fatalf("SaveInstance failed: %v", err)
+	}
+	instances, err := db.GetInstancesByService("math-service")
+	if err != nil || len(instances) != 1 {
+		t.Fatalf("GetInstancesByService failed: %v", err)
+	}
+	if instances[0].CertExpiry == nil || !instances[0].CertExpiry.Equal(expiry) {
+		t.Errorf("expected cert expiry %v, got %v", expiry, instances[0].CertExpiry)
+	}
+}
+
+func TestMigrateAddsMissingColumns(t *testing.T) {
+	db := setupTestDB(t)
+
+	// Running migrations again must be a no-op
+	if err := db.migrate(); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	for _, m := range columnMigrations {
+		exists, err := db.columnExists(m.table, m.column)
+		if err != nil {
+			t.Fatalf("columnExists failed: %v", err)
+		}
+		if !exists {
+			t.Errorf("Column %s.%s not found", m.table, m.column)
+		}
+	}
+}
+
+func TestSaveAndGetTraceSpans(t *testing.T) {
+	db := setupTestDB(t)
+
+	start := time.Now().Add(-time.Second)
+	spans := []tracing.SpanData{
+		{TraceID: "trace-1", SpanID: "root", Name: "$SRV.frontend.sum", Kind: tracing.SpanKindClient,
+			Service: "light-link-manager", StartTime: start, EndTime: start.Add(30 * time.Millisecond), Status: tracing.StatusOK},
+		{TraceID: "trace-1", SpanID: "backend", ParentSpanID: "frontend", Name: "$SRV.backend.add", Kind: tracing.SpanKindServer,
+			Service: "backend", StartTime: start.Add(10 * time.Millisecond), EndTime: start.Add(20 * time.Millisecond),
+			Status: tracing.StatusError, StatusMessage: "boom", Attributes: map[string]string{"rpc.method": "add"}},
+		{TraceID: "trace-1", SpanID: "frontend", ParentSpanID: "root", Name: "$SRV.frontend.sum", Kind: tracing.SpanKindServer,
+			Service: "frontend", StartTime: start.Add(5 * time.Millisecond), EndTime: start.Add(25 * time.Millisecond), Status: tracing.StatusOK},
+		{TraceID: "trace-2", SpanID: "other", Name: "other", Kind: tracing.SpanKindClient,
+			Service: "other", StartTime: start, EndTime: start, Status: tracing.StatusOK},
+	}
+	if err := db.SaveSpans(spans); err != nil {
+		t.Fatalf("SaveSpans failed: %v", err)
+	}
+	// Spans reported twice are stored once
+	if err := db.SaveSpans(spans[:1]); err != nil {
+		t.Fatalf("SaveSpans failed: %v", err)
+	}
+
+	saved, err := db.GetTraceSpans("trace-1")
+	if err != nil {
+		t.Fatalf("GetTraceSpans failed: %v", err)
+	}
+	if len(saved) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(saved))
+	}
+
+	roots := BuildTraceTree(saved)
+	if len(roots) != 1 || roots[0].SpanID != "root" {
+		t.Fatalf("Expected single root span, got %+v", roots)
+	}
+	if len(roots[0].Children) != 1 || roots[0].Children[0].SpanID != "frontend" {
+		t.Fatalf("Expected frontend span under root, got %+v", roots[0].Children)
+	}
+	backend := roots[0].Children[0].Children
+	if len(backend) != 1 || backend[0].Status != "error" || backend[0].Attributes["rpc.method"] != "add" {
+		t.Errorf("Unexpected backend span %+v", backend)
+	}
+	if backend[0].DurationMs != 10 {
+		t.Errorf("Expected duration 10ms, got %v", backend[0].DurationMs)
+	}
+
+	deleted, err := db.DeleteTracesBefore(time.Now())
+	if err != nil || deleted != 4 {
+		t.Errorf("Expected 4 spans deleted, got %d (%v)", deleted, err)
+	}
+}
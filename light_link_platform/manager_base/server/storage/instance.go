@@ -8,26 +8,29 @@ import (
 
 // Instance represents a service instance
 type Instance struct {
-	ID            int64     `db:"id" json:"id"`
-	ServiceName   string    `db:"service_name" json:"service_name"`
-	InstanceKey   string    `db:"instance_key" json:"instance_key"`
-	Language      string    `db:"language" json:"language"`
-	HostIP        string    `db:"host_ip" json:"host_ip"`
-	HostMAC       string    `db:"host_mac" json:"host_mac"`
-	WorkingDir    string    `db:"working_dir" json:"working_dir"`
-	Version       string    `db:"version" json:"version"`
-	FirstSeen     time.Time `db:"first_seen" json:"first_seen"`
-	LastHeartbeat time.Time `db:"last_heartbeat" json:"last_heartbeat"`
-	Online        bool      `db:"online" json:"online"`
-	CreatedAt     time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
+	ID            int64      `db:"id" json:"id"`
+	ServiceName   string     `db:"service_name" json:"service_name"`
+	InstanceKey   string     `db:"instance_key" json:"instance_key"`
+	Language      string     `db:"language" json:"language"`
+	HostIP        string     `db:"host_ip" json:"host_ip"`
+	HostMAC       string     `db:"host_mac" json:"host_mac"`
+	WorkingDir    string     `db:"working_dir" json:"working_dir"`
+	Version       string     `db:"version" json:"version"`
+	FirstSeen     time.Time  `db:"first_seen" json:"first_seen"`
+	LastHeartbeat time.Time  `db:"last_heartbeat" json:"last_heartbeat"`
+	Online        bool       `db:"online" json:"online"`
+	Weight        int        `db:"weight" json:"weight"`
+	Draining      bool       `db:"draining" json:"draining"`
+	CertExpiry    *time.Time `db:"cert_expiry" json:"cert_expiry,omitempty"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time  `db:"updated_at" json:"updated_at"`
 }
 
 // SaveInstance saves or updates an instance record
 func (d *Database) SaveInstance(inst *Instance) error {
 	query := `
-	INSERT INTO instances (service_name, instance_key, language, host_ip, host_mac, working_dir, version, first_seen, last_heartbeat, online)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	INSERT INTO instances (service_name, instance_key, language, host_ip, host_mac, working_dir, version, first_seen, last_heartbeat, online, weight, draining, cert_expiry)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	ON CONFLICT(instance_key) DO UPDATE SET
 		service_name = excluded.service_name,
 		language = excluded.language,
@@ -37,23 +40,30 @@ func (d *Database) SaveInstance(inst *Instance) error {
 		version = excluded.version,
 		last_heartbeat = excluded.last_heartbeat,
 		online = excluded.online,
+		weight = excluded.weight,
+		draining = excluded.draining,
+		cert_expiry = excluded.cert_expiry,
 		updated_at = CURRENT_TIMESTAMP
 	`
+	weight := inst.Weight
+	if weight == 0 {
+		weight = 1
+	}
 	_, err := d.db.Exec(query, inst.ServiceName, inst.InstanceKey, inst.Language,
 		inst.HostIP, inst.HostMAC, inst.WorkingDir, inst.Version,
-		inst.FirstSeen, inst.LastHeartbeat, inst.Online)
+		inst.FirstSeen, inst.LastHeartbeat, inst.Online, weight, inst.Draining, inst.CertExpiry)
 	return err
 }
 
 // GetInstance retrieves an instance by its instance key
 func (d *Database) GetInstance(instanceKey string) (*Instance, error) {
-	query := `SELECT id, service_name, instance_key, language, host_ip, host_mac, working_dir, version, first_seen, last_heartbeat, online, created_at, updated_at FROM instances WHERE instance_key = ?`
+	query := `SELECT id, service_name, instance_key, language, host_ip, host_mac, working_dir, version, first_seen, last_heartbeat, online, weight, draining, cert_expiry, created_at, updated_at FROM instances WHERE instance_key = ?`
 	row := d.db.QueryRow(query, instanceKey)
 
 	var inst Instance
 	err := row.Scan(&inst.ID, &inst.ServiceName, &inst.InstanceKey, &inst.Language,
 		&inst.HostIP, &inst.HostMAC, &inst.WorkingDir, &inst.Version,
-		&inst.FirstSeen, &inst.LastHeartbeat, &inst.Online, &inst.CreatedAt, &inst.UpdatedAt)
+		&inst.FirstSeen, &inst.LastHeartbeat, &inst.Online, &inst.Weight, &inst.Draining, &inst.CertExpiry, &inst.CreatedAt, &inst.UpdatedAt)
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("instance not found: %s", instanceKey)
 	}
@@ -62,7 +72,7 @@ func (d *Database) GetInstance(instanceKey string) (*Instance, error) {
 
 // GetInstancesByService retrieves all instances for a service
 func (d *Database) GetInstancesByService(serviceName string) ([]*Instance, error) {
-	query := `SELECT id, service_name, instance_key, language, host_ip, host_mac, working_dir, version, first_seen, last_heartbeat, online, created_at, updated_at FROM instances WHERE service_name = ? ORDER BY created_at DESC`
+	query := `SELECT id, service_name, instance_key, language, host_ip, host_mac, working_dir, version, first_seen, last_heartbeat, online, weight, draining, cert_expiry, created_at, updated_at FROM instances WHERE service_name = ? ORDER BY created_at DESC`
 	rows, err := d.db.Query(query, serviceName)
 	if err != nil {
 		return nil, err
@@ -74,7 +84,7 @@ func (d *Database) GetInstancesByService(serviceName string) ([]*Instance, error
 		var inst Instance
 		err := rows.Scan(&inst.ID, &inst.ServiceName, &inst.InstanceKey, &inst.Language,
 			&inst.HostIP, &inst.HostMAC, &inst.WorkingDir, &inst.Version,
-			&inst.FirstSeen, &inst.LastHeartbeat, &inst.Online, &inst.CreatedAt, &inst.UpdatedAt)
+			&inst.FirstSeen, &inst.LastHeartbeat, &inst.Online, &inst.Weight, &inst.Draining, &inst.CertExpiry, &inst.CreatedAt, &inst.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -85,7 +95,7 @@ func (d *Database) GetInstancesByService(serviceName string) ([]*Instance, error
 
 // ListAllInstances retrieves all instances
 func (d *Database) ListAllInstances() ([]*Instance, error) {
-	query := `SELECT id, service_name, instance_key, language, host_ip, host_mac, working_dir, version, first_seen, last_heartbeat, online, created_at, updated_at FROM instances ORDER BY created_at DESC`
+	query := `SELECT id, service_name, instance_key, language, host_ip, host_mac, working_dir, version, first_seen, last_heartbeat, online, weight, draining, cert_expiry, created_at, updated_at FROM instances ORDER BY created_at DESC`
 	rows, err := d.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -97,7 +107,7 @@ func (d *Database) ListAllInstances() ([]*Instance, error) {
 		var inst Instance
 		err := rows.Scan(&inst.ID, &inst.ServiceName, &inst.InstanceKey, &inst.Language,
 			&inst.HostIP, &inst.HostMAC, &inst.WorkingDir, &inst.Version,
-			&inst.FirstSeen, &inst.LastHeartbeat, &inst.Online, &inst.CreatedAt, &inst.UpdatedAt)
+			&inst.FirstSeen, &inst.LastHeartbeat, &inst.Online, &inst.Weight, &inst.Draining, &inst.CertExpiry, &inst.CreatedAt, &inst.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -133,3 +143,17 @@ func (d *Database) UpdateInstanceOnline(instanceKey string, online bool) error {
 	}
 	return nil
 }
+
+// UpdateInstanceTraffic updates the traffic weight and draining flag of an instance
+func (d *Database) UpdateInstanceTraffic(instanceKey string, weight int, draining bool) error {
+	query := `UPDATE instances SET weight = ?, draining = ?, updated_at = CURRENT_TIMESTAMP WHERE instance_key = ?`
+	result, err := d.db.Exec(query, weight, draining, instanceKey)
+	if err != nil {
+		return err
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("instance not found: %s", instanceKey)
+	}
+	return nil
+}
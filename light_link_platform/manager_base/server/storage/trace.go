@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/tracing"
+)
+
+// TraceSpan represents a stored span of a distributed trace
+type TraceSpan struct {
+	TraceID       string            `json:"trace_id"`
+	SpanID        string            `json:"span_id"`
+	ParentSpanID  string            `json:"parent_span_id,omitempty"`
+	Name          string            `json:"name"`
+	Kind          string            `json:"kind"`
+	Service       string            `json:"service"`
+	StartTime     time.Time         `json:"start_time"`
+	EndTime       time.Time         `json:"end_time"`
+	DurationMs    float64           `json:"duration_ms"`
+	Status        string            `json:"status"`
+	StatusMessage string            `json:"status_message,omitempty"`
+	Attributes    map[string]string `json:"attributes,omitempty"`
+	Children      []*TraceSpan      `json:"children,omitempty"`
+}
+
+// SaveSpans saves finished spans, ignoring spans already stored
+func (d *Database) SaveSpans(spans []tracing.SpanData) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+	INSERT OR IGNORE INTO trace_spans (trace_id, span_id, parent_span_id, name, kind, service,
+		start_time, end_time, status, status_message, attributes)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, span := range spans {
+		attributes, _ := json.Marshal(span.Attributes)
+		if _, err := stmt.Exec(span.TraceID, span.SpanID, span.ParentSpanID, span.Name,
+			string(span.Kind), span.Service, span.StartTime, span.EndTime,
+			string(span.Status), span.StatusMessage, string(attributes)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetTraceSpans retrieves all spans of a trace ordered by start time
+func (d *Database) GetTraceSpans(traceID string) ([]*TraceSpan, error) {
+	query := `
+	SELECT trace_id, span_id, parent_span_id, name, kind, service,
+		start_time, end_time, status, status_message, attributes
+	FROM trace_spans
+	WHERE trace_id = ?
+	ORDER BY start_time
+	`
+
+	rows, err := d.db.Query(query, traceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	spans := []*TraceSpan{}
+	for rows.Next() {
+		var s TraceSpan
+		var attributes string
+		if err := rows.Scan(&s.TraceID, &s.SpanID, &s.ParentSpanID, &s.Name, &s.Kind, &s.Service,
+			&s.StartTime, &s.EndTime, &s.Status, &s.StatusMessage, &attributes); err != nil {
+			return nil, err
+		}
+		json.Unmarshal([]byte(attributes), &s.Attributes)
+		s.DurationMs = float64(s.EndTime.Sub(s.StartTime).Microseconds()) / 1000
+		spans = append(spans, &s)
+	}
+
+	return spans, rows.Err()
+}
+
+// DeleteTracesBefore deletes spans that started before the given time
+func (d *Database) DeleteTracesBefore(before time.Time) (int64, error) {
+	result, err := d.db.Exec("DELETE FROM trace_spans WHERE start_time < ?", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// BuildTraceTree links spans to their parents and returns the root spans.
+// Spans whose parent was not reported are treated as roots.
+func BuildTraceTree(spans []*TraceSpan) []*TraceSpan {
+	byID := make(map[string]*TraceSpan, len(spans))
+	for _, span := range spans {
+		span.Children = nil
+		byID[span.SpanID] = span
+	}
+
+	roots := []*TraceSpan{}
+	for _, span := range spans {
+		if parent, ok := byID[span.ParentSpanID]; ok && parent != span {
+			parent.Children = append(parent.Children, span)
+		} else {
+			roots = append(roots, span)
+		}
+	}
+
+	var sortByStart func(list []*TraceSpan)
+	sortByStart = func(list []*TraceSpan) {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].StartTime.Before(list[j].StartTime) })
+		for _, span := range list {
+			sortByStart(span.Children)
+		}
+	}
+	sortByStart(roots)
+	return roots
+}
@@ -121,6 +121,7 @@ export interface CallRequest {
   service: string
   method: string
   params: Record<string, any>
+  instance_key?: string     // 指定实例调用，为空时负载均衡
 }
 
 export interface CallResult {
@@ -130,6 +131,56 @@ export interface CallResult {
   error?: string
   duration?: number
   durationMs?: number
+  trace_id?: string         // 调用链 ID，可用 tracesApi.get 查看
+  error_info?: RPCErrorInfo // 失败时的结构化错误
+}
+
+export interface RPCErrorInfo {
+  code: string              // 例如 not_found、validation_failed、unavailable
+  message: string
+  details?: Record<string, any>
+  retryable?: boolean       // 为 true 时可以重试
+}
+
+export interface CallAllRequest {
+  service: string
+  method: string
+  params: Record<string, any>
+  timeout_ms?: number       // 收集回复的超时时间，默认 5 秒
+}
+
+export interface InstanceCallResult extends CallResult {
+  instance_key: string
+}
+
+export interface CallAllResult {
+  expected: number          // 在线实例数
+  received: number          // 收到的回复数
+  instances: InstanceCallResult[]
+  duration: number
+  trace_id?: string
+}
+
+// 调用链相关类型定义
+export interface TraceSpan {
+  trace_id: string
+  span_id: string
+  parent_span_id?: string
+  name: string              // 例如 $SRV.math-service.add
+  kind: 'client' | 'server' | 'internal'
+  service: string
+  start_time: string        // ISO 8601 格式时间戳
+  end_time: string
+  duration_ms: number
+  status: 'unset' | 'ok' | 'error'
+  status_message?: string
+  attributes?: Record<string, string>
+  children?: TraceSpan[]
+}
+
+export interface TraceTree {
+  trace_id: string
+  spans: TraceSpan[]        // 根 span，子 span 位于 children
 }
 
 // 实例相关类型定义
@@ -145,12 +196,15 @@ export interface Instance {
   first_seen: string        // ISO 8601 格式时间戳
   last_heartbeat: string    // ISO 8601 格式时间戳
   online: boolean
+  weight: number            // 流量权重
+  draining: boolean         // 是否正在排空
+  cert_expiry?: string      // TLS 证书到期时间（ISO 8601），未使用 TLS 时为空
   created_at: string
   updated_at: string
 }
 
 export interface InstanceControlResponse {
-  status: 'stopping' | 'restarting' | 'deleted'
+  status: 'stopping' | 'restarting' | 'deleted' | 'drain' | 'resume' | 'weight'
   instance: string
 }
 
@@ -193,7 +247,14 @@ export const eventsApi = {
 
 // 调用相关
 export const callApi = {
-  call: (data: CallRequest) => api.post<CallResult>('/call', data) as unknown as Promise<CallResult>
+  call: (data: CallRequest) => api.post<CallResult>('/call', data) as unknown as Promise<CallResult>,
+  // 调用服务的所有实例，收集每个实例的回复
+  callAll: (data: CallAllRequest) => api.post<CallAllResult>('/call/all', data) as unknown as Promise<CallAllResult>
+}
+
+// 调用链相关
+export const tracesApi = {
+  get: (traceId: string) => api.get<TraceTree>(`/traces/${traceId}`) as unknown as Promise<TraceTree>
 }
 
 // 实例相关 API
@@ -214,6 +275,18 @@ export const instancesApi = {
   restart: (key: string) =>
     api.post<InstanceControlResponse>(`/instances/${encodeURIComponent(key)}/restart`) as unknown as Promise<InstanceControlResponse>,
 
+  // 排空实例，不再接收新请求 (管理员权限)
+  drain: (key: string) =>
+    api.post<InstanceControlResponse>(`/instances/${encodeURIComponent(key)}/drain`) as unknown as Promise<InstanceControlResponse>,
+
+  // 恢复排空的实例 (管理员权限)
+  resume: (key: string) =>
+    api.post<InstanceControlResponse>(`/instances/${encodeURIComponent(key)}/resume`) as unknown as Promise<InstanceControlResponse>,
+
+  // 设置实例流量权重 (管理员权限)
+  setWeight: (key: string, weight: number) =>
+    api.post<InstanceControlResponse>(`/instances/${encodeURIComponent(key)}/weight`, { weight }) as unknown as Promise<InstanceControlResponse>,
+
   // 删除离线实例 (管理员权限)
   delete: (key: string) =>
     api.delete<InstanceControlResponse>(`/instances/${encodeURIComponent(key)}`) as unknown as Promise<InstanceControlResponse>
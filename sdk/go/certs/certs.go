@@ -0,0 +1,337 @@
+// Package certs is a small certificate authority for LightLink deployments.
+// It creates the CA and issues the NATS server certificate and per-service
+// client certificates, laid out in the nats-server/ and client/ directories
+// searched by types.DiscoverServerCerts and types.DiscoverClientCerts.
+//
+// Client certificates carry the service name as CN and DNS SAN, so the
+// verify_and_map option of nats-server.conf maps them to a user of that name.
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+)
+
+const (
+	// CACertFile and CAKeyFile are the CA files in the CA directory
+	CACertFile = "ca.crt"
+	CAKeyFile  = "ca.key"
+
+	// DefaultCAName is the CN of new CAs
+	DefaultCAName = "LightLink CA"
+	// DefaultClientName is the CN of the client certificate shared by all services
+	DefaultClientName = "lightlink-client"
+	// Organization is the O of all issued certificates
+	Organization = "LightLink"
+
+	// DefaultCAValidity is how long a new CA is valid
+	DefaultCAValidity = 10 * 365 * 24 * time.Hour
+	// DefaultValidity is how long issued certificates are valid
+	DefaultValidity = 2 * 365 * 24 * time.Hour
+)
+
+var (
+	// ErrCAExists is returned by InitCA when the directory already holds a CA
+	ErrCAExists = errors.New("CA already exists")
+	// ErrCertExists is returned by Issue when the output directory holds the
+	// current certificate of another name and Request.Force is not set
+	ErrCertExists = errors.New("directory holds the certificate of another name")
+)
+
+// Authority issues certificates signed by the CA stored in its directory
+type Authority struct {
+	dir     string
+	cert    *x509.Certificate
+	certPEM []byte
+	key     crypto.Signer
+
+	mu sync.Mutex // serializes index updates
+}
+
+// Request describes a certificate to issue
+type Request struct {
+	// Kind is types.ServerCerts or types.ClientCerts
+	Kind types.CertKind
+	// Name is the CN; it defaults to types.DefaultServerName for server
+	// certificates and DefaultClientName for client certificates
+	Name string
+	// Hosts are extra DNS names or IP addresses of server certificates
+	Hosts []string
+	// Validity defaults to DefaultValidity
+	Validity time.Duration
+	// OutDir receives the nats-server/ or client/ directory; "." when empty.
+	// Give every service its own OutDir.
+	OutDir string
+	// Force replaces the certificate of another name in the output directory
+	Force bool
+}
+
+// InitCA creates a CA in dir. It fails with ErrCAExists rather than
+// overwriting an existing CA, which would invalidate every issued certificate.
+func InitCA(dir, name string, validity time.Duration) (*Authority, error) {
+	if _, err := os.Stat(filepath.Join(dir, CAKeyFile)); err == nil {
+		return nil, fmt.Errorf("%w in %s", ErrCAExists, dir)
+	}
+	if name == "" {
+		name = DefaultCAName
+	}
+	if validity <= 0 {
+		validity = DefaultCAValidity
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate CA key: %w", err)
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name, Organization: []string{Organization}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(validity),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		MaxPathLenZero:        true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, fmt.Errorf("create CA certificate: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create CA directory: %w", err)
+	}
+	if err := writeKey(filepath.Join(dir, CAKeyFile), key); err != nil {
+		return nil, err
+	}
+	if err := writeFile(filepath.Join(dir, CACertFile), encodeCert(der), 0644); err != nil {
+		return nil, err
+	}
+	return LoadCA(dir)
+}
+
+// LoadCA loads the CA stored in dir
+func LoadCA(dir string) (*Authority, error) {
+	certPEM, err := os.ReadFile(filepath.Join(dir, CACertFile))
+	if err != nil {
+		return nil, fmt.Errorf("read CA certificate: %w", err)
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no certificate found in %s", filepath.Join(dir, CACertFile))
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse CA certificate: %w", err)
+	}
+
+	keyPEM, err := os.ReadFile(filepath.Join(dir, CAKeyFile))
+	if err != nil {
+		return nil, fmt.Errorf("read CA key: %w", err)
+	}
+	block, _ = pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("no key found in %s", filepath.Join(dir, CAKeyFile))
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse CA key: %w", err)
+	}
+	key, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("CA key cannot sign")
+	}
+
+	return &Authority{dir: dir, cert: cert, certPEM: certPEM, key: key}, nil
+}
+
+// Certificate returns the CA certificate
+func (a *Authority) Certificate() *x509.Certificate {
+	return a.cert
+}
+
+// Issue creates a key and certificate and writes them with the CA
+// certificate to OutDir/nats-server or OutDir/client. Files from an earlier
+// certificate of the same name in the directory are replaced and the earlier
+// entry is marked as replaced in the index; a certificate of another name is
+// only replaced with Request.Force, otherwise Issue fails with ErrCertExists.
+func (a *Authority) Issue(req Request) (*Issued, error) {
+	req, err := req.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate key: %w", err)
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: req.Name, Organization: []string{Organization}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(req.Validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		DNSNames:     []string{req.Name},
+	}
+	if template.NotAfter.After(a.cert.NotAfter) {
+		template.NotAfter = a.cert.NotAfter
+	}
+
+	if req.Kind == types.ServerCerts {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		template.DNSNames = append(template.DNSNames, "localhost")
+		template.IPAddresses = []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+		for _, host := range req.Hosts {
+			if ip := net.ParseIP(host); ip != nil {
+				template.IPAddresses = append(template.IPAddresses, ip)
+			} else {
+				template.DNSNames = append(template.DNSNames, host)
+			}
+		}
+	} else {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+
+	dir, err := filepath.Abs(filepath.Join(req.OutDir, outDirName(req.Kind)))
+	if err != nil {
+		return nil, err
+	}
+	if !req.Force {
+		if current, err := a.current(dir); err != nil {
+			return nil, err
+		} else if current != nil && current.Name != req.Name {
+			return nil, fmt.Errorf("%w: %s holds %q, not replacing it with %q", ErrCertExists, dir, current.Name, req.Name)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, key.Public(), a.key)
+	if err != nil {
+		return nil, fmt.Errorf("create certificate: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create certificate directory: %w", err)
+	}
+	// A service reloading between the writes fails to pair key and
+	// certificate, keeps the old pair and picks up the new one next time
+	if err := writeKey(filepath.Join(dir, string(req.Kind)+".key"), key); err != nil {
+		return nil, err
+	}
+	if err := writeFile(filepath.Join(dir, string(req.Kind)+".crt"), encodeCert(der), 0644); err != nil {
+		return nil, err
+	}
+	if err := writeFile(filepath.Join(dir, CACertFile), a.certPEM, 0644); err != nil {
+		return nil, err
+	}
+
+	issued := &Issued{
+		Kind:      req.Kind,
+		Name:      req.Name,
+		Serial:    fmt.Sprintf("%x", serial),
+		Hosts:     req.Hosts,
+		Dir:       dir,
+		NotBefore: template.NotBefore,
+		NotAfter:  template.NotAfter,
+	}
+	if err := a.record(issued); err != nil {
+		return nil, err
+	}
+	return issued, nil
+}
+
+// withDefaults validates the request and fills in the defaults
+func (r Request) withDefaults() (Request, error) {
+	switch r.Kind {
+	case types.ServerCerts:
+		if r.Name == "" {
+			r.Name = types.DefaultServerName
+		}
+	case types.ClientCerts:
+		if r.Name == "" {
+			r.Name = DefaultClientName
+		}
+		if len(r.Hosts) > 0 {
+			return r, errors.New("hosts are only valid for server certificates")
+		}
+	default:
+		return r, fmt.Errorf("unknown certificate kind %q", r.Kind)
+	}
+	if r.Validity <= 0 {
+		r.Validity = DefaultValidity
+	}
+	if r.OutDir == "" {
+		r.OutDir = "."
+	}
+	return r, nil
+}
+
+// outDirName returns the directory certificates of kind are written to,
+// matching the directories searched by certificate discovery
+func outDirName(kind types.CertKind) string {
+	if kind == types.ServerCerts {
+		return filepath.Base(types.DefaultServerCertDir)
+	}
+	return filepath.Base(types.DefaultClientCertDir)
+}
+
+// newSerial returns a random 128 bit serial number
+func newSerial() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("generate serial number: %w", err)
+	}
+	return serial, nil
+}
+
+// encodeCert returns the PEM encoding of a DER certificate
+func encodeCert(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// writeKey writes key as PKCS#8 PEM readable only by the owner
+func writeKey(path string, key crypto.Signer) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("encode key: %w", err)
+	}
+	return writeFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+}
+
+// writeFile replaces path atomically, so watchers never read a partial file
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	os.Remove(tmp) // a leftover file would keep its permissions
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
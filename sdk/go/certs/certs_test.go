@@ -0,0 +1,185 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+)
+
+func TestIssueAndDiscover(t *testing.T) {
+	dir := t.TempDir()
+	ca, err := InitCA(filepath.Join(dir, "ca"), "", 0)
+	if err != nil {
+		t.Fatalf("InitCA failed: %v", err)
+	}
+	if _, err := InitCA(filepath.Join(dir, "ca"), "", 0); !errors.Is(err, ErrCAExists) {
+		t.Errorf("Expected ErrCAExists, got %v", err)
+	}
+
+	deploy := filepath.Join(dir, "deploy")
+	if _, err := ca.Issue(Request{Kind: types.ServerCerts, Hosts: []string{"10.0.0.5", "nats.example.com"}, OutDir: deploy}); err != nil {
+		t.Fatalf("Issue server failed: %v", err)
+	}
+	if _, err := ca.Issue(Request{Kind: types.ClientCerts, Name: "math-service", OutDir: deploy}); err != nil {
+		t.Fatalf("Issue client failed: %v", err)
+	}
+
+	// The layout is the one certificate discovery searches
+	server, err := types.DiscoverCerts(types.ServerCerts, types.CertDiscoveryOptions{Dirs: []string{filepath.Join(deploy, "nats-server")}})
+	if err != nil {
+		t.Fatalf("DiscoverCerts server failed: %v", err)
+	}
+	client, err := types.DiscoverCerts(types.ClientCerts, types.CertDiscoveryOptions{Dirs: []string{filepath.Join(deploy, "client")}})
+	if err != nil {
+		t.Fatalf("DiscoverCerts client failed: %v", err)
+	}
+
+	clientCert, err := tls.LoadX509KeyPair(client.CertFile, client.KeyFile)
+	if err != nil {
+		t.Fatalf("Load client pair failed: %v", err)
+	}
+	if leaf := clientCert.Leaf; leaf.Subject.CommonName != "math-service" || len(leaf.DNSNames) != 1 || leaf.DNSNames[0] != "math-service" {
+		t.Errorf("Unexpected client subject %s, SANs %v", leaf.Subject, leaf.DNSNames)
+	}
+	if info, _ := os.Stat(client.KeyFile); info.Mode().Perm()&0077 != 0 && os.PathSeparator == '/' {
+		t.Errorf("Key file readable by others: %v", info.Mode())
+	}
+
+	// Mutual TLS with the issued pair, by IP and by name
+	serverCert, err := tls.LoadX509KeyPair(server.CertFile, server.KeyFile)
+	if err != nil {
+		t.Fatalf("Load server pair failed: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Certificate())
+	for _, serverName := range []string{"10.0.0.5", "nats.example.com", "nats-server"} {
+		if err := handshake(serverCert, clientCert, roots, serverName); err != nil {
+			t.Errorf("Handshake as %s failed: %v", serverName, err)
+		}
+	}
+	if err := handshake(serverCert, clientCert, roots, "other.example.com"); err == nil {
+		t.Error("Expected handshake to fail for a name not in the SANs")
+	}
+}
+
+func TestRenewAndList(t *testing.T) {
+	dir := t.TempDir()
+	ca, err := InitCA(filepath.Join(dir, "ca"), "Test CA", 0)
+	if err != nil {
+		t.Fatalf("InitCA failed: %v", err)
+	}
+	first, _ := ca.Issue(Request{Kind: types.ClientCerts, Name: "math-service", Validity: 10 * 24 * time.Hour, OutDir: filepath.Join(dir, "math")})
+	ca.Issue(Request{Kind: types.ClientCerts, Name: "text-service", OutDir: filepath.Join(dir, "text")})
+
+	// Reloaded from disk, as the command line tool does
+	ca, err = LoadCA(filepath.Join(dir, "ca"))
+	if err != nil {
+		t.Fatalf("LoadCA failed: %v", err)
+	}
+	renewed, err := ca.RenewExpiring(30*24*time.Hour, 0)
+	if err != nil {
+		t.Fatalf("RenewExpiring failed: %v", err)
+	}
+	if len(renewed) != 1 || renewed[0].Name != "math-service" || renewed[0].Dir != first.Dir {
+		t.Fatalf("Expected math-service to be renewed in place, got %+v", renewed)
+	}
+	if renewed[0].Serial == first.Serial || renewed[0].NotAfter.Before(first.NotAfter) {
+		t.Errorf("Expected a new certificate, got %+v", renewed[0])
+	}
+
+	pair, err := tls.LoadX509KeyPair(filepath.Join(first.Dir, "client.crt"), filepath.Join(first.Dir, "client.key"))
+	if err != nil {
+		t.Fatalf("Load renewed pair failed: %v", err)
+	}
+	if got := pair.Leaf.SerialNumber.Text(16); got != renewed[0].Serial {
+		t.Errorf("Expected serial %s on disk, got %s", renewed[0].Serial, got)
+	}
+
+	entries, err := ca.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	current := 0
+	for _, entry := range entries {
+		if entry.Current() {
+			current++
+		} else if entry.Serial != first.Serial || entry.ReplacedBy != renewed[0].Serial {
+			t.Errorf("Unexpected replaced entry %+v", entry)
+		}
+	}
+	if len(entries) != 3 || current != 2 {
+		t.Errorf("Expected 3 entries with 2 current, got %d with %d", len(entries), current)
+	}
+
+	if renewed, err := ca.Renew("unknown", 0); err != nil || len(renewed) != 0 {
+		t.Errorf("Expected nothing renewed, got %v, %v", renewed, err)
+	}
+}
+
+func TestIssueKeepsOtherServices(t *testing.T) {
+	dir := t.TempDir()
+	ca, err := InitCA(filepath.Join(dir, "ca"), "", 0)
+	if err != nil {
+		t.Fatalf("InitCA failed: %v", err)
+	}
+
+	math, err := ca.Issue(Request{Kind: types.ClientCerts, Name: "math-service", OutDir: filepath.Join(dir, "math")})
+	if err != nil {
+		t.Fatalf("Issue math-service failed: %v", err)
+	}
+	text, err := ca.Issue(Request{Kind: types.ClientCerts, Name: "text-service", OutDir: filepath.Join(dir, "text")})
+	if err != nil {
+		t.Fatalf("Issue text-service failed: %v", err)
+	}
+	// Issued into the directory of another service by mistake
+	if _, err := ca.Issue(Request{Kind: types.ClientCerts, Name: "text-service", OutDir: filepath.Join(dir, "math")}); !errors.Is(err, ErrCertExists) {
+		t.Errorf("Expected ErrCertExists, got %v", err)
+	}
+
+	for name, issued := range map[string]*Issued{"math-service": math, "text-service": text} {
+		pair, err := tls.LoadX509KeyPair(filepath.Join(issued.Dir, "client.crt"), filepath.Join(issued.Dir, "client.key"))
+		if err != nil {
+			t.Fatalf("Load %s pair failed: %v", name, err)
+		}
+		if cn := pair.Leaf.Subject.CommonName; cn != name {
+			t.Errorf("Expected %s in %s, got %s", name, issued.Dir, cn)
+		}
+	}
+
+	forced, err := ca.Issue(Request{Kind: types.ClientCerts, Name: "text-service", OutDir: filepath.Join(dir, "math"), Force: true})
+	if err != nil || forced.Dir != math.Dir {
+		t.Fatalf("Forced issue returned %+v, %v", forced, err)
+	}
+	entries, _ := ca.List()
+	for _, entry := range entries {
+		if entry.Serial == math.Serial && entry.ReplacedBy != forced.Serial {
+			t.Errorf("Expected math-service to be replaced, got %+v", entry)
+		}
+	}
+}
+
+// handshake runs a mutual TLS handshake over a pipe
+func handshake(serverCert, clientCert tls.Certificate, roots *x509.CertPool, serverName string) error {
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	go tls.Server(serverConn, &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    roots,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}).Handshake()
+
+	return tls.Client(clientConn, &tls.Config{
+		Certificates: []tls.Certificate{clientCert},
+		RootCAs:      roots,
+		ServerName:   serverName,
+	}).Handshake()
+}
@@ -0,0 +1,150 @@
+package certs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+)
+
+// IndexFile is the file in the CA directory listing the issued certificates
+const IndexFile = "index.json"
+
+// Issued is an entry of the certificate index
+type Issued struct {
+	Kind      types.CertKind `json:"kind"`
+	Name      string         `json:"name"`
+	Serial    string         `json:"serial"`
+	Hosts     []string       `json:"hosts,omitempty"`
+	Dir       string         `json:"dir"` // absolute directory holding the files
+	NotBefore time.Time      `json:"not_before"`
+	NotAfter  time.Time      `json:"not_after"`
+	// ReplacedBy is the serial of the certificate that replaced this one in Dir
+	ReplacedBy string `json:"replaced_by,omitempty"`
+}
+
+// Current reports whether the certificate is still deployed in its directory
+func (i *Issued) Current() bool {
+	return i.ReplacedBy == ""
+}
+
+// Expired reports whether the certificate is no longer valid
+func (i *Issued) Expired() bool {
+	return time.Now().After(i.NotAfter)
+}
+
+// List returns the issued certificates ordered by name, then issue time
+func (a *Authority) List() ([]Issued, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.readIndex()
+}
+
+// Renew issues new certificates for the current certificates named name,
+// keeping their kind, hosts and directory. validity defaults to the validity
+// of the certificate renewed.
+func (a *Authority) Renew(name string, validity time.Duration) ([]*Issued, error) {
+	return a.renewMatching(validity, func(i *Issued) bool { return i.Name == name })
+}
+
+// RenewExpiring renews the current certificates expiring within the given duration
+func (a *Authority) RenewExpiring(within, validity time.Duration) ([]*Issued, error) {
+	deadline := time.Now().Add(within)
+	return a.renewMatching(validity, func(i *Issued) bool { return i.NotAfter.Before(deadline) })
+}
+
+// renewMatching renews the current certificates match selects
+func (a *Authority) renewMatching(validity time.Duration, match func(*Issued) bool) ([]*Issued, error) {
+	entries, err := a.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var renewed []*Issued
+	for i := range entries {
+		entry := &entries[i]
+		if !entry.Current() || !match(entry) {
+			continue
+		}
+		entryValidity := validity
+		if entryValidity <= 0 {
+			entryValidity = entry.NotAfter.Sub(entry.NotBefore) - time.Hour
+		}
+		issued, err := a.Issue(Request{
+			Kind:     entry.Kind,
+			Name:     entry.Name,
+			Hosts:    entry.Hosts,
+			Validity: entryValidity,
+			OutDir:   filepath.Dir(entry.Dir),
+		})
+		if err != nil {
+			return renewed, fmt.Errorf("renew %s certificate %s: %w", entry.Kind, entry.Name, err)
+		}
+		renewed = append(renewed, issued)
+	}
+	return renewed, nil
+}
+
+// current returns the certificate deployed in dir, nil if none was issued there
+func (a *Authority) current(dir string) (*Issued, error) {
+	entries, err := a.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].Current() && entries[i].Dir == dir {
+			return &entries[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// record adds issued to the index, marking the certificate it replaces
+func (a *Authority) record(issued *Issued) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entries, err := a.readIndex()
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		if entries[i].Current() && entries[i].Dir == issued.Dir {
+			entries[i].ReplacedBy = issued.Serial
+		}
+	}
+	entries = append(entries, *issued)
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(a.dir, IndexFile), data, 0644)
+}
+
+// readIndex reads the index, empty when nothing was issued yet
+func (a *Authority) readIndex() ([]Issued, error) {
+	data, err := os.ReadFile(filepath.Join(a.dir, IndexFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read certificate index: %w", err)
+	}
+
+	var entries []Issued
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse certificate index: %w", err)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].NotBefore.Before(entries[j].NotBefore)
+	})
+	return entries, nil
+}
@@ -1,10 +1,10 @@
 package client
 
 import (
-	"encoding/base64"
 	"fmt"
 
 	"github.com/LiteHomeLab/light_link/sdk/go/backup"
+	"github.com/LiteHomeLab/light_link/sdk/go/codec"
 	"github.com/LiteHomeLab/light_link/sdk/go/types"
 )
 
@@ -13,7 +13,7 @@ func (c *Client) CreateBackup(serviceName, backupName string, data []byte) (int,
 	args := map[string]interface{}{
 		"service_name": serviceName,
 		"backup_name":  backupName,
-		"data":         base64.StdEncoding.EncodeToString(data),
+		"data":         data,
 	}
 
 	result, err := c.Call("backup-agent", "backup.create", args)
@@ -21,7 +21,7 @@ func (c *Client) CreateBackup(serviceName, backupName string, data []byte) (int,
 		return 0, err
 	}
 
-	versionFloat, ok := result["version"].(float64)
+	versionFloat, ok := codec.Float64(result["version"])
 	if !ok {
 		return 0, nil
 	}
@@ -34,7 +34,7 @@ func (c *Client) CreateBackupWithMaxVersions(serviceName, backupName string, dat
 	args := map[string]interface{}{
 		"service_name": serviceName,
 		"backup_name":  backupName,
-		"data":         base64.StdEncoding.EncodeToString(data),
+		"data":         data,
 		"max_versions": float64(maxVersions),
 	}
 
@@ -43,7 +43,7 @@ func (c *Client) CreateBackupWithMaxVersions(serviceName, backupName string, dat
 		return 0, err
 	}
 
-	versionFloat, ok := result["version"].(float64)
+	versionFloat, ok := codec.Float64(result["version"])
 	if !ok {
 		return 0, nil
 	}
@@ -56,7 +56,7 @@ func (c *Client) CreateIncrementalBackup(serviceName, backupName string, data []
 	args := map[string]interface{}{
 		"service_name": serviceName,
 		"backup_name":  backupName,
-		"data":         base64.StdEncoding.EncodeToString(data),
+		"data":         data,
 	}
 
 	result, err := c.Call("backup-agent", "backup.create_incremental", args)
@@ -64,7 +64,7 @@ func (c *Client) CreateIncrementalBackup(serviceName, backupName string, data []
 		return 0, err
 	}
 
-	versionFloat, ok := result["version"].(float64)
+	versionFloat, ok := codec.Float64(result["version"])
 	if !ok {
 		return 0, nil
 	}
@@ -97,16 +97,16 @@ func (c *Client) ListBackups(serviceName, backupName string) ([]types.BackupVers
 		}
 
 		version := types.BackupVersion{}
-		if val, ok := vMap["version"].(float64); ok {
+		if val, ok := codec.Float64(vMap["version"]); ok {
 			version.Version = int(val)
 		}
 		if val, ok := vMap["type"].(string); ok {
 			version.Type = val
 		}
-		if val, ok := vMap["base_version"].(float64); ok {
+		if val, ok := codec.Float64(vMap["base_version"]); ok {
 			version.BaseVersion = int(val)
 		}
-		if val, ok := vMap["file_size"].(float64); ok {
+		if val, ok := codec.Float64(vMap["file_size"]); ok {
 			version.FileSize = int64(val)
 		}
 		if val, ok := vMap["checksum"].(string); ok {
@@ -132,12 +132,12 @@ func (c *Client) GetBackup(serviceName, backupName string, version int) ([]byte,
 		return nil, err
 	}
 
-	dataBase64, ok := result["data"].(string)
+	dataRaw, ok := result["data"]
 	if !ok {
 		return nil, nil
 	}
 
-	return base64.StdEncoding.DecodeString(dataBase64)
+	return codec.Bytes(dataRaw)
 }
 
 // DeleteBackup deletes a specific backup version
@@ -220,7 +220,7 @@ func (c *Client) UploadChunkedWithSplitter(serviceName, backupName string, split
 	args := map[string]interface{}{
 		"service_name": serviceName,
 		"backup_name":  backupName,
-		"metadata":     base64.StdEncoding.EncodeToString(metadataBytes),
+		"metadata":     metadataBytes,
 	}
 
 	result, err := c.Call("backup-agent", "backup.upload_init", args)
@@ -233,7 +233,7 @@ func (c *Client) UploadChunkedWithSplitter(serviceName, backupName string, split
 		return nil, fmt.Errorf("invalid transfer_id response")
 	}
 
-	totalChunksFloat, ok := result["total_chunks"].(float64)
+	totalChunksFloat, ok := codec.Float64(result["total_chunks"])
 	if !ok {
 		return nil, fmt.Errorf("invalid total_chunks response")
 	}
@@ -255,7 +255,7 @@ func (h *ChunkedUploadHandle) UploadNextChunk(chunk backup.Chunk) error {
 
 	args := map[string]interface{}{
 		"transfer_id": h.transferID,
-		"chunk":       base64.StdEncoding.EncodeToString(chunkBytes),
+		"chunk":       chunkBytes,
 	}
 
 	_, err = h.client.Call("backup-agent", "backup.upload_chunk", args)
@@ -289,7 +289,7 @@ func (h *ChunkedUploadHandle) Complete() (int, error) {
 		return 0, err
 	}
 
-	versionFloat, ok := result["version"].(float64)
+	versionFloat, ok := codec.Float64(result["version"])
 	if !ok {
 		return 0, fmt.Errorf("invalid version response")
 	}
@@ -343,17 +343,17 @@ func (c *Client) DownloadChunkedWithSize(serviceName, backupName string, version
 		return nil, fmt.Errorf("invalid transfer_id response")
 	}
 
-	totalChunksFloat, ok := result["total_chunks"].(float64)
+	totalChunksFloat, ok := codec.Float64(result["total_chunks"])
 	if !ok {
 		return nil, fmt.Errorf("invalid total_chunks response")
 	}
 
-	metadataBase64, ok := result["metadata"].(string)
+	metadataRaw, ok := result["metadata"]
 	if !ok {
 		return nil, fmt.Errorf("invalid metadata response")
 	}
 
-	metadataBytes, err := base64.StdEncoding.DecodeString(metadataBase64)
+	metadataBytes, err := codec.Bytes(metadataRaw)
 	if err != nil {
 		return nil, fmt.Errorf("decode metadata: %w", err)
 	}
@@ -383,12 +383,12 @@ func (h *ChunkedDownloadHandle) DownloadChunk(chunkIndex int) (*backup.Chunk, er
 		return nil, err
 	}
 
-	chunkBase64, ok := result["chunk"].(string)
+	chunkRaw, ok := result["chunk"]
 	if !ok {
 		return nil, fmt.Errorf("invalid chunk response")
 	}
 
-	chunkBytes, err := base64.StdEncoding.DecodeString(chunkBase64)
+	chunkBytes, err := codec.Bytes(chunkRaw)
 	if err != nil {
 		return nil, fmt.Errorf("decode chunk: %w", err)
 	}
@@ -0,0 +1,354 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WQGroup/logger"
+)
+
+// ErrCircuitOpen is returned when a call is rejected by an open circuit breaker
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of a circuit breaker
+type BreakerState int
+
+const (
+	// BreakerClosed lets all calls through
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects all calls until the open timeout has passed
+	BreakerOpen
+	// BreakerHalfOpen lets a limited number of probe calls through
+	BreakerHalfOpen
+)
+
+// String returns the name of the state
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// MarshalText encodes the state as its name
+func (s BreakerState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// CircuitOpenError is returned when a call to service.method is rejected.
+// It matches ErrCircuitOpen with errors.Is.
+type CircuitOpenError struct {
+	Service    string
+	Method     string
+	RetryAfter time.Duration
+}
+
+// Error implements error
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s for %s.%s, retry after %v", ErrCircuitOpen, e.Service, e.Method, e.RetryAfter)
+}
+
+// Unwrap returns ErrCircuitOpen
+func (e *CircuitOpenError) Unwrap() error {
+	return ErrCircuitOpen
+}
+
+// CircuitBreakerConfig configures the per service and method circuit breakers
+type CircuitBreakerConfig struct {
+	// WindowSize is the number of most recent calls the failure ratio is computed over
+	WindowSize int
+	// MinRequests is the number of calls in the window needed before the breaker can open
+	MinRequests int
+	// FailureRatio opens the breaker when failures/calls in the window reaches it
+	FailureRatio float64
+	// OpenTimeout is how long the breaker stays open before sending probes
+	OpenTimeout time.Duration
+	// HalfOpenProbes is the number of concurrent probe calls allowed while half-open
+	HalfOpenProbes int
+	// IsFailure decides whether an error counts as a failure; nil uses IsRetryable,
+	// so only transport failures count and errors returned by handlers do not
+	IsFailure func(err error) bool
+	// OnStateChange is called after a breaker changes state, in the order of
+	// the changes and on the goroutine of the call causing them
+	OnStateChange func(service, method string, from, to BreakerState)
+}
+
+// DefaultCircuitBreakerConfig returns the default circuit breaker configuration
+func DefaultCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		WindowSize:     20,
+		MinRequests:    10,
+		FailureRatio:   0.5,
+		OpenTimeout:    10 * time.Second,
+		HalfOpenProbes: 1,
+	}
+}
+
+// WithCircuitBreaker enables a circuit breaker per service and method.
+// Zero values in config select the defaults of DefaultCircuitBreakerConfig.
+func WithCircuitBreaker(config CircuitBreakerConfig) Option {
+	return func(c *Client) error {
+		defaults := DefaultCircuitBreakerConfig()
+		if config.WindowSize <= 0 {
+			config.WindowSize = defaults.WindowSize
+		}
+		if config.MinRequests <= 0 {
+			config.MinRequests = defaults.MinRequests
+		}
+		if config.MinRequests > config.WindowSize {
+			config.MinRequests = config.WindowSize
+		}
+		if config.FailureRatio <= 0 || config.FailureRatio > 1 {
+			config.FailureRatio = defaults.FailureRatio
+		}
+		if config.OpenTimeout <= 0 {
+			config.OpenTimeout = defaults.OpenTimeout
+		}
+		if config.HalfOpenProbes <= 0 {
+			config.HalfOpenProbes = defaults.HalfOpenProbes
+		}
+		if config.IsFailure == nil {
+			config.IsFailure = IsRetryable
+		}
+		c.breakerConfig = &config
+		c.breakers = make(map[string]*circuitBreaker)
+		return nil
+	}
+}
+
+// BreakerSnapshot is the state of one circuit breaker at a point in time
+type BreakerSnapshot struct {
+	Service  string       `json:"service"`
+	Method   string       `json:"method"`
+	State    BreakerState `json:"state"`
+	Requests int          `json:"requests"`
+	Failures int          `json:"failures"`
+	OpenedAt time.Time    `json:"opened_at"` // zero while closed
+}
+
+// BreakerStates returns a snapshot of all circuit breakers sorted by service and method.
+// It returns nil if WithCircuitBreaker was not used.
+func (c *Client) BreakerStates() []BreakerSnapshot {
+	if c.breakerConfig == nil {
+		return nil
+	}
+
+	c.breakerMu.Lock()
+	breakers := make([]*circuitBreaker, 0, len(c.breakers))
+	for _, b := range c.breakers {
+		breakers = append(breakers, b)
+	}
+	c.breakerMu.Unlock()
+
+	snapshots := make([]BreakerSnapshot, 0, len(breakers))
+	for _, b := range breakers {
+		snapshots = append(snapshots, b.snapshot())
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		if snapshots[i].Service != snapshots[j].Service {
+			return snapshots[i].Service < snapshots[j].Service
+		}
+		return snapshots[i].Method < snapshots[j].Method
+	})
+	return snapshots
+}
+
+// breaker returns the circuit breaker of service.method, or nil if breakers are disabled
+func (c *Client) breaker(service, method string) *circuitBreaker {
+	if c.breakerConfig == nil {
+		return nil
+	}
+
+	key := service + "." + method
+	c.breakerMu.Lock()
+	defer c.breakerMu.Unlock()
+
+	b, ok := c.breakers[key]
+	if !ok {
+		b = newCircuitBreaker(service, method, c.breakerConfig)
+		c.breakers[key] = b
+	}
+	return b
+}
+
+// circuitBreaker tracks the outcome of the most recent calls to one service method
+type circuitBreaker struct {
+	service string
+	method  string
+	config  *CircuitBreakerConfig
+
+	mu       sync.Mutex
+	state    BreakerState
+	outcomes []bool // ring buffer, true means failure
+	next     int
+	count    int
+	failures int
+	openedAt time.Time
+	probes   int
+	// generation changes with every state change, so outcomes of calls
+	// allowed in an earlier state are ignored
+	generation uint64
+	changes    []breakerChange // state changes not yet passed to OnStateChange
+
+	notifyMu sync.Mutex // serializes OnStateChange calls
+}
+
+// breakerTicket is handed out by allow and passed back to record
+type breakerTicket struct {
+	generation uint64
+}
+
+// breakerChange is a state change waiting to be passed to OnStateChange
+type breakerChange struct {
+	from, to BreakerState
+}
+
+// newCircuitBreaker creates a closed circuit breaker
+func newCircuitBreaker(service, method string, config *CircuitBreakerConfig) *circuitBreaker {
+	return &circuitBreaker{
+		service:  service,
+		method:   method,
+		config:   config,
+		outcomes: make([]bool, config.WindowSize),
+	}
+}
+
+// allow reports whether a call may proceed. Every allowed call must be
+// followed by exactly one call to record with the returned ticket.
+func (b *circuitBreaker) allow() (breakerTicket, error) {
+	defer b.notify()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen {
+		elapsed := time.Since(b.openedAt)
+		if elapsed < b.config.OpenTimeout {
+			return breakerTicket{}, &CircuitOpenError{Service: b.service, Method: b.method, RetryAfter: b.config.OpenTimeout - elapsed}
+		}
+		b.setStateLocked(BreakerHalfOpen)
+	}
+
+	if b.state == BreakerHalfOpen {
+		if b.probes >= b.config.HalfOpenProbes {
+			return breakerTicket{}, &CircuitOpenError{Service: b.service, Method: b.method}
+		}
+		b.probes++
+	}
+	return breakerTicket{generation: b.generation}, nil
+}
+
+// record records the outcome of an allowed call. Outcomes of calls allowed
+// before the last state change are ignored: a slow call started while closed
+// does not decide a probe.
+func (b *circuitBreaker) record(ticket breakerTicket, err error) {
+	failed := err != nil && b.config.IsFailure(err)
+
+	defer b.notify()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ticket.generation != b.generation {
+		return
+	}
+	switch b.state {
+	case BreakerHalfOpen:
+		if b.probes > 0 {
+			b.probes--
+		}
+		if failed {
+			b.setStateLocked(BreakerOpen)
+		} else {
+			b.setStateLocked(BreakerClosed)
+		}
+	case BreakerClosed:
+		b.addOutcomeLocked(failed)
+		if b.count >= b.config.MinRequests &&
+			float64(b.failures)/float64(b.count) >= b.config.FailureRatio {
+			b.setStateLocked(BreakerOpen)
+		}
+	}
+}
+
+// addOutcomeLocked adds an outcome to the window. Caller must hold mu.
+func (b *circuitBreaker) addOutcomeLocked(failed bool) {
+	if b.count == len(b.outcomes) {
+		if b.outcomes[b.next] {
+			b.failures--
+		}
+	} else {
+		b.count++
+	}
+	b.outcomes[b.next] = failed
+	if failed {
+		b.failures++
+	}
+	b.next = (b.next + 1) % len(b.outcomes)
+}
+
+// setStateLocked moves the breaker to state. Caller must hold mu.
+func (b *circuitBreaker) setStateLocked(state BreakerState) {
+	from := b.state
+	if from == state {
+		return
+	}
+	b.state = state
+	b.generation++
+
+	switch state {
+	case BreakerOpen:
+		b.openedAt = time.Now()
+		b.probes = 0
+		logger.Warnf("Circuit breaker for %s.%s opened", b.service, b.method)
+	case BreakerClosed:
+		b.next, b.count, b.failures = 0, 0, 0
+		b.probes = 0
+		logger.Infof("Circuit breaker for %s.%s closed", b.service, b.method)
+	}
+
+	if b.config.OnStateChange != nil {
+		b.changes = append(b.changes, breakerChange{from: from, to: state})
+	}
+}
+
+// notify passes the pending state changes to OnStateChange. It is called
+// without holding mu; notifyMu keeps the changes in order across goroutines.
+func (b *circuitBreaker) notify() {
+	b.notifyMu.Lock()
+	defer b.notifyMu.Unlock()
+
+	b.mu.Lock()
+	changes := b.changes
+	b.changes = nil
+	b.mu.Unlock()
+
+	for _, change := range changes {
+		b.config.OnStateChange(b.service, b.method, change.from, change.to)
+	}
+}
+
+// snapshot returns the current state of the breaker
+func (b *circuitBreaker) snapshot() BreakerSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	snapshot := BreakerSnapshot{
+		Service:  b.service,
+		Method:   b.method,
+		State:    b.state,
+		Requests: b.count,
+		Failures: b.failures,
+	}
+	if b.state != BreakerClosed {
+		snapshot.OpenedAt = b.openedAt
+	}
+	return snapshot
+}
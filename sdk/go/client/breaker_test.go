@@ -0,0 +1,172 @@
+package client
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func newTestBreaker(t *testing.T, config CircuitBreakerConfig) *circuitBreaker {
+	c := &Client{}
+	if err := WithCircuitBreaker(config)(c); err != nil {
+		t.Fatalf("WithCircuitBreaker failed: %v", err)
+	}
+	return c.breaker("svc", "method")
+}
+
+func TestCircuitBreakerOpens(t *testing.T) {
+	b := newTestBreaker(t, CircuitBreakerConfig{WindowSize: 4, MinRequests: 4, FailureRatio: 0.5, OpenTimeout: time.Hour})
+
+	outcomes := []error{nil, nats.ErrNoResponders, nil, nats.ErrTimeout}
+	for _, err := range outcomes {
+		ticket, allowErr := b.allow()
+		if allowErr != nil {
+			t.Fatalf("Expected call to be allowed, got %v", allowErr)
+		}
+		b.record(ticket, err)
+	}
+
+	_, err := b.allow()
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+	var openErr *CircuitOpenError
+	if !errors.As(err, &openErr) || openErr.Service != "svc" || openErr.Method != "method" {
+		t.Errorf("Expected CircuitOpenError for svc.method, got %v", err)
+	}
+}
+
+func TestCircuitBreakerIgnoresHandlerErrors(t *testing.T) {
+	b := newTestBreaker(t, CircuitBreakerConfig{WindowSize: 2, MinRequests: 2, OpenTimeout: time.Hour})
+
+	for i := 0; i < 4; i++ {
+		ticket, err := b.allow()
+		if err != nil {
+			t.Fatalf("Expected call to be allowed, got %v", err)
+		}
+		b.record(ticket, errors.New("RPC error: invalid input"))
+	}
+	if state := b.snapshot().State; state != BreakerClosed {
+		t.Errorf("Expected closed breaker, got %s", state)
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	b := newTestBreaker(t, CircuitBreakerConfig{WindowSize: 1, MinRequests: 1, OpenTimeout: 20 * time.Millisecond})
+
+	ticket, _ := b.allow()
+	b.record(ticket, nats.ErrNoResponders)
+	if state := b.snapshot().State; state != BreakerOpen {
+		t.Fatalf("Expected open breaker, got %s", state)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	// Only one probe is let through while half-open
+	probe, err := b.allow()
+	if err != nil {
+		t.Fatalf("Expected probe to be allowed, got %v", err)
+	}
+	if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected second probe to be rejected, got %v", err)
+	}
+
+	// A failed probe re-opens the breaker
+	b.record(probe, nats.ErrTimeout)
+	if state := b.snapshot().State; state != BreakerOpen {
+		t.Fatalf("Expected open breaker after failed probe, got %s", state)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	// A successful probe closes it
+	probe, err = b.allow()
+	if err != nil {
+		t.Fatalf("Expected probe to be allowed, got %v", err)
+	}
+	b.record(probe, nil)
+	if state := b.snapshot().State; state != BreakerClosed {
+		t.Errorf("Expected closed breaker after successful probe, got %s", state)
+	}
+}
+
+func TestCircuitBreakerIgnoresStaleOutcomes(t *testing.T) {
+	var changes []string
+	b := newTestBreaker(t, CircuitBreakerConfig{
+		WindowSize:  1,
+		MinRequests: 1,
+		OpenTimeout: 20 * time.Millisecond,
+		OnStateChange: func(service, method string, from, to BreakerState) {
+			changes = append(changes, from.String()+">"+to.String())
+		},
+	})
+
+	slow, _ := b.allow()
+	failed, _ := b.allow()
+	b.record(failed, nats.ErrNoResponders)
+	time.Sleep(30 * time.Millisecond)
+	probe, err := b.allow()
+	if err != nil {
+		t.Fatalf("Expected probe to be allowed, got %v", err)
+	}
+
+	// The slow call started while closed does not decide the probe
+	b.record(slow, nil)
+	if state := b.snapshot().State; state != BreakerHalfOpen {
+		t.Fatalf("Expected half-open breaker after a stale outcome, got %s", state)
+	}
+	if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected the probe slot to stay taken, got %v", err)
+	}
+
+	b.record(probe, nil)
+	if state := b.snapshot().State; state != BreakerClosed {
+		t.Errorf("Expected closed breaker after the probe, got %s", state)
+	}
+
+	// Delivered synchronously and in order
+	want := []string{"closed>open", "open>half-open", "half-open>closed"}
+	if len(changes) != len(want) {
+		t.Fatalf("Expected changes %v, got %v", want, changes)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("Expected changes %v, got %v", want, changes)
+			break
+		}
+	}
+}
+
+func TestCallFailsFastWithOpenBreaker(t *testing.T) {
+	c, err := NewClient(nats.DefaultURL, WithCircuitBreaker(CircuitBreakerConfig{
+		WindowSize:  3,
+		MinRequests: 3,
+		OpenTimeout: time.Minute,
+	}))
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer c.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Call("no-such-breaker-service", "ping", nil); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Breaker opened too early on call %d", i+1)
+		}
+	}
+
+	start := time.Now()
+	_, err = c.Call("no-such-breaker-service", "ping", nil)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Errorf("Expected open breaker to fail fast, took %v", time.Since(start))
+	}
+
+	states := c.BreakerStates()
+	if len(states) != 1 || states[0].Service != "no-such-breaker-service" || states[0].State != BreakerOpen {
+		t.Errorf("Unexpected breaker states: %+v", states)
+	}
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/tracing"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultCallAllTimeout is how long CallAll collects replies when no timeout is given
+const DefaultCallAllTimeout = 2 * time.Second
+
+// CallAllOptions configures a scatter-gather call
+type CallAllOptions struct {
+	// Timeout bounds how long replies are collected; zero uses DefaultCallAllTimeout
+	Timeout time.Duration
+	// Expected stops collecting once this many replies arrived; zero waits for the timeout
+	Expected int
+}
+
+// InstanceResult is the reply of one service instance to a scatter-gather call
+type InstanceResult struct {
+	InstanceKey string
+	Result      map[string]interface{}
+	Err         error
+}
+
+// CallAll calls method on every instance of service and collects the replies.
+// It returns once opts.Expected replies arrived or the timeout passed; missing
+// replies are not an error, callers compare len(results) with what they expect.
+// Without running instances it returns no results at once.
+func (c *Client) CallAll(service, method string, args map[string]interface{}, opts *CallAllOptions) ([]InstanceResult, error) {
+	return c.CallAllContext(context.Background(), service, method, args, opts)
+}
+
+// CallAllContext is CallAll bound to ctx
+func (c *Client) CallAllContext(ctx context.Context, service, method string, args map[string]interface{}, opts *CallAllOptions) ([]InstanceResult, error) {
+	info := &CallInfo{
+		Kind:    CallKindBroadcast,
+		Service: service,
+		Method:  method,
+		Subject: types.BroadcastRPCSubject(service, method),
+		Header:  nats.Header{},
+	}
+	ctx, span := c.tracer.Start(ctx, info.Subject, tracing.SpanKindClient)
+	defer span.End()
+	span.SetAttribute("rpc.method", method)
+
+	if opts == nil {
+		opts = &CallAllOptions{}
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultCallAllTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var results []InstanceResult
+	_, err := c.intercept(ctx, info, args, func(ctx context.Context, info *CallInfo, args map[string]interface{}) (map[string]interface{}, error) {
+		var err error
+		results, err = c.callAll(ctx, info, args, opts.Expected)
+		return nil, err
+	})
+	span.SetError(err)
+	span.SetAttribute("rpc.replies", strconv.Itoa(len(results)))
+	return results, err
+}
+
+// callAll publishes the broadcast request at the end of the interceptor chain
+// and collects up to expected replies until ctx is done
+func (c *Client) callAll(ctx context.Context, info *CallInfo, args map[string]interface{}, expected int) ([]InstanceResult, error) {
+	requestID := uuid.New().String()
+	cd := c.payloadCodec()
+	reqData, err := cd.Marshal(types.RPCRequest{
+		ID:     requestID,
+		Method: info.Method,
+		Args:   args,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	// Collect replies on a private inbox, subscribed before the request is published
+	inbox := c.conn.NewRespInbox()
+	sub, err := c.conn.SubscribeSync(inbox)
+	if err != nil {
+		return nil, fmt.Errorf("subscribe reply inbox: %w", err)
+	}
+	defer sub.Unsubscribe()
+
+	msg := nats.NewMsg(info.Subject)
+	msg.Reply = inbox
+	msg.Data = reqData
+	for key, values := range info.Header {
+		msg.Header[key] = values
+	}
+	msg.Header.Set(types.HeaderContentType, cd.ContentType())
+	setEncodingHeaders(msg, "")
+	setRequestHeaders(ctx, msg, requestID)
+	if err := c.conn.PublishMsg(msg); err != nil {
+		return nil, fmt.Errorf("publish request: %w", err)
+	}
+
+	var results []InstanceResult
+	for expected <= 0 || len(results) < expected {
+		respMsg, err := sub.NextMsgWithContext(ctx)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				c.sendCancel(requestID)
+				break
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				break
+			}
+			// No instance is running, which is zero replies rather than a failure
+			if errors.Is(err, nats.ErrNoResponders) {
+				break
+			}
+			return results, fmt.Errorf("receive reply: %w", err)
+		}
+		if respMsg.Header.Get("Status") == "503" {
+			break
+		}
+		results = append(results, parseInstanceResult(respMsg))
+	}
+	return results, nil
+}
+
+// parseInstanceResult converts one reply of a scatter-gather call
+func parseInstanceResult(msg *nats.Msg) InstanceResult {
+	result := InstanceResult{InstanceKey: msg.Header.Get(types.HeaderInstanceKey)}
+
+	var response types.RPCResponse
+	if err := decodeResponse(msg, &response); err != nil {
+		result.Err = fmt.Errorf("unmarshal response: %w", err)
+		return result
+	}
+	if err := response.Err(); err != nil {
+		result.Err = fmt.Errorf("RPC error: %w", err)
+		return result
+	}
+	result.Result = response.Result
+	return result
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/testutil"
+	"github.com/LiteHomeLab/light_link/sdk/go/transport"
+)
+
+func TestCallAllNoInstances(t *testing.T) {
+	connect := map[string]func(t *testing.T) (*Client, error){
+		"memory": func(t *testing.T) (*Client, error) {
+			return NewClient("", WithTransport(transport.NewMemoryBus().Connect()))
+		},
+		"nats": func(t *testing.T) (*Client, error) {
+			return NewClient(testutil.StartNATS(t, testutil.Options{}).URL)
+		},
+	}
+	for name, newClient := range connect {
+		t.Run(name, func(t *testing.T) {
+			c, err := newClient(t)
+			if err != nil {
+				t.Fatalf("NewClient failed: %v", err)
+			}
+			defer c.Close()
+
+			start := time.Now()
+			results, err := c.CallAll("no-such-callall-service", "hit", nil, &CallAllOptions{Timeout: 2 * time.Second})
+			if err != nil {
+				t.Fatalf("Expected zero results, got %v", err)
+			}
+			if len(results) != 0 {
+				t.Errorf("Expected no replies, got %+v", results)
+			}
+			if time.Since(start) > time.Second {
+				t.Errorf("Expected CallAll to return without instances, took %v", time.Since(start))
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/WQGroup/logger"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// DefaultCertReloadInterval is how often the certificate files are checked for changes
+	DefaultCertReloadInterval = time.Minute
+	// DefaultCertExpiryWarning is how long before expiry CertExpiringSoon is emitted
+	DefaultCertExpiryWarning = 30 * 24 * time.Hour
+)
+
+// CertEventType is the kind of a CertEvent
+type CertEventType string
+
+const (
+	// CertReloaded means changed certificate files were loaded
+	CertReloaded CertEventType = "reloaded"
+	// CertReloadFailed means changed files could not be loaded; the previous certificate stays in use
+	CertReloadFailed CertEventType = "reload_failed"
+	// CertExpiringSoon means the certificate expires within the warning period
+	CertExpiringSoon CertEventType = "expiring_soon"
+	// CertExpired means the certificate is no longer valid
+	CertExpired CertEventType = "expired"
+)
+
+// CertEvent reports a change of the certificate used for TLS connections
+type CertEvent struct {
+	Type     CertEventType
+	NotAfter time.Time // expiry of the certificate in use
+	Err      error     // set for CertReloadFailed
+}
+
+// CertReloader keeps the client certificate and CA of a TLSConfig up to date.
+// It watches the files and reloads them when they change, so new connections
+// and reconnects use the rotated certificate without a restart.
+type CertReloader struct {
+	config     *TLSConfig
+	interval   time.Duration
+	warnBefore time.Duration
+	onEvent    func(CertEvent)
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	roots    *x509.CertPool
+	modTimes map[string]time.Time
+	warned   time.Time // NotAfter of the certificate last warned about
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// NewCertReloader loads the files of config. Call Start to watch them for
+// changes; onEvent, if not nil, is called for every CertEvent.
+func NewCertReloader(config *TLSConfig, onEvent func(CertEvent)) (*CertReloader, error) {
+	r := &CertReloader{
+		config:     config,
+		interval:   config.ReloadInterval,
+		warnBefore: config.ExpiryWarning,
+		onEvent:    onEvent,
+		stop:       make(chan struct{}),
+	}
+	if r.interval == 0 {
+		r.interval = DefaultCertReloadInterval
+	}
+	if r.warnBefore <= 0 {
+		r.warnBefore = DefaultCertExpiryWarning
+	}
+
+	cert, roots, err := loadTLSFiles(config)
+	if err != nil {
+		return nil, err
+	}
+	r.cert, r.roots, r.modTimes = cert, roots, r.fileModTimes()
+	return r, nil
+}
+
+// NATSOption returns the TLS option for nats.Connect. The certificate and CA
+// are fetched from the reloader on every connect and reconnect.
+func (r *CertReloader) NATSOption() nats.Option {
+	tlsConfig := newTLSConfig(r.config, r.RootCAs)
+	if r.config.CertFile != "" || r.config.KeyFile != "" || r.config.PKCS12File != "" {
+		tlsConfig.GetClientCertificate = r.GetClientCertificate
+	}
+	hasRoots := r.RootCAs() != nil
+
+	return func(o *nats.Options) error {
+		if err := nats.Secure(tlsConfig)(o); err != nil {
+			return err
+		}
+		if hasRoots {
+			o.RootCAsCB = func() (*x509.CertPool, error) {
+				return r.RootCAs(), nil
+			}
+		}
+		return nil
+	}
+}
+
+// GetClientCertificate returns the current client certificate, for tls.Config.GetClientCertificate
+func (r *CertReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.cert == nil {
+		return &tls.Certificate{}, nil
+	}
+	return r.cert, nil
+}
+
+// RootCAs returns the current CA pool, nil when the system roots are used
+func (r *CertReloader) RootCAs() *x509.CertPool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.roots
+}
+
+// NotAfter returns the expiry of the current client certificate, zero without one
+func (r *CertReloader) NotAfter() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.cert == nil || r.cert.Leaf == nil {
+		return time.Time{}
+	}
+	return r.cert.Leaf.NotAfter
+}
+
+// Reload loads the files again. On error the previous certificate stays in use.
+func (r *CertReloader) Reload() error {
+	modTimes := r.fileModTimes()
+	cert, roots, err := loadTLSFiles(r.config)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.cert, r.roots, r.modTimes = cert, roots, modTimes
+	r.mu.Unlock()
+	return nil
+}
+
+// Start watches the files until Stop, checking the expiry at the same time.
+// A negative ReloadInterval disables watching.
+func (r *CertReloader) Start() {
+	r.checkExpiry()
+	if r.interval < 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				r.checkFiles()
+				r.checkExpiry()
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops watching the files
+func (r *CertReloader) Stop() {
+	r.stopOnce.Do(func() { close(r.stop) })
+}
+
+// checkFiles reloads the files if one of them changed since the last load
+func (r *CertReloader) checkFiles() {
+	modTimes := r.fileModTimes()
+	r.mu.Lock()
+	changed := len(modTimes) != len(r.modTimes)
+	for file, modTime := range modTimes {
+		if !modTime.Equal(r.modTimes[file]) {
+			changed = true
+		}
+	}
+	// Remember the times even if loading fails: files still being written
+	// change again once complete and are loaded then
+	r.modTimes = modTimes
+	r.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	if err := r.Reload(); err != nil {
+		logger.Errorf("Reload TLS certificate failed: %v", err)
+		r.emit(CertEvent{Type: CertReloadFailed, NotAfter: r.NotAfter(), Err: err})
+		return
+	}
+	logger.Infof("Reloaded TLS certificate, valid until %s", r.NotAfter().Format(time.RFC3339))
+	r.emit(CertEvent{Type: CertReloaded, NotAfter: r.NotAfter()})
+}
+
+// checkExpiry warns once per certificate when its expiry is near
+func (r *CertReloader) checkExpiry() {
+	notAfter := r.NotAfter()
+	if notAfter.IsZero() {
+		return
+	}
+	remaining := time.Until(notAfter)
+	if remaining > r.warnBefore {
+		return
+	}
+
+	r.mu.Lock()
+	if r.warned.Equal(notAfter) {
+		r.mu.Unlock()
+		return
+	}
+	r.warned = notAfter
+	r.mu.Unlock()
+
+	if remaining <= 0 {
+		logger.Errorf("TLS certificate %s expired at %s", r.certFile(), notAfter.Format(time.RFC3339))
+		r.emit(CertEvent{Type: CertExpired, NotAfter: notAfter})
+		return
+	}
+	logger.Warnf("TLS certificate %s expires at %s", r.certFile(), notAfter.Format(time.RFC3339))
+	r.emit(CertEvent{Type: CertExpiringSoon, NotAfter: notAfter})
+}
+
+// emit calls the event handler, if any
+func (r *CertReloader) emit(event CertEvent) {
+	if r.onEvent != nil {
+		r.onEvent(event)
+	}
+}
+
+// certFile returns the file holding the certificate, for log messages
+func (r *CertReloader) certFile() string {
+	if r.config.PKCS12File != "" {
+		return r.config.PKCS12File
+	}
+	return r.config.CertFile
+}
+
+// fileModTimes returns the modification times of the watched files
+func (r *CertReloader) fileModTimes() map[string]time.Time {
+	modTimes := make(map[string]time.Time)
+	for _, file := range []string{r.config.CertFile, r.config.KeyFile, r.config.PKCS12File, r.config.CaFile} {
+		if file == "" {
+			continue
+		}
+		if info, err := os.Stat(file); err == nil {
+			modTimes[file] = info.ModTime()
+		}
+	}
+	return modTimes
+}
+
+// loadTLSFiles loads the client certificate and the CA pool of config.
+// Both are nil when the files are not configured.
+func loadTLSFiles(config *TLSConfig) (*tls.Certificate, *x509.CertPool, error) {
+	if config.PKCS12File != "" {
+		return loadPKCS12Files(config)
+	}
+
+	var cert *tls.Certificate
+	if config.CertFile != "" || config.KeyFile != "" {
+		pair, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		if len(pair.Certificate) == 0 {
+			return nil, nil, errors.New("load client certificate: no certificate found")
+		}
+		if pair.Leaf == nil {
+			if pair.Leaf, err = x509.ParseCertificate(pair.Certificate[0]); err != nil {
+				return nil, nil, fmt.Errorf("parse client certificate: %w", err)
+			}
+		}
+		cert = &pair
+	}
+
+	pool, err := loadCAFile(config.CaFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, pool, nil
+}
+
+// loadPKCS12Files loads the certificate of a PKCS#12 bundle. The CA pool is
+// read from CaFile, or built from the CA certificates in the bundle.
+func loadPKCS12Files(config *TLSConfig) (*tls.Certificate, *x509.CertPool, error) {
+	cert, cas, err := types.LoadPKCS12(config.PKCS12File, config.PKCS12Password)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if config.CaFile != "" {
+		pool, err := loadCAFile(config.CaFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return cert, pool, nil
+	}
+	if len(cas) == 0 {
+		return cert, nil, nil
+	}
+	pool := x509.NewCertPool()
+	for _, ca := range cas {
+		pool.AddCert(ca)
+	}
+	return cert, pool, nil
+}
+
+// loadCAFile loads the CA pool of caFile, nil when caFile is empty
+func loadCAFile(caFile string) (*x509.CertPool, error) {
+	if caFile == "" {
+		return nil, nil
+	}
+	caCert, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("read CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("no certificates found in CA file %s", caFile)
+	}
+	return pool, nil
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeClientCert writes a self-signed client certificate expiring at notAfter
+func writeClientCert(t *testing.T, certFile, keyFile string, notAfter time.Time) {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "lightlink-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "lightlink-client"}}, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+}
+
+// touch moves the modification time of files forward so the reloader sees a change
+func touch(files ...string) {
+	later := time.Now().Add(time.Minute)
+	for _, f := range files {
+		os.Chtimes(f, later, later)
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	firstExpiry := time.Now().Add(365 * 24 * time.Hour).Truncate(time.Second)
+	writeClientCert(t, certFile, keyFile, firstExpiry)
+
+	var events []CertEvent
+	r, err := NewCertReloader(&TLSConfig{CertFile: certFile, KeyFile: keyFile, ReloadInterval: -1},
+		func(e CertEvent) { events = append(events, e) })
+	if err != nil {
+		t.Fatalf("NewCertReloader failed: %v", err)
+	}
+	if !r.NotAfter().Equal(firstExpiry) {
+		t.Errorf("Expected expiry %v, got %v", firstExpiry, r.NotAfter())
+	}
+	r.checkFiles()
+	if len(events) != 0 {
+		t.Fatalf("Expected no events for unchanged files, got %v", events)
+	}
+
+	// Rotated certificate is picked up
+	secondExpiry := time.Now().Add(10 * 24 * time.Hour).Truncate(time.Second)
+	writeClientCert(t, certFile, keyFile, secondExpiry)
+	touch(certFile, keyFile)
+	r.checkFiles()
+	if len(events) != 1 || events[0].Type != CertReloaded || !events[0].NotAfter.Equal(secondExpiry) {
+		t.Fatalf("Expected reloaded event, got %+v", events)
+	}
+	cert, _ := r.GetClientCertificate(nil)
+	if !cert.Leaf.NotAfter.Equal(secondExpiry) {
+		t.Errorf("Expected rotated certificate, got expiry %v", cert.Leaf.NotAfter)
+	}
+
+	// Expiry within the warning period is reported once
+	r.checkExpiry()
+	r.checkExpiry()
+	if len(events) != 2 || events[1].Type != CertExpiringSoon {
+		t.Fatalf("Expected one expiring event, got %+v", events)
+	}
+
+	// A broken file keeps the previous certificate
+	os.WriteFile(certFile, []byte("garbage"), 0644)
+	touch(certFile)
+	r.checkFiles()
+	if len(events) != 3 || events[2].Type != CertReloadFailed || events[2].Err == nil {
+		t.Fatalf("Expected reload failure event, got %+v", events)
+	}
+	if !r.NotAfter().Equal(secondExpiry) {
+		t.Errorf("Expected previous certificate to stay in use, got expiry %v", r.NotAfter())
+	}
+}
+
+func TestCertReloaderExpired(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	writeClientCert(t, certFile, keyFile, time.Now().Add(-time.Minute))
+
+	var events []CertEvent
+	r, err := NewCertReloader(&TLSConfig{CertFile: certFile, KeyFile: keyFile, ReloadInterval: -1},
+		func(e CertEvent) { events = append(events, e) })
+	if err != nil {
+		t.Fatalf("NewCertReloader failed: %v", err)
+	}
+	r.Start()
+	defer r.Stop()
+	if len(events) != 1 || events[0].Type != CertExpired {
+		t.Errorf("Expected expired event on start, got %+v", events)
+	}
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"github.com/LiteHomeLab/light_link/sdk/go/codec"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+// WithCodec sets the codec RPC requests are encoded with.
+// Services answer in the codec of the request; the default is codec.JSON,
+// which is also the only codec services written with the other SDKs understand.
+func WithCodec(c codec.Codec) Option {
+	return func(cl *Client) error {
+		cl.codec = c
+		return nil
+	}
+}
+
+// payloadCodec returns the codec requests are encoded with
+func (c *Client) payloadCodec() codec.Codec {
+	if c.codec == nil {
+		return codec.JSON
+	}
+	return c.codec
+}
+
+// decodeResponse decompresses an RPC response and decodes it in the codec
+// named by its Content-Type header
+func decodeResponse(msg *nats.Msg, response *types.RPCResponse) error {
+	data, err := messagePayload(msg)
+	if err != nil {
+		return err
+	}
+	cd, err := codec.ForContentType(msg.Header.Get(types.HeaderContentType))
+	if err != nil {
+		return err
+	}
+	return cd.Unmarshal(data, response)
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/codec"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+// compressionConfig configures payload compression
+type compressionConfig struct {
+	encoding  string
+	threshold int
+	publish   bool
+}
+
+// WithCompression compresses request payloads of at least threshold bytes
+// with encoding (codec.EncodingGzip or codec.EncodingZstd).
+// Only instance-addressed requests (CallInstance) are compressed, and only
+// once that instance has advertised that it can decompress them. Requests
+// load-balanced over a queue group stay plain, since the next one may reach
+// a member that cannot decompress, e.g. an older or other-language instance.
+// A threshold of zero selects codec.DefaultCompressionThreshold.
+// Compressed responses are always decompressed, with or without this option.
+func WithCompression(encoding string, threshold int) Option {
+	return func(c *Client) error {
+		if !codec.IsSupportedEncoding(encoding) {
+			return fmt.Errorf("unsupported content encoding: %s", encoding)
+		}
+		if threshold <= 0 {
+			threshold = codec.DefaultCompressionThreshold
+		}
+		publish := c.compression != nil && c.compression.publish
+		c.compression = &compressionConfig{encoding: encoding, threshold: threshold, publish: publish}
+		return nil
+	}
+}
+
+// WithPublishCompression also compresses payloads sent with Publish.
+// There is no negotiation for Publish: subscribers cannot advertise support,
+// so every subscriber receives the compressed payload. Subscribers written
+// with the other SDKs or older versions of this one cannot decode it; only
+// enable this when every subscriber of the published subjects uses this SDK.
+// Without WithCompression, gzip and the default threshold are used.
+func WithPublishCompression() Option {
+	return func(c *Client) error {
+		if c.compression == nil {
+			c.compression = &compressionConfig{
+				encoding:  codec.EncodingGzip,
+				threshold: codec.DefaultCompressionThreshold,
+			}
+		}
+		c.compression.publish = true
+		return nil
+	}
+}
+
+// compressRequest compresses the request payload of an instance-addressed
+// call if that instance has advertised support. It returns the payload and
+// the encoding applied.
+func (c *Client) compressRequest(info *CallInfo, data []byte) ([]byte, string) {
+	if c.compression == nil || info.InstanceKey == "" {
+		return data, ""
+	}
+	accept, _ := c.peerAccept.Load(info.Subject)
+	acceptStr, _ := accept.(string)
+	encoding := codec.NegotiateEncoding(c.compression.encoding, acceptStr)
+	return codec.CompressPayload(data, encoding, c.compression.threshold)
+}
+
+// learnAcceptEncoding remembers the encodings the instance addressed by info
+// accepts. A response without them means the instance cannot decompress, so
+// its requests are sent uncompressed until a response advertises support again.
+func (c *Client) learnAcceptEncoding(info *CallInfo, resp *nats.Msg) {
+	if c.compression == nil || info.InstanceKey == "" {
+		return
+	}
+	if accept := resp.Header.Get(types.HeaderAcceptEncoding); accept != "" {
+		c.peerAccept.Store(info.Subject, accept)
+	} else {
+		c.peerAccept.Delete(info.Subject)
+	}
+}
+
+// setEncodingHeaders advertises the encodings this client decompresses and
+// names the encoding applied to msg, if any
+func setEncodingHeaders(msg *nats.Msg, applied string) {
+	msg.Header.Set(types.HeaderAcceptEncoding, codec.AcceptEncoding())
+	if applied != "" {
+		msg.Header.Set(types.HeaderContentEncoding, applied)
+	}
+}
+
+// messagePayload returns the decompressed payload of msg
+func messagePayload(msg *nats.Msg) ([]byte, error) {
+	if msg.Header == nil {
+		return msg.Data, nil
+	}
+	return codec.Decompress(msg.Header.Get(types.HeaderContentEncoding), msg.Data)
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/codec"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+func TestLearnAcceptEncoding(t *testing.T) {
+	c := &Client{}
+	if err := WithCompression(codec.EncodingGzip, 16)(c); err != nil {
+		t.Fatalf("WithCompression failed: %v", err)
+	}
+	key := "10.0.0.1:aabbccddeeff:report"
+	info := &CallInfo{InstanceKey: key, Method: "build", Subject: types.InstanceRPCSubject(key, "build")}
+	payload := bytes.Repeat([]byte("report "), 100)
+
+	if _, encoding := c.compressRequest(info, payload); encoding != "" {
+		t.Errorf("Expected no compression before the instance advertised support, got %s", encoding)
+	}
+
+	upgraded := nats.NewMsg("inbox")
+	upgraded.Header.Set(types.HeaderAcceptEncoding, codec.AcceptEncoding())
+	c.learnAcceptEncoding(info, upgraded)
+	if _, encoding := c.compressRequest(info, payload); encoding != codec.EncodingGzip {
+		t.Errorf("Expected gzip after the instance advertised it, got %q", encoding)
+	}
+
+	// The instance was replaced by one without compression support
+	c.learnAcceptEncoding(info, &nats.Msg{Subject: "inbox"})
+	if _, encoding := c.compressRequest(info, payload); encoding != "" {
+		t.Errorf("Expected no compression after a response without support, got %s", encoding)
+	}
+}
+
+func TestLoadBalancedRequestsStayPlain(t *testing.T) {
+	c := &Client{}
+	if err := WithCompression(codec.EncodingGzip, 16)(c); err != nil {
+		t.Fatalf("WithCompression failed: %v", err)
+	}
+	info := &CallInfo{Service: "report", Method: "build", Subject: types.ServiceRPCSubject("report", "build")}
+	payload := bytes.Repeat([]byte("report "), 100)
+
+	// One member of the queue group advertising support says nothing of the next one
+	upgraded := nats.NewMsg("inbox")
+	upgraded.Header.Set(types.HeaderAcceptEncoding, codec.AcceptEncoding())
+	c.learnAcceptEncoding(info, upgraded)
+	if _, encoding := c.compressRequest(info, payload); encoding != "" {
+		t.Errorf("Expected load-balanced requests to stay plain, got %s", encoding)
+	}
+}
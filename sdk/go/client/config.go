@@ -0,0 +1,75 @@
+package client
+
+import (
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+)
+
+// NewClientFromConfig creates a client from a YAML or JSON config file with
+// the LIGHTLINK_* environment overrides applied, see types.LoadConfig.
+// An empty path reads the environment only. opts are applied after the config.
+func NewClientFromConfig(path string, opts ...Option) (*Client, error) {
+	cfg, err := types.LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(cfg.NATSURL, append([]Option{WithConfig(cfg)}, opts...)...)
+}
+
+// WithConfig applies the connection settings of cfg: servers, reconnect,
+// authentication, inbox prefix, name and TLS. The NATS URL is passed to NewClient.
+func WithConfig(cfg *types.Config) Option {
+	return func(c *Client) error {
+		c.connect.ApplyConfig(cfg)
+		if cfg.Name != "" {
+			c.name = cfg.Name
+		}
+		if cfg.AutoTLS {
+			return WithAutoTLS()(c)
+		}
+		if cfg.TLS != nil {
+			c.tlsConfig = TLSConfigFrom(cfg.TLS)
+		}
+		return nil
+	}
+}
+
+// ApplyConfig copies the connection settings of cfg that are set
+func (o *ConnectOptions) ApplyConfig(cfg *types.Config) {
+	o.Servers = append(o.Servers, cfg.Servers...)
+	if cfg.Reconnect.Wait > 0 {
+		o.ReconnectWait = time.Duration(cfg.Reconnect.Wait)
+	}
+	if cfg.Reconnect.MaxReconnects != nil {
+		o.MaxReconnects = *cfg.Reconnect.MaxReconnects
+	}
+	if cfg.Auth.User != "" {
+		o.User, o.Password = cfg.Auth.User, cfg.Auth.Password
+	}
+	if cfg.Auth.Token != "" {
+		o.Token = cfg.Auth.Token
+	}
+	if cfg.Auth.NKeyFile != "" {
+		o.NKeySeedFile = cfg.Auth.NKeyFile
+	}
+	if cfg.Auth.CredsFile != "" {
+		o.CredsFile = cfg.Auth.CredsFile
+	}
+	if cfg.InboxPrefix != "" {
+		o.InboxPrefix = cfg.InboxPrefix
+	}
+}
+
+// TLSConfigFrom converts the TLS settings of a config file
+func TLSConfigFrom(cfg *types.TLSConfig) *TLSConfig {
+	return &TLSConfig{
+		CaFile:             cfg.CaFile,
+		CertFile:           cfg.CertFile,
+		KeyFile:            cfg.KeyFile,
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		PKCS12File:         cfg.PKCS12File,
+		PKCS12Password:     cfg.PKCS12Password,
+	}
+}
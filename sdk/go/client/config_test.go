@@ -0,0 +1,44 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestNewClientFromConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lightlink.json")
+	os.WriteFile(path, []byte(`{
+		"nats_url": "`+nats.DefaultURL+`",
+		"name": "config-client",
+		"reconnect": {"wait": "250ms", "max_reconnects": 3},
+		"inbox_prefix": "_LL_CFG_INBOX"
+	}`), 0644)
+
+	c, err := NewClientFromConfig(path)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer c.Close()
+
+	opts := c.GetNATSConn().Opts
+	if opts.Name != "config-client" || opts.MaxReconnect != 3 || opts.ReconnectWait != 250*time.Millisecond {
+		t.Errorf("Unexpected options: %q, %d, %v", opts.Name, opts.MaxReconnect, opts.ReconnectWait)
+	}
+	if opts.InboxPrefix != "_LL_CFG_INBOX" {
+		t.Errorf("Unexpected inbox prefix %q", opts.InboxPrefix)
+	}
+
+	// Options passed explicitly win over the config
+	c2, err := NewClientFromConfig(path, WithName("explicit"))
+	if err != nil {
+		t.Fatalf("NewClientFromConfig failed: %v", err)
+	}
+	defer c2.Close()
+	if c2.GetNATSConn().Opts.Name != "explicit" {
+		t.Errorf("Expected explicit name, got %q", c2.GetNATSConn().Opts.Name)
+	}
+}
@@ -0,0 +1,244 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// DefaultReconnectWait is the wait time between reconnect attempts
+	DefaultReconnectWait = 2 * time.Second
+	// DefaultMaxReconnects is the number of reconnect attempts of a client
+	// before its connection is closed
+	DefaultMaxReconnects = 10
+	// InfiniteReconnects keeps reconnecting until the connection is closed
+	InfiniteReconnects = -1
+)
+
+// ConnectOptions configures how the NATS connection is established, authenticated
+// and kept alive. The zero value connects to the given URL without credentials.
+type ConnectOptions struct {
+	// Servers are additional seed URLs tried besides the URL passed to NewClient
+	Servers []string
+	// ReconnectWait is the wait time between reconnect attempts; zero uses DefaultReconnectWait
+	ReconnectWait time.Duration
+	// MaxReconnects caps reconnect attempts; zero disables reconnecting and
+	// InfiniteReconnects never gives up
+	MaxReconnects int
+
+	// User and Password authenticate with username/password
+	User     string
+	Password string
+	// Token authenticates with a token
+	Token string
+	// NKeySeedFile authenticates with the NKey seed stored in the file
+	NKeySeedFile string
+	// CredsFile authenticates with a .creds file holding a user JWT and NKey seed
+	CredsFile string
+
+	// InboxPrefix replaces the _INBOX prefix of reply subjects, for accounts
+	// whose permissions only allow specific subjects
+	InboxPrefix string
+
+	// OnDisconnect is called when the connection is lost; err may be nil
+	OnDisconnect func(err error)
+	// OnReconnect is called with the server URL after a reconnect
+	OnReconnect func(url string)
+	// OnClosed is called once the connection is closed for good
+	OnClosed func()
+	// OnError is called for asynchronous errors such as slow consumers;
+	// subject is empty for errors not tied to a subscription
+	OnError func(subject string, err error)
+}
+
+// ConnectHooks are internal connection event handlers run before the user callbacks
+type ConnectHooks struct {
+	OnDisconnect func(err error)
+	OnReconnect  func(url string)
+	OnClosed     func()
+	OnError      func(subject string, err error)
+}
+
+// URL joins url and the extra seed servers into the comma separated list nats.Connect accepts
+func (o *ConnectOptions) URL(url string) string {
+	var urls []string
+	for _, u := range append([]string{url}, o.Servers...) {
+		if u = strings.TrimSpace(u); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	if len(urls) == 0 {
+		return nats.DefaultURL
+	}
+	return strings.Join(urls, ",")
+}
+
+// NATSOptions converts the options to nats.Options. The reconnect, disconnect,
+// closed and error handlers call the matching callback after the hook function
+// passed in, so callers can keep their own logging or metrics.
+func (o *ConnectOptions) NATSOptions(hooks ConnectHooks) ([]nats.Option, error) {
+	wait := o.ReconnectWait
+	if wait <= 0 {
+		wait = DefaultReconnectWait
+	}
+
+	natsOpts := []nats.Option{
+		nats.ReconnectWait(wait),
+		nats.MaxReconnects(o.MaxReconnects),
+		nats.DisconnectErrHandler(func(nc *nats.Conn, err error) {
+			if hooks.OnDisconnect != nil {
+				hooks.OnDisconnect(err)
+			}
+			if o.OnDisconnect != nil {
+				o.OnDisconnect(err)
+			}
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			if hooks.OnReconnect != nil {
+				hooks.OnReconnect(nc.ConnectedUrl())
+			}
+			if o.OnReconnect != nil {
+				o.OnReconnect(nc.ConnectedUrl())
+			}
+		}),
+		nats.ClosedHandler(func(nc *nats.Conn) {
+			if hooks.OnClosed != nil {
+				hooks.OnClosed()
+			}
+			if o.OnClosed != nil {
+				o.OnClosed()
+			}
+		}),
+		nats.ErrorHandler(func(nc *nats.Conn, sub *nats.Subscription, err error) {
+			subject := ""
+			if sub != nil {
+				subject = sub.Subject
+			}
+			if hooks.OnError != nil {
+				hooks.OnError(subject, err)
+			}
+			if o.OnError != nil {
+				o.OnError(subject, err)
+			}
+		}),
+	}
+
+	if o.User != "" {
+		natsOpts = append(natsOpts, nats.UserInfo(o.User, o.Password))
+	}
+	if o.Token != "" {
+		natsOpts = append(natsOpts, nats.Token(o.Token))
+	}
+	if o.NKeySeedFile != "" {
+		nkeyOpt, err := nats.NkeyOptionFromSeed(o.NKeySeedFile)
+		if err != nil {
+			return nil, fmt.Errorf("load nkey seed: %w", err)
+		}
+		natsOpts = append(natsOpts, nkeyOpt)
+	}
+	if o.CredsFile != "" {
+		natsOpts = append(natsOpts, nats.UserCredentials(o.CredsFile))
+	}
+	if o.InboxPrefix != "" {
+		natsOpts = append(natsOpts, nats.CustomInboxPrefix(o.InboxPrefix))
+	}
+	return natsOpts, nil
+}
+
+// WithServers adds seed server URLs tried besides the URL passed to NewClient
+func WithServers(urls ...string) Option {
+	return func(c *Client) error {
+		c.connect.Servers = append(c.connect.Servers, urls...)
+		return nil
+	}
+}
+
+// WithReconnect sets the wait time between reconnect attempts and their maximum
+// number; use InfiniteReconnects to never give up
+func WithReconnect(wait time.Duration, maxReconnects int) Option {
+	return func(c *Client) error {
+		if maxReconnects < InfiniteReconnects {
+			return fmt.Errorf("max reconnects must be at least %d, got %d", InfiniteReconnects, maxReconnects)
+		}
+		c.connect.ReconnectWait = wait
+		c.connect.MaxReconnects = maxReconnects
+		return nil
+	}
+}
+
+// WithUserInfo authenticates with a username and password
+func WithUserInfo(user, password string) Option {
+	return func(c *Client) error {
+		c.connect.User = user
+		c.connect.Password = password
+		return nil
+	}
+}
+
+// WithToken authenticates with a token
+func WithToken(token string) Option {
+	return func(c *Client) error {
+		c.connect.Token = token
+		return nil
+	}
+}
+
+// WithNKeyFile authenticates with the NKey seed stored in seedFile
+func WithNKeyFile(seedFile string) Option {
+	return func(c *Client) error {
+		c.connect.NKeySeedFile = seedFile
+		return nil
+	}
+}
+
+// WithCredentials authenticates with a .creds file holding a user JWT and NKey seed
+func WithCredentials(credsFile string) Option {
+	return func(c *Client) error {
+		c.connect.CredsFile = credsFile
+		return nil
+	}
+}
+
+// WithInboxPrefix replaces the _INBOX prefix of reply subjects
+func WithInboxPrefix(prefix string) Option {
+	return func(c *Client) error {
+		c.connect.InboxPrefix = prefix
+		return nil
+	}
+}
+
+// WithDisconnectHandler calls fn when the connection is lost
+func WithDisconnectHandler(fn func(err error)) Option {
+	return func(c *Client) error {
+		c.connect.OnDisconnect = fn
+		return nil
+	}
+}
+
+// WithReconnectHandler calls fn with the server URL after a reconnect
+func WithReconnectHandler(fn func(url string)) Option {
+	return func(c *Client) error {
+		c.connect.OnReconnect = fn
+		return nil
+	}
+}
+
+// WithClosedHandler calls fn once the connection is closed for good, either by
+// Close or after the reconnect attempts ran out
+func WithClosedHandler(fn func()) Option {
+	return func(c *Client) error {
+		c.connect.OnClosed = fn
+		return nil
+	}
+}
+
+// WithErrorHandler calls fn for asynchronous connection errors such as slow consumers
+func WithErrorHandler(fn func(subject string, err error)) Option {
+	return func(c *Client) error {
+		c.connect.OnError = fn
+		return nil
+	}
+}
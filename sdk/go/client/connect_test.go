@@ -0,0 +1,68 @@
+package client
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestConnectOptionsURL(t *testing.T) {
+	opts := ConnectOptions{Servers: []string{" nats://b:4222", "", "nats://c:4222"}}
+	if got := opts.URL("nats://a:4222"); got != "nats://a:4222,nats://b:4222,nats://c:4222" {
+		t.Errorf("Unexpected URL %q", got)
+	}
+	if got := (&ConnectOptions{}).URL(""); got != nats.DefaultURL {
+		t.Errorf("Expected default URL, got %q", got)
+	}
+}
+
+func TestConnectOptionsErrors(t *testing.T) {
+	if _, err := (&ConnectOptions{NKeySeedFile: "missing.nk"}).NATSOptions(ConnectHooks{}); err == nil {
+		t.Error("Expected error for missing nkey seed file")
+	}
+	if _, err := NewClient(nats.DefaultURL, WithReconnect(time.Second, -2)); err == nil {
+		t.Error("Expected error for invalid max reconnects")
+	}
+}
+
+func TestClientConnectOptions(t *testing.T) {
+	closed := make(chan struct{})
+	c, err := NewClient("",
+		WithServers(nats.DefaultURL),
+		WithReconnect(100*time.Millisecond, InfiniteReconnects),
+		WithInboxPrefix("_LL_TEST_INBOX"),
+		WithClosedHandler(func() { close(closed) }))
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+
+	nc := c.GetNATSConn()
+	if nc.Opts.MaxReconnect != InfiniteReconnects || nc.Opts.ReconnectWait != 100*time.Millisecond {
+		t.Errorf("Unexpected reconnect options: %d, %v", nc.Opts.MaxReconnect, nc.Opts.ReconnectWait)
+	}
+
+	// Replies arrive on the custom inbox prefix
+	sub, err := nc.Subscribe("test.connect.echo", func(msg *nats.Msg) {
+		msg.Respond([]byte(msg.Reply))
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer sub.Unsubscribe()
+	reply, err := nc.Request("test.connect.echo", nil, time.Second)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if !strings.HasPrefix(string(reply.Data), "_LL_TEST_INBOX.") {
+		t.Errorf("Expected reply subject with custom prefix, got %q", reply.Data)
+	}
+
+	c.Close()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("Closed handler not called")
+	}
+}
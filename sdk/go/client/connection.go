@@ -1,14 +1,17 @@
 package client
 
 import (
-	"crypto/tls"
 	"crypto/x509"
 	"fmt"
-	"os"
+	"sync"
 	"time"
 
 	"github.com/WQGroup/logger"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/LiteHomeLab/light_link/sdk/go/codec"
+	"github.com/LiteHomeLab/light_link/sdk/go/tracing"
+	"github.com/LiteHomeLab/light_link/sdk/go/transport"
 	"github.com/LiteHomeLab/light_link/sdk/go/types"
 )
 
@@ -18,6 +21,23 @@ type TLSConfig struct {
 	CertFile   string
 	KeyFile    string
 	ServerName string
+	// PKCS12File is a .p12/.pfx bundle holding the certificate and key, used
+	// instead of CertFile and KeyFile; CA certificates in it are trusted when
+	// CaFile is empty
+	PKCS12File     string
+	PKCS12Password string
+
+	// InsecureSkipVerify skips the server name check while still verifying the
+	// chain. Only meant for development certificates without SANs.
+	InsecureSkipVerify bool
+	// VerifyPeerCertificate is called after the standard verification, e.g. PinCertificates
+	VerifyPeerCertificate func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error
+
+	// ReloadInterval is how often the files are checked for changes; zero uses
+	// DefaultCertReloadInterval and a negative value disables reloading
+	ReloadInterval time.Duration
+	// ExpiryWarning is how long before expiry a warning is logged; zero uses DefaultCertExpiryWarning
+	ExpiryWarning time.Duration
 }
 
 // Option is a function that configures a Client
@@ -25,25 +45,49 @@ type Option func(*Client) error
 
 // Client represents a NATS client
 type Client struct {
-	nc        *nats.Conn
-	tlsConfig *TLSConfig
-	name      string
+	nc                *nats.Conn // nil on a transport set with WithTransport
+	conn              transport.Conn
+	tlsConfig         *TLSConfig
+	name              string
+	retryPolicy       *RetryPolicy
+	streamWindow      int
+	streamIdleTimeout time.Duration
+	codec             codec.Codec
+	compression       *compressionConfig
+	peerAccept        sync.Map // subject -> LL-Accept-Encoding of the responder
+
+	breakerConfig *CircuitBreakerConfig
+	breakers      map[string]*circuitBreaker
+	breakerMu     sync.Mutex
+
+	tracer         *tracing.Tracer
+	traceReporting bool
+	ownsTracer     bool // created by setupTracing, shut down by Close
+	interceptors   []Interceptor
+	connect        ConnectOptions
+	certs          *CertReloader
+	onCertEvent    func(CertEvent)
+
+	durableConfig DurableStreamConfig
+	durableMu     sync.Mutex
+	js            jetstream.JetStream // set once the durable stream exists
 }
 
 // WithAutoTLS automatically discovers and uses TLS certificates
-// Searches in ./client directory
+// Searches $LIGHTLINK_CERT_DIR, ./client and its parents, the user config dir and /etc/lightlink
 func WithAutoTLS() Option {
+	return WithAutoTLSOptions(types.CertDiscoveryOptions{})
+}
+
+// WithAutoTLSOptions discovers the client certificates with custom search
+// directories and file names
+func WithAutoTLSOptions(opts types.CertDiscoveryOptions) Option {
 	return func(c *Client) error {
-		result, err := types.DiscoverClientCerts()
+		result, err := types.DiscoverCerts(types.ClientCerts, opts)
 		if err != nil {
 			return fmt.Errorf("auto-discover TLS failed: %w", err)
 		}
-		c.tlsConfig = &TLSConfig{
-			CaFile:     result.CaFile,
-			CertFile:   result.CertFile,
-			KeyFile:    result.KeyFile,
-			ServerName: result.ServerName,
-		}
+		c.tlsConfig = TLSConfigFrom(types.CertDiscoveryResultToTLSConfig(result))
 		return nil
 	}
 }
@@ -56,6 +100,14 @@ func WithTLS(tlsConfig *TLSConfig) Option {
 	}
 }
 
+// WithCertEventHandler calls fn when the TLS certificate is reloaded or about to expire
+func WithCertEventHandler(fn func(CertEvent)) Option {
+	return func(c *Client) error {
+		c.onCertEvent = fn
+		return nil
+	}
+}
+
 // WithName sets the client name
 func WithName(name string) Option {
 	return func(c *Client) error {
@@ -64,10 +116,22 @@ func WithName(name string) Option {
 	}
 }
 
-// NewClient creates a new client with options
+// WithTransport uses conn instead of connecting to NATS, e.g. a
+// transport.MemoryBus connection in tests. The URL and the connection and
+// TLS options are ignored.
+func WithTransport(conn transport.Conn) Option {
+	return func(c *Client) error {
+		c.conn = conn
+		return nil
+	}
+}
+
+// NewClient creates a new client with options.
+// url may list several servers separated by commas; WithServers adds more.
 func NewClient(url string, opts ...Option) (*Client, error) {
 	client := &Client{
-		name: "LightLink Client",
+		name:    "LightLink Client",
+		connect: ConnectOptions{MaxReconnects: DefaultMaxReconnects},
 	}
 
 	// Apply options
@@ -80,76 +144,85 @@ func NewClient(url string, opts ...Option) (*Client, error) {
 	// Initialize logger
 	logger.SetLoggerName("LightLink-Client")
 
-	natsOpts := []nats.Option{
-		nats.Name(client.name),
-		nats.ReconnectWait(2 * time.Second),
-		nats.MaxReconnects(10),
-		nats.DisconnectErrHandler(func(nc *nats.Conn, err error) {
+	if client.conn != nil {
+		client.setupTracing()
+		return client, nil
+	}
+
+	natsOpts, err := client.connect.NATSOptions(ConnectHooks{
+		OnDisconnect: func(err error) {
 			if err != nil {
 				logger.Errorf("Disconnected: %s", err.Error())
 			}
-		}),
-		nats.ReconnectHandler(func(nc *nats.Conn) {
-			logger.Infof("Reconnected to %s", nc.ConnectedUrl())
-		}),
+		},
+		OnReconnect: func(url string) {
+			logger.Infof("Reconnected to %s", url)
+		},
+		OnError: func(subject string, err error) {
+			logger.Errorf("NATS error on %q: %v", subject, err)
+		},
+	})
+	if err != nil {
+		return nil, err
 	}
+	natsOpts = append(natsOpts, nats.Name(client.name))
 
-	// Configure TLS
+	// Configure TLS, reloading rotated certificate files on reconnect
 	if client.tlsConfig != nil {
-		tlsOpt, err := CreateTLSOption(client.tlsConfig)
+		certs, err := NewCertReloader(client.tlsConfig, client.onCertEvent)
 		if err != nil {
 			return nil, err
 		}
-		natsOpts = append(natsOpts, tlsOpt)
+		client.certs = certs
+		natsOpts = append(natsOpts, certs.NATSOption())
 	}
 
-	nc, err := nats.Connect(url, natsOpts...)
+	nc, err := nats.Connect(client.connect.URL(url), natsOpts...)
 	if err != nil {
 		return nil, err
 	}
+	if client.certs != nil {
+		client.certs.Start()
+	}
 
 	client.nc = nc
+	client.conn = transport.NewNATS(nc)
+	client.setupTracing()
 	return client, nil
 }
 
 // CreateTLSOption creates a TLS option
 func CreateTLSOption(config *TLSConfig) (nats.Option, error) {
-    // Load client certificate
-    cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
-    if err != nil {
-        return nil, err
-    }
-
-    // Create CA pool
-    pool := x509.NewCertPool()
-    caCert, err := os.ReadFile(config.CaFile)
+    tlsConfig, err := BuildTLSConfig(config)
     if err != nil {
         return nil, err
     }
-    pool.AppendCertsFromPEM(caCert)
-
-    // Create TLS config
-    // For development with self-signed certificates using legacy CN, we skip server name verification
-    // The connection is still encrypted with TLS, and we verify the CA chain
-    tlsConfig := &tls.Config{
-        Certificates:       []tls.Certificate{cert},
-        RootCAs:            pool,
-        MinVersion:         tls.VersionTLS12,
-        InsecureSkipVerify: true, // Skip server name verification for self-signed certs
-    }
-
     return nats.Secure(tlsConfig), nil
 }
 
-// GetNATSConn returns the NATS connection
+// CertReloader returns the reloader of the TLS certificate, or nil without TLS
+func (c *Client) CertReloader() *CertReloader {
+    return c.certs
+}
+
+// GetNATSConn returns the NATS connection, nil when the client was created WithTransport
 func (c *Client) GetNATSConn() *nats.Conn {
     return c.nc
 }
 
+// Transport returns the connection the client sends and receives on
+func (c *Client) Transport() transport.Conn {
+    return c.conn
+}
+
 // Close closes the client
 func (c *Client) Close() error {
-    if c.nc != nil {
-        c.nc.Close()
+    c.shutdownTracing()
+    if c.certs != nil {
+        c.certs.Stop()
+    }
+    if c.conn != nil {
+        c.conn.Close()
     }
     return nil
 }
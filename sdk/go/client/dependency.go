@@ -8,10 +8,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/LiteHomeLab/light_link/sdk/go/transport"
 	"github.com/LiteHomeLab/light_link/sdk/go/types"
 	"github.com/WQGroup/logger"
 	"github.com/nats-io/nats.go"
-	"github.com/nats-io/nats.go/jetstream"
 	"github.com/sirupsen/logrus"
 )
 
@@ -32,18 +32,23 @@ type DependencyCheckResult struct {
 
 // DependencyChecker 依赖检查器
 type DependencyChecker struct {
-	nc         *nats.Conn
+	conn       transport.Conn
 	deps       []Dependency
 	registered map[string]*types.ServiceMetadata // 已注册的服务
 	mu         sync.RWMutex
-	sub        *nats.Subscription
+	sub        transport.Subscription
 	logger     *logrus.Logger
 }
 
 // NewDependencyChecker 创建依赖检查器
 func NewDependencyChecker(nc *nats.Conn, deps []Dependency) *DependencyChecker {
+	return NewDependencyCheckerWithTransport(transport.NewNATS(nc), deps)
+}
+
+// NewDependencyCheckerWithTransport 在任意传输上创建依赖检查器，例如 Client.Transport()
+func NewDependencyCheckerWithTransport(conn transport.Conn, deps []Dependency) *DependencyChecker {
 	return &DependencyChecker{
-		nc:         nc,
+		conn:       conn,
 		deps:       deps,
 		registered: make(map[string]*types.ServiceMetadata),
 		logger:     logrus.New(),
@@ -52,18 +57,11 @@ func NewDependencyChecker(nc *nats.Conn, deps []Dependency) *DependencyChecker {
 
 // queryExistingServices queries existing service metadata from NATS KV store
 func (dc *DependencyChecker) queryExistingServices() error {
-	js, err := jetstream.New(dc.nc)
-	if err != nil {
-		return fmt.Errorf("failed to create jetstream context: %w", err)
-	}
-
 	// Get or create KV bucket
-	kv, err := js.KeyValue(context.Background(), "light_link_state")
+	kv, err := dc.conn.KeyValue(context.Background(), "light_link_state")
 	if err != nil {
 		// Try to create the bucket
-		kv, err = js.CreateKeyValue(context.Background(), jetstream.KeyValueConfig{
-			Bucket: "light_link_state",
-		})
+		kv, err = dc.conn.CreateKeyValue(context.Background(), "light_link_state")
 		if err != nil {
 			return fmt.Errorf("failed to get or create KV store: %w", err)
 		}
@@ -177,7 +175,7 @@ func (dc *DependencyChecker) WaitForDependencies(ctx context.Context) error {
 	}
 
 	// Subscribe to registration messages
-	sub, err := dc.nc.Subscribe("$LL.register.>", func(msg *nats.Msg) {
+	sub, err := dc.conn.Subscribe("$LL.register.>", func(msg *nats.Msg) {
 		dc.handleRegisterMessage(msg)
 	})
 	if err != nil {
@@ -0,0 +1,422 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/WQGroup/logger"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	// DefaultDurableStream is the name of the JetStream stream holding durable messages
+	DefaultDurableStream = "LIGHTLINK_DURABLE"
+	// DefaultDurableMaxAge is how long durable messages are kept by default
+	DefaultDurableMaxAge = 7 * 24 * time.Hour
+	// DefaultDurableAckWait is how long a delivered message may stay unacknowledged before it is redelivered
+	DefaultDurableAckWait = 30 * time.Second
+	// DefaultDurableMaxDeliver is how often a message is delivered before it is given up
+	DefaultDurableMaxDeliver = 5
+
+	// durableTimeout bounds the JetStream API calls of the durable API
+	durableTimeout = 5 * time.Second
+	// deadLetterRetryDelay is how long a message waits for redelivery after
+	// storing it as dead letter failed
+	deadLetterRetryDelay = 5 * time.Second
+)
+
+// ErrDurableUnavailable is returned by the durable API on a transport without JetStream
+var ErrDurableUnavailable = errors.New("durable topics need a NATS connection with JetStream")
+
+// DurableStreamConfig configures the JetStream stream storing durable messages.
+// The stream is created, or updated to match, on first use.
+type DurableStreamConfig struct {
+	// Name of the stream, DefaultDurableStream when empty
+	Name string
+	// MaxAge is how long messages are kept; zero uses DefaultDurableMaxAge
+	// and a negative value keeps them until a size limit is reached
+	MaxAge time.Duration
+	// MaxMsgs and MaxBytes limit the stream size, dropping the oldest
+	// messages; zero means no limit
+	MaxMsgs  int64
+	MaxBytes int64
+	// MaxMsgsPerSubject limits the messages kept per subject; zero means no limit
+	MaxMsgsPerSubject int64
+	// Replicas is the number of stream replicas in a cluster, 1 when zero
+	Replicas int
+	// Memory stores the messages in memory instead of on disk
+	Memory bool
+}
+
+// DeliverPolicy selects where a new durable subscription starts
+type DeliverPolicy int
+
+const (
+	// DeliverNew starts with the messages published after the subscription is created
+	DeliverNew DeliverPolicy = iota
+	// DeliverAll starts with the oldest message kept in the stream
+	DeliverAll
+	// DeliverFromSequence starts at DurableOptions.StartSequence
+	DeliverFromSequence
+	// DeliverFromTime starts with the messages stored at or after DurableOptions.StartTime
+	DeliverFromTime
+)
+
+// DurableOptions configures a durable subscription. Deliver, StartSequence and
+// StartTime only apply when the subscription is created; an existing
+// subscription resumes after the last acknowledged message.
+type DurableOptions struct {
+	Deliver       DeliverPolicy
+	StartSequence uint64
+	StartTime     time.Time
+
+	// AckWait is how long a message may stay unacknowledged before it is
+	// redelivered; zero uses DefaultDurableAckWait
+	AckWait time.Duration
+	// MaxDeliver is how often a message is delivered; zero uses
+	// DefaultDurableMaxDeliver and a negative value redelivers forever
+	MaxDeliver int
+	// DeadLetterSubject receives the messages given up after MaxDeliver
+	// deliveries or terminated with Term, with the LL-Dead-Letter-* headers
+	// set. Dead letters are durable messages themselves, read them with
+	// SubscribeDurable; the subject must not match the subscribed subject.
+	DeadLetterSubject string
+	// AutoAck acknowledges messages the handler returns from without calling
+	// Ack, Nak or Term
+	AutoAck bool
+}
+
+// DurableHandler handles a durable message; it acknowledges it with Ack,
+// asks for redelivery with Nak or gives it up with Term
+type DurableHandler func(msg *DurableMessage)
+
+// DurableMessage is a message delivered to a durable subscription
+type DurableMessage struct {
+	// Subject is the subject the message was published on
+	Subject string
+	// Data is the decoded JSON payload, nil when it does not decode
+	Data map[string]interface{}
+	// Raw is the payload, decompressed
+	Raw    []byte
+	Header nats.Header
+	// Sequence is the position of the message in the stream
+	Sequence uint64
+	// NumDelivered counts the deliveries, starting at 1
+	NumDelivered uint64
+	// Timestamp is when the message was stored
+	Timestamp time.Time
+
+	msg     jetstream.Msg
+	sub     *DurableSubscription
+	settled bool
+}
+
+// Ack acknowledges the message, it is not delivered again
+func (m *DurableMessage) Ack() error {
+	m.settled = true
+	return m.msg.Ack()
+}
+
+// Nak asks for the message to be redelivered, after delay if positive
+func (m *DurableMessage) Nak(delay time.Duration) error {
+	m.settled = true
+	if delay > 0 {
+		return m.msg.NakWithDelay(delay)
+	}
+	return m.msg.Nak()
+}
+
+// Term gives the message up. With a dead-letter subject it is stored there
+// first; if that fails the message is redelivered later instead of being lost.
+func (m *DurableMessage) Term(reason string) error {
+	m.settled = true
+	if err := m.sub.deadLetter(m, reason); err != nil {
+		logger.Errorf("Dead-letter message %d of %s failed: %v", m.Sequence, m.sub.name, err)
+		m.msg.NakWithDelay(deadLetterRetryDelay)
+		return err
+	}
+	return m.msg.TermWithReason(reason)
+}
+
+// DurableSubscription is a durable subscription; the JetStream consumer keeps
+// its position while the subscriber is down
+type DurableSubscription struct {
+	js      jetstream.JetStream
+	name    string
+	opts    DurableOptions
+	handler DurableHandler
+	consume jetstream.ConsumeContext
+}
+
+// Unsubscribe stops receiving messages. The subscription keeps its position
+// and resumes when subscribed again under the same name.
+func (s *DurableSubscription) Unsubscribe() error {
+	s.consume.Stop()
+	return nil
+}
+
+// WithDurableStream configures the stream of the durable API
+func WithDurableStream(config DurableStreamConfig) Option {
+	return func(c *Client) error {
+		c.durableConfig = config
+		return nil
+	}
+}
+
+// PublishDurable stores a message in the durable stream, where subscribers
+// receive it even if they were down when it was published. It returns the
+// sequence of the message in the stream.
+func (c *Client) PublishDurable(subject string, data map[string]interface{}) (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), durableTimeout)
+	defer cancel()
+	return c.PublishDurableContext(ctx, subject, data)
+}
+
+// PublishDurableContext is PublishDurable with a context bounding the wait for
+// the stream to acknowledge the message
+func (c *Client) PublishDurableContext(ctx context.Context, subject string, data map[string]interface{}) (uint64, error) {
+	js, err := c.durableStream(ctx)
+	if err != nil {
+		return 0, err
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return 0, err
+	}
+	ack, err := js.Publish(ctx, types.DurableSubject(subject), payload)
+	if err != nil {
+		return 0, fmt.Errorf("publish durable message: %w", err)
+	}
+	return ack.Sequence, nil
+}
+
+// SubscribeDurable subscribes under name to the durable messages of subject,
+// which may contain wildcards. Subscribers sharing a name share the work,
+// every message goes to one of them.
+func (c *Client) SubscribeDurable(name, subject string, opts DurableOptions, handler DurableHandler) (*DurableSubscription, error) {
+	if name == "" || strings.ContainsAny(name, ".*> \t\r\n") {
+		return nil, fmt.Errorf("invalid durable subscription name %q", name)
+	}
+	if opts.DeadLetterSubject != "" && types.MatchSubject(subject, opts.DeadLetterSubject) {
+		return nil, fmt.Errorf("dead-letter subject %s matches the subscribed subject %s", opts.DeadLetterSubject, subject)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), durableTimeout)
+	defer cancel()
+	js, err := c.durableStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := opts.consumerConfig(name, subject)
+	if err != nil {
+		return nil, err
+	}
+	stream := c.durableConfig.name()
+	if existing, err := js.Consumer(ctx, stream, name); err == nil {
+		// The start position cannot change, keep it
+		info := existing.CachedInfo()
+		config.DeliverPolicy = info.Config.DeliverPolicy
+		config.OptStartSeq = info.Config.OptStartSeq
+		config.OptStartTime = info.Config.OptStartTime
+	} else if !errors.Is(err, jetstream.ErrConsumerNotFound) {
+		return nil, fmt.Errorf("look up durable subscription: %w", err)
+	}
+	consumer, err := js.CreateOrUpdateConsumer(ctx, stream, config)
+	if err != nil {
+		return nil, fmt.Errorf("create durable subscription: %w", err)
+	}
+
+	sub := &DurableSubscription{js: js, name: name, opts: opts, handler: handler}
+	sub.consume, err = consumer.Consume(sub.handle)
+	if err != nil {
+		return nil, fmt.Errorf("consume durable subscription: %w", err)
+	}
+	return sub, nil
+}
+
+// handle passes a delivery to the handler, or dead-letters it once it
+// exceeded the delivery limit
+func (s *DurableSubscription) handle(msg jetstream.Msg) {
+	meta, err := msg.Metadata()
+	if err != nil {
+		logger.Errorf("Durable message without metadata: %v", err)
+		msg.Term()
+		return
+	}
+	m := &DurableMessage{
+		Subject:      strings.TrimPrefix(msg.Subject(), types.DurableSubjectPrefix+"."),
+		Header:       msg.Headers(),
+		Sequence:     meta.Sequence.Stream,
+		NumDelivered: meta.NumDelivered,
+		Timestamp:    meta.Timestamp,
+		msg:          msg,
+		sub:          s,
+	}
+
+	// With a dead-letter subject the limit is enforced here rather than by
+	// the consumer, so a message is redelivered until it is stored there
+	if limit := s.opts.maxDeliver(); limit > 0 && s.opts.DeadLetterSubject != "" && int(meta.NumDelivered) > limit {
+		m.Raw = msg.Data()
+		m.Term("max deliveries exceeded")
+		return
+	}
+
+	m.Raw, err = messagePayload(&nats.Msg{Data: msg.Data(), Header: msg.Headers()})
+	if err != nil {
+		m.Raw = msg.Data()
+	}
+	json.Unmarshal(m.Raw, &m.Data)
+
+	s.handler(m)
+	if s.opts.AutoAck && !m.settled {
+		m.Ack()
+	}
+}
+
+// deadLetter stores m under the dead-letter subject, if one is set, and
+// returns once the stream acknowledged it
+func (s *DurableSubscription) deadLetter(m *DurableMessage, reason string) error {
+	if s.opts.DeadLetterSubject == "" {
+		return nil
+	}
+	msg := nats.NewMsg(types.DurableSubject(s.opts.DeadLetterSubject))
+	for key, values := range m.Header {
+		// JetStream headers such as Nats-Msg-Id would apply to the dead letter
+		if !strings.HasPrefix(key, "Nats-") {
+			msg.Header[key] = values
+		}
+	}
+	msg.Data = m.msg.Data()
+	msg.Header.Set(types.HeaderDeadLetterSubject, m.Subject)
+	msg.Header.Set(types.HeaderDeadLetterSeq, strconv.FormatUint(m.Sequence, 10))
+	msg.Header.Set(types.HeaderDeadLetterDeliveries, strconv.FormatUint(m.NumDelivered, 10))
+	msg.Header.Set(types.HeaderDeadLetterReason, reason)
+
+	ctx, cancel := context.WithTimeout(context.Background(), durableTimeout)
+	defer cancel()
+	_, err := s.js.PublishMsg(ctx, msg)
+	return err
+}
+
+// consumerConfig returns the JetStream consumer of the subscription
+func (o DurableOptions) consumerConfig(name, subject string) (jetstream.ConsumerConfig, error) {
+	config := jetstream.ConsumerConfig{
+		Durable:       name,
+		FilterSubject: types.DurableSubject(subject),
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       o.AckWait,
+		MaxDeliver:    o.maxDeliver(),
+	}
+	if config.AckWait <= 0 {
+		config.AckWait = DefaultDurableAckWait
+	}
+	if o.DeadLetterSubject != "" {
+		// handle enforces the limit once the message is stored as dead letter
+		config.MaxDeliver = -1
+	}
+
+	switch o.Deliver {
+	case DeliverNew:
+		config.DeliverPolicy = jetstream.DeliverNewPolicy
+	case DeliverAll:
+		config.DeliverPolicy = jetstream.DeliverAllPolicy
+	case DeliverFromSequence:
+		if o.StartSequence == 0 {
+			return config, errors.New("DeliverFromSequence needs a StartSequence")
+		}
+		config.DeliverPolicy = jetstream.DeliverByStartSequencePolicy
+		config.OptStartSeq = o.StartSequence
+	case DeliverFromTime:
+		if o.StartTime.IsZero() {
+			return config, errors.New("DeliverFromTime needs a StartTime")
+		}
+		config.DeliverPolicy = jetstream.DeliverByStartTimePolicy
+		startTime := o.StartTime
+		config.OptStartTime = &startTime
+	default:
+		return config, fmt.Errorf("unknown deliver policy %d", o.Deliver)
+	}
+	return config, nil
+}
+
+// maxDeliver returns the delivery limit, -1 for none
+func (o DurableOptions) maxDeliver() int {
+	switch {
+	case o.MaxDeliver == 0:
+		return DefaultDurableMaxDeliver
+	case o.MaxDeliver < 0:
+		return -1
+	}
+	return o.MaxDeliver
+}
+
+// name returns the stream name
+func (c DurableStreamConfig) name() string {
+	if c.Name == "" {
+		return DefaultDurableStream
+	}
+	return c.Name
+}
+
+// streamConfig returns the JetStream stream configuration
+func (c DurableStreamConfig) streamConfig() jetstream.StreamConfig {
+	config := jetstream.StreamConfig{
+		Name:              c.name(),
+		Subjects:          []string{types.DurableSubject(">")},
+		Retention:         jetstream.LimitsPolicy,
+		Discard:           jetstream.DiscardOld,
+		MaxAge:            c.MaxAge,
+		MaxMsgs:           c.MaxMsgs,
+		MaxBytes:          c.MaxBytes,
+		MaxMsgsPerSubject: c.MaxMsgsPerSubject,
+		Replicas:          c.Replicas,
+		Storage:           jetstream.FileStorage,
+	}
+	switch {
+	case config.MaxAge == 0:
+		config.MaxAge = DefaultDurableMaxAge
+	case config.MaxAge < 0:
+		config.MaxAge = 0
+	}
+	for _, limit := range []*int64{&config.MaxMsgs, &config.MaxBytes, &config.MaxMsgsPerSubject} {
+		if *limit == 0 {
+			*limit = -1
+		}
+	}
+	if config.Replicas == 0 {
+		config.Replicas = 1
+	}
+	if c.Memory {
+		config.Storage = jetstream.MemoryStorage
+	}
+	return config
+}
+
+// durableStream returns the JetStream context once the durable stream exists
+func (c *Client) durableStream(ctx context.Context) (jetstream.JetStream, error) {
+	c.durableMu.Lock()
+	defer c.durableMu.Unlock()
+
+	if c.js != nil {
+		return c.js, nil
+	}
+	if c.nc == nil {
+		return nil, ErrDurableUnavailable
+	}
+	js, err := jetstream.New(c.nc)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := js.CreateOrUpdateStream(ctx, c.durableConfig.streamConfig()); err != nil {
+		return nil, fmt.Errorf("create durable stream %s: %w", c.durableConfig.name(), err)
+	}
+	c.js = js
+	return js, nil
+}
@@ -0,0 +1,196 @@
+package client
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/testutil"
+	"github.com/LiteHomeLab/light_link/sdk/go/transport"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+)
+
+func newDurableClient(t *testing.T) *Client {
+	t.Helper()
+	srv := testutil.StartNATS(t, testutil.Options{})
+	c, err := NewClient(srv.URL, WithDurableStream(DurableStreamConfig{Memory: true}))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func nextDurable(t *testing.T, received <-chan *DurableMessage) *DurableMessage {
+	t.Helper()
+	select {
+	case msg := <-received:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("Durable message not delivered")
+		return nil
+	}
+}
+
+func TestDurableReplay(t *testing.T) {
+	c := newDurableClient(t)
+
+	received := make(chan *DurableMessage, 10)
+	sub, err := c.SubscribeDurable("billing", "orders.*", DurableOptions{Deliver: DeliverAll, AutoAck: true}, func(msg *DurableMessage) {
+		received <- msg
+	})
+	if err != nil {
+		t.Fatalf("SubscribeDurable failed: %v", err)
+	}
+	c.PublishDurable("orders.created", map[string]interface{}{"id": float64(1)})
+	if msg := nextDurable(t, received); msg.Subject != "orders.created" || msg.Data["id"] != float64(1) || msg.NumDelivered != 1 {
+		t.Errorf("Unexpected message %+v", msg)
+	}
+	sub.Unsubscribe()
+
+	// Published while the subscriber is down
+	seq, err := c.PublishDurable("orders.paid", map[string]interface{}{"id": float64(2)})
+	if err != nil || seq != 2 {
+		t.Fatalf("PublishDurable returned %d, %v", seq, err)
+	}
+	sub, err = c.SubscribeDurable("billing", "orders.*", DurableOptions{AutoAck: true}, func(msg *DurableMessage) {
+		received <- msg
+	})
+	if err != nil {
+		t.Fatalf("Resubscribe failed: %v", err)
+	}
+	defer sub.Unsubscribe()
+	if msg := nextDurable(t, received); msg.Subject != "orders.paid" || msg.Sequence != 2 {
+		t.Errorf("Expected the missed message, got %+v", msg)
+	}
+}
+
+func TestDurableDeliverFrom(t *testing.T) {
+	c := newDurableClient(t)
+
+	for i := 1; i <= 3; i++ {
+		c.PublishDurable("events", map[string]interface{}{"n": float64(i)})
+	}
+	received := make(chan *DurableMessage, 10)
+	handler := func(msg *DurableMessage) {
+		msg.Ack()
+		received <- msg
+	}
+
+	sub, err := c.SubscribeDurable("from-seq", "events", DurableOptions{Deliver: DeliverFromSequence, StartSequence: 2}, handler)
+	if err != nil {
+		t.Fatalf("SubscribeDurable failed: %v", err)
+	}
+	for _, want := range []uint64{2, 3} {
+		if msg := nextDurable(t, received); msg.Sequence != want {
+			t.Errorf("Expected sequence %d, got %d", want, msg.Sequence)
+		}
+	}
+	sub.Unsubscribe()
+
+	time.Sleep(10 * time.Millisecond)
+	start := time.Now()
+	c.PublishDurable("events", map[string]interface{}{"n": float64(4)})
+	sub, err = c.SubscribeDurable("from-time", "events", DurableOptions{Deliver: DeliverFromTime, StartTime: start}, handler)
+	if err != nil {
+		t.Fatalf("SubscribeDurable failed: %v", err)
+	}
+	defer sub.Unsubscribe()
+	if msg := nextDurable(t, received); msg.Sequence != 4 {
+		t.Errorf("Expected sequence 4, got %d", msg.Sequence)
+	}
+
+	if _, err := c.SubscribeDurable("bad", "events", DurableOptions{Deliver: DeliverFromSequence}, handler); err == nil {
+		t.Error("Expected an error without StartSequence")
+	}
+	if _, err := c.SubscribeDurable("a.b", "events", DurableOptions{}, handler); err == nil {
+		t.Error("Expected an error for an invalid name")
+	}
+}
+
+func TestDurableDeadLetter(t *testing.T) {
+	c := newDurableClient(t)
+
+	delivered := make(chan *DurableMessage, 10)
+	sub, err := c.SubscribeDurable("retry", "orders.>", DurableOptions{
+		Deliver:           DeliverAll,
+		MaxDeliver:        2,
+		DeadLetterSubject: "dead.orders",
+	}, func(msg *DurableMessage) {
+		delivered <- msg
+		if msg.Data["poison"] == true {
+			msg.Term("poison")
+		} else {
+			msg.Nak(0)
+		}
+	})
+	if err != nil {
+		t.Fatalf("SubscribeDurable failed: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	// Redelivered up to MaxDeliver, then dead-lettered
+	c.PublishDurable("orders.retry", map[string]interface{}{"poison": false})
+	for want := uint64(1); want <= 2; want++ {
+		if msg := nextDurable(t, delivered); msg.NumDelivered != want {
+			t.Errorf("Expected delivery %d, got %d", want, msg.NumDelivered)
+		}
+	}
+	// Nobody read the dead letters while they were stored
+	deadLetters := make(chan *DurableMessage, 10)
+	deadSub, err := c.SubscribeDurable("dead-reader", "dead.orders", DurableOptions{Deliver: DeliverAll, AutoAck: true}, func(msg *DurableMessage) {
+		deadLetters <- msg
+	})
+	if err != nil {
+		t.Fatalf("SubscribeDurable dead letters failed: %v", err)
+	}
+	defer deadSub.Unsubscribe()
+	msg := nextDurable(t, deadLetters)
+	if msg.Subject != "dead.orders" || msg.Data["poison"] != false {
+		t.Errorf("Unexpected dead letter %+v", msg)
+	}
+	if msg.Header.Get(types.HeaderDeadLetterSubject) != "orders.retry" ||
+		msg.Header.Get(types.HeaderDeadLetterSeq) != "1" ||
+		msg.Header.Get(types.HeaderDeadLetterDeliveries) != "3" ||
+		msg.Header.Get(types.HeaderDeadLetterReason) != "max deliveries exceeded" {
+		t.Errorf("Unexpected dead-letter headers %v", msg.Header)
+	}
+	select {
+	case msg := <-delivered:
+		t.Errorf("Handler called after the limit: %+v", msg)
+	default:
+	}
+
+	// Terminated by the handler
+	c.PublishDurable("orders.poison", map[string]interface{}{"poison": true})
+	nextDurable(t, delivered)
+	msg = nextDurable(t, deadLetters)
+	if msg.Header.Get(types.HeaderDeadLetterReason) != "poison" || msg.Header.Get(types.HeaderDeadLetterDeliveries) != "1" {
+		t.Errorf("Unexpected dead-letter headers %v", msg.Header)
+	}
+}
+
+func TestDurableDeadLetterSubjectLoop(t *testing.T) {
+	c := newDurableClient(t)
+
+	_, err := c.SubscribeDurable("loop", "orders.>", DurableOptions{DeadLetterSubject: "orders.dead"}, func(*DurableMessage) {})
+	if err == nil {
+		t.Error("Expected an error for a dead-letter subject matching the subscription")
+	}
+}
+
+func TestDurableRequiresNATS(t *testing.T) {
+	c, err := NewClient("", WithTransport(transport.NewMemoryBus().Connect()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer c.Close()
+
+	if _, err := c.PublishDurable("orders", nil); !errors.Is(err, ErrDurableUnavailable) {
+		t.Errorf("Expected ErrDurableUnavailable, got %v", err)
+	}
+	_, err = c.SubscribeDurable("billing", "orders", DurableOptions{}, func(*DurableMessage) {})
+	if !errors.Is(err, ErrDurableUnavailable) {
+		t.Errorf("Expected ErrDurableUnavailable, got %v", err)
+	}
+}
@@ -2,26 +2,17 @@ package client
 
 import (
     "context"
-    "io"
     "os"
 
     "github.com/google/uuid"
-    "github.com/nats-io/nats.go/jetstream"
 )
 
 // UploadFile uploads file to Object Store
 func (c *Client) UploadFile(filePath, fileName string) (string, error) {
-    js, err := jetstream.New(c.nc)
-    if err != nil {
-        return "", err
-    }
-
     // Get or create Object Store
-    store, err := js.ObjectStore(context.Background(), "light_link_files")
+    store, err := c.conn.ObjectStore(context.Background(), "light_link_files")
     if err != nil {
-        store, err = js.CreateObjectStore(context.Background(), jetstream.ObjectStoreConfig{
-            Bucket: "light_link_files",
-        })
+        store, err = c.conn.CreateObjectStore(context.Background(), "light_link_files")
         if err != nil {
             return "", err
         }
@@ -47,25 +38,13 @@ func (c *Client) UploadFile(filePath, fileName string) (string, error) {
 
 // DownloadFile downloads file from Object Store
 func (c *Client) DownloadFile(fileID, destPath string) error {
-    js, err := jetstream.New(c.nc)
-    if err != nil {
-        return err
-    }
-
-    store, err := js.ObjectStore(context.Background(), "light_link_files")
+    store, err := c.conn.ObjectStore(context.Background(), "light_link_files")
     if err != nil {
         return err
     }
 
     // Get file
-    result, err := store.Get(context.Background(), fileID)
-    if err != nil {
-        return err
-    }
-    defer result.Close()
-
-    // Read all data
-    data, err := io.ReadAll(result)
+    data, err := store.GetBytes(context.Background(), fileID)
     if err != nil {
         return err
     }
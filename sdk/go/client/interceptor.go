@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/WQGroup/logger"
+	"github.com/nats-io/nats.go"
+)
+
+// CallKind is the shape of an RPC call
+type CallKind string
+
+const (
+	// CallKindUnary is a call answered by one instance, made with Call,
+	// CallWithTimeout, CallContext or CallInstance
+	CallKindUnary CallKind = "unary"
+	// CallKindBroadcast is a call answered by every instance, made with CallAll
+	CallKindBroadcast CallKind = "broadcast"
+	// CallKindStream is a server-streaming call made with CallStream
+	CallKindStream CallKind = "stream"
+)
+
+// CallInfo describes an outgoing RPC call
+type CallInfo struct {
+	Kind CallKind
+	// Service is the called service, empty for instance-addressed calls
+	Service string
+	// InstanceKey is the addressed instance, empty for load-balanced calls
+	InstanceKey string
+	Method      string
+	Subject     string
+	// Header holds extra request headers sent with the call. Headers used by
+	// the RPC protocol itself are overwritten.
+	Header nats.Header
+}
+
+// Invoker performs an RPC call, either the next interceptor or the request itself
+type Invoker func(ctx context.Context, info *CallInfo, args map[string]interface{}) (map[string]interface{}, error)
+
+// Interceptor wraps every RPC call made with Call, CallWithTimeout, CallContext,
+// CallInstance, CallAll and CallStream. It may inspect or replace the args and
+// headers, call invoker to continue the chain, and inspect or replace the
+// result and error. For CallAll the invoker returns once the replies are
+// collected and for CallStream once the stream is open; both return a nil
+// result, the replies and the stream are returned to the caller directly.
+type Interceptor func(ctx context.Context, info *CallInfo, args map[string]interface{}, invoker Invoker) (map[string]interface{}, error)
+
+// WithInterceptor appends interceptors to the chain. The first interceptor is
+// the outermost: it runs first and sees the final result. Retries and the
+// circuit breaker sit inside the chain, so an interceptor sees one call.
+func WithInterceptor(interceptors ...Interceptor) Option {
+	return func(c *Client) error {
+		c.interceptors = append(c.interceptors, interceptors...)
+		return nil
+	}
+}
+
+// intercept calls invoker through the interceptor chain
+func (c *Client) intercept(ctx context.Context, info *CallInfo, args map[string]interface{}, invoker Invoker) (map[string]interface{}, error) {
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		ic, next := c.interceptors[i], invoker
+		invoker = func(ctx context.Context, info *CallInfo, args map[string]interface{}) (map[string]interface{}, error) {
+			return ic(ctx, info, args, next)
+		}
+	}
+	return invoker(ctx, info, args)
+}
+
+// LoggingInterceptor logs every call with its target, duration and outcome as structured fields
+func LoggingInterceptor() Interceptor {
+	return func(ctx context.Context, info *CallInfo, args map[string]interface{}, invoker Invoker) (map[string]interface{}, error) {
+		start := time.Now()
+		result, err := invoker(ctx, info, args)
+
+		entry := logger.WithFields(map[string]interface{}{
+			"subject":     info.Subject,
+			"method":      info.Method,
+			"duration_ms": time.Since(start).Milliseconds(),
+		})
+		if err != nil {
+			entry.WithField("error", err.Error()).Warn("RPC call failed")
+		} else {
+			entry.Debug("RPC call succeeded")
+		}
+		return result, err
+	}
+}
+
+// TimingInterceptor reports the duration and error of every call to observe
+func TimingInterceptor(observe func(info *CallInfo, duration time.Duration, err error)) Interceptor {
+	return func(ctx context.Context, info *CallInfo, args map[string]interface{}, invoker Invoker) (map[string]interface{}, error) {
+		start := time.Now()
+		result, err := invoker(ctx, info, args)
+		observe(info, time.Since(start), err)
+		return result, err
+	}
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestInterceptorChain(t *testing.T) {
+	var order []string
+	record := func(name string) Interceptor {
+		return func(ctx context.Context, info *CallInfo, args map[string]interface{}, invoker Invoker) (map[string]interface{}, error) {
+			order = append(order, name+":before")
+			info.Header.Add("X-Chain", name)
+			result, err := invoker(ctx, info, args)
+			order = append(order, name+":after")
+			return result, err
+		}
+	}
+
+	var observed time.Duration
+	c := &Client{}
+	for _, opt := range []Option{
+		WithInterceptor(record("first"), record("second")),
+		WithInterceptor(TimingInterceptor(func(info *CallInfo, d time.Duration, err error) { observed = d })),
+	} {
+		if err := opt(c); err != nil {
+			t.Fatalf("Option failed: %v", err)
+		}
+	}
+
+	info := &CallInfo{Service: "svc", Method: "m", Subject: "$SRV.svc.m", Header: nats.Header{}}
+	result, err := c.intercept(context.Background(), info, map[string]interface{}{"a": 1}, func(ctx context.Context, info *CallInfo, args map[string]interface{}) (map[string]interface{}, error) {
+		order = append(order, "invoke")
+		time.Sleep(time.Millisecond)
+		return args, nil
+	})
+	if err != nil || result["a"] != 1 {
+		t.Fatalf("Unexpected result %v, %v", result, err)
+	}
+
+	expected := "first:before,second:before,invoke,second:after,first:after"
+	if strings.Join(order, ",") != expected {
+		t.Errorf("Expected order %s, got %v", expected, order)
+	}
+	if got := info.Header.Values("X-Chain"); len(got) != 2 || got[0] != "first" {
+		t.Errorf("Expected headers from both interceptors, got %v", got)
+	}
+	if observed < time.Millisecond {
+		t.Errorf("Expected timing interceptor to observe the call, got %v", observed)
+	}
+}
+
+func TestInterceptorShortCircuit(t *testing.T) {
+	denied := errors.New("denied by policy")
+	c := &Client{}
+	WithInterceptor(LoggingInterceptor(), func(ctx context.Context, info *CallInfo, args map[string]interface{}, invoker Invoker) (map[string]interface{}, error) {
+		return nil, denied
+	})(c)
+
+	_, err := c.intercept(context.Background(), &CallInfo{Header: nats.Header{}}, nil, func(ctx context.Context, info *CallInfo, args map[string]interface{}) (map[string]interface{}, error) {
+		t.Error("Expected invoker not to be called")
+		return nil, nil
+	})
+	if !errors.Is(err, denied) {
+		t.Errorf("Expected policy error, got %v", err)
+	}
+}
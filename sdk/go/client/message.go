@@ -0,0 +1,97 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+// ErrNoReply is returned by Message.Respond when the sender expects no reply
+var ErrNoReply = errors.New("message has no reply subject")
+
+// MsgHandler handles a message with its envelope. A returned error is passed
+// to SubscribeOptions.OnError.
+type MsgHandler func(msg *Message) error
+
+// Message is a message received by a subscription
+type Message struct {
+	// Subject is the subject the message was published on, useful with wildcards
+	Subject string
+	Header  nats.Header
+	// Raw is the payload, decompressed
+	Raw []byte
+	// Data is the decoded JSON payload
+	Data map[string]interface{}
+	// Reply is the subject Respond answers on, empty when no reply is expected
+	Reply string
+	// Timestamp is when the message was received
+	Timestamp time.Time
+
+	client *Client
+}
+
+// Respond publishes data to the reply subject of the message
+func (m *Message) Respond(data map[string]interface{}) error {
+	if m.Reply == "" {
+		return ErrNoReply
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return m.client.conn.Publish(m.Reply, payload)
+}
+
+// DecodeError is passed to SubscribeOptions.OnError for a payload that does
+// not decompress or is not a JSON object
+type DecodeError struct {
+	Subject string
+	Err     error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decode message on %s: %v", e.Subject, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// newMessage decodes msg, returning a *DecodeError with the partly filled
+// message if the payload is not a JSON object
+func (c *Client) newMessage(msg *nats.Msg) (*Message, error) {
+	m := &Message{
+		Subject:   msg.Subject,
+		Header:    msg.Header,
+		Raw:       msg.Data,
+		Reply:     msg.Reply,
+		Timestamp: time.Now(),
+		client:    c,
+	}
+	payload, err := messagePayload(msg)
+	if err != nil {
+		return m, &DecodeError{Subject: msg.Subject, Err: err}
+	}
+	m.Raw = payload
+	if err := json.Unmarshal(payload, &m.Data); err != nil {
+		return m, &DecodeError{Subject: msg.Subject, Err: err}
+	}
+	return m, nil
+}
+
+// deadLetter publishes the undecodable msg to subject with the dead-letter
+// headers set
+func (c *Client) deadLetter(subject string, msg *nats.Msg, reason error) error {
+	dead := nats.NewMsg(subject)
+	for key, values := range msg.Header {
+		dead.Header[key] = values
+	}
+	dead.Data = msg.Data
+	dead.Header.Set(types.HeaderDeadLetterSubject, msg.Subject)
+	dead.Header.Set(types.HeaderDeadLetterReason, reason.Error())
+	return c.conn.PublishMsg(dead)
+}
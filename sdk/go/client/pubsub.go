@@ -2,45 +2,192 @@ package client
 
 import (
     "encoding/json"
+    "fmt"
+    "sync"
 
+    "github.com/LiteHomeLab/light_link/sdk/go/codec"
+    "github.com/LiteHomeLab/light_link/sdk/go/transport"
+    "github.com/LiteHomeLab/light_link/sdk/go/types"
+    "github.com/WQGroup/logger"
     "github.com/nats-io/nats.go"
 )
 
 // MessageHandler message handler
 type MessageHandler func(data map[string]interface{})
 
+// SubscribeOptions configures a subscription
+type SubscribeOptions struct {
+    // Queue joins the named queue group. Each message goes to one member of
+    // the group, spreading the work across the subscribers.
+    Queue string
+    // PendingMsgs and PendingBytes limit the messages buffered while the
+    // handlers are busy; messages beyond them are dropped. Zero keeps the NATS
+    // defaults and a negative value removes the limit.
+    PendingMsgs  int
+    PendingBytes int
+    // Concurrency is the number of workers running the handler. With 0 or 1
+    // messages are handled one at a time in order; with more they are handled
+    // in parallel and may complete out of order.
+    Concurrency int
+    // OnError is called with payloads that do not decode, as a *DecodeError,
+    // and with the errors returned by a MsgHandler. When nil they are logged.
+    OnError func(msg *Message, err error)
+    // DeadLetterSubject receives the payloads that do not decode, unchanged
+    // and with the LL-Dead-Letter-* headers set. Subscribing fails when it
+    // matches the subscribed subject.
+    DeadLetterSubject string
+}
+
 // Subscription represents a subscription
 type Subscription struct {
-    sub *nats.Subscription
+    sub      transport.Subscription
+    stop     chan struct{} // closed on Unsubscribe, stops the workers
+    stopOnce sync.Once
 }
 
-// Unsubscribe unsubscribes
+// Unsubscribe unsubscribes. Handlers already running finish, messages not
+// yet handed to a worker are dropped.
 func (s *Subscription) Unsubscribe() error {
+    if s.stop != nil {
+        s.stopOnce.Do(func() { close(s.stop) })
+    }
     if s.sub != nil {
         return s.sub.Unsubscribe()
     }
     return nil
 }
 
-// Publish publishes a message
+// Publish publishes a message.
+// Large payloads are compressed if WithPublishCompression is set.
 func (c *Client) Publish(subject string, data map[string]interface{}) error {
     msgData, err := json.Marshal(data)
     if err != nil {
         return err
     }
 
-    return c.nc.Publish(subject, msgData)
+    if c.compression == nil || !c.compression.publish {
+        return c.conn.Publish(subject, msgData)
+    }
+
+    msgData, encoding := codec.CompressPayload(msgData, c.compression.encoding, c.compression.threshold)
+    if encoding == "" {
+        return c.conn.Publish(subject, msgData)
+    }
+    msg := nats.NewMsg(subject)
+    msg.Data = msgData
+    msg.Header.Set(types.HeaderContentEncoding, encoding)
+    return c.conn.PublishMsg(msg)
 }
 
-// Subscribe subscribes to messages
+// Subscribe subscribes to messages, decompressing compressed payloads
 func (c *Client) Subscribe(subject string, handler MessageHandler) (*Subscription, error) {
-    sub, err := c.nc.Subscribe(subject, func(msg *nats.Msg) {
-        var data map[string]interface{}
-        if err := json.Unmarshal(msg.Data, &data); err != nil {
+    return c.SubscribeWithOptions(subject, SubscribeOptions{}, handler)
+}
+
+// SubscribeQueue subscribes as a member of the queue group, so each message
+// is handled by only one of the subscribers sharing the group name
+func (c *Client) SubscribeQueue(subject, queue string, handler MessageHandler) (*Subscription, error) {
+    return c.SubscribeWithOptions(subject, SubscribeOptions{Queue: queue}, handler)
+}
+
+// SubscribeWithOptions subscribes with a queue group, pending limits and a
+// worker pool as set in opts
+func (c *Client) SubscribeWithOptions(subject string, opts SubscribeOptions, handler MessageHandler) (*Subscription, error) {
+    return c.SubscribeMsg(subject, opts, func(msg *Message) error {
+        handler(msg.Data)
+        return nil
+    })
+}
+
+// SubscribeMsg subscribes with a handler receiving the message envelope:
+// subject, headers, raw and decoded payload and reply subject
+func (c *Client) SubscribeMsg(subject string, opts SubscribeOptions, handler MsgHandler) (*Subscription, error) {
+    // Dead letters would be received again and republished forever
+    if opts.DeadLetterSubject != "" && types.MatchSubject(subject, opts.DeadLetterSubject) {
+        return nil, fmt.Errorf("dead-letter subject %s matches the subscribed subject %s", opts.DeadLetterSubject, subject)
+    }
+    onError := opts.OnError
+    if onError == nil {
+        onError = func(msg *Message, err error) {
+            logger.Warnf("Subscription %s: %v", subject, err)
+        }
+    }
+    return c.subscribe(subject, opts, func(natsMsg *nats.Msg) {
+        msg, err := c.newMessage(natsMsg)
+        if err != nil {
+            if opts.DeadLetterSubject != "" {
+                if dlErr := c.deadLetter(opts.DeadLetterSubject, natsMsg, err); dlErr != nil {
+                    logger.Errorf("Dead-letter message on %s failed: %v", natsMsg.Subject, dlErr)
+                }
+            }
+            onError(msg, err)
             return
         }
-        handler(data)
+        if err := handler(msg); err != nil {
+            onError(msg, err)
+        }
     })
+}
+
+// subscribe subscribes handle to subject, running it on a worker pool when
+// opts.Concurrency is above one
+func (c *Client) subscribe(subject string, opts SubscribeOptions, handle nats.MsgHandler) (*Subscription, error) {
+    if opts.Concurrency < 0 {
+        return nil, fmt.Errorf("concurrency must not be negative, got %d", opts.Concurrency)
+    }
+
+    s := &Subscription{}
+    callback := handle
+    if opts.Concurrency > 1 {
+        // The NATS callback blocks while all workers are busy, so the
+        // backlog stays in the pending buffer bounded by the pending limits
+        s.stop = make(chan struct{})
+        work := make(chan *nats.Msg)
+        for i := 0; i < opts.Concurrency; i++ {
+            go func() {
+                for {
+                    select {
+                    case msg := <-work:
+                        handle(msg)
+                    case <-s.stop:
+                        return
+                    }
+                }
+            }()
+        }
+        callback = func(msg *nats.Msg) {
+            select {
+            case work <- msg:
+            case <-s.stop:
+            }
+        }
+    }
 
-    return &Subscription{sub: sub}, err
+    var sub transport.Subscription
+    var err error
+    if opts.Queue != "" {
+        sub, err = c.conn.QueueSubscribe(subject, opts.Queue, callback)
+    } else {
+        sub, err = c.conn.Subscribe(subject, callback)
+    }
+    if err != nil {
+        s.Unsubscribe()
+        return nil, err
+    }
+    s.sub = sub
+
+    if opts.PendingMsgs != 0 || opts.PendingBytes != 0 {
+        msgLimit, bytesLimit := opts.PendingMsgs, opts.PendingBytes
+        if msgLimit == 0 {
+            msgLimit = nats.DefaultSubPendingMsgsLimit
+        }
+        if bytesLimit == 0 {
+            bytesLimit = nats.DefaultSubPendingBytesLimit
+        }
+        if err := sub.SetPendingLimits(msgLimit, bytesLimit); err != nil {
+            s.Unsubscribe()
+            return nil, fmt.Errorf("set pending limits: %w", err)
+        }
+    }
+    return s, nil
 }
@@ -1,8 +1,17 @@
 package client
 
 import (
+    "context"
+    "errors"
+    "strings"
+    "sync"
     "testing"
     "time"
+
+    "github.com/LiteHomeLab/light_link/sdk/go/codec"
+    "github.com/LiteHomeLab/light_link/sdk/go/transport"
+    "github.com/LiteHomeLab/light_link/sdk/go/types"
+    "github.com/nats-io/nats.go"
 )
 
 func TestPublishSubscribe(t *testing.T) {
@@ -54,3 +63,268 @@ func TestPublishSubscribe(t *testing.T) {
         t.Error("Timeout waiting for message")
     }
 }
+
+func TestPublishCompression(t *testing.T) {
+    pubClient, err := NewClient(nats.DefaultURL, WithCompression(codec.EncodingZstd, 1024), WithPublishCompression())
+    if err != nil {
+        t.Skip("Need running NATS server:", err)
+    }
+    defer pubClient.Close()
+
+    subClient, err := NewClient(nats.DefaultURL)
+    if err != nil {
+        t.Skip("Need running NATS server:", err)
+    }
+    defer subClient.Close()
+
+    received := make(chan map[string]interface{}, 1)
+    sub, err := subClient.Subscribe("test.compressed.subject", func(data map[string]interface{}) {
+        received <- data
+    })
+    if err != nil {
+        t.Fatalf("Subscribe failed: %v", err)
+    }
+    defer sub.Unsubscribe()
+
+    // Raw subscriber to check the payload on the wire
+    raw, err := subClient.GetNATSConn().SubscribeSync("test.compressed.subject")
+    if err != nil {
+        t.Fatalf("SubscribeSync failed: %v", err)
+    }
+    defer raw.Unsubscribe()
+    time.Sleep(100 * time.Millisecond)
+
+    log := strings.Repeat("line of log output\n", 1000)
+    if err := pubClient.Publish("test.compressed.subject", map[string]interface{}{"log": log}); err != nil {
+        t.Fatalf("Publish failed: %v", err)
+    }
+
+    msg, err := raw.NextMsg(2 * time.Second)
+    if err != nil {
+        t.Fatalf("Raw receive failed: %v", err)
+    }
+    if msg.Header.Get(types.HeaderContentEncoding) != codec.EncodingZstd || len(msg.Data) >= len(log) {
+        t.Errorf("Expected zstd compressed payload, got %q with %d bytes", msg.Header.Get(types.HeaderContentEncoding), len(msg.Data))
+    }
+
+    select {
+    case data := <-received:
+        if data["log"] != log {
+            t.Error("Unexpected decompressed message")
+        }
+    case <-time.After(2 * time.Second):
+        t.Error("Timeout waiting for message")
+    }
+}
+
+func TestSubscribeQueue(t *testing.T) {
+    bus := transport.NewMemoryBus()
+    var workers []*Client
+    for i := 0; i < 2; i++ {
+        c, _ := NewClient("", WithTransport(bus.Connect()))
+        defer c.Close()
+        workers = append(workers, c)
+    }
+
+    received := make(chan int, 10)
+    for i, c := range workers {
+        i := i
+        if _, err := c.SubscribeQueue("jobs.resize", "resizers", func(data map[string]interface{}) {
+            received <- i
+        }); err != nil {
+            t.Fatalf("SubscribeQueue failed: %v", err)
+        }
+    }
+
+    for n := 0; n < 10; n++ {
+        workers[0].Publish("jobs.resize", map[string]interface{}{"n": n})
+    }
+    counts := make([]int, len(workers))
+    for n := 0; n < 10; n++ {
+        select {
+        case i := <-received:
+            counts[i]++
+        case <-time.After(time.Second):
+            t.Fatalf("Expected 10 jobs, got %v", counts)
+        }
+    }
+    if counts[0] == 0 || counts[1] == 0 {
+        t.Errorf("Expected both workers to get jobs, got %v", counts)
+    }
+    select {
+    case <-received:
+        t.Error("Job delivered twice")
+    case <-time.After(20 * time.Millisecond):
+    }
+}
+
+func TestSubscribeWorkerPool(t *testing.T) {
+    c, _ := NewClient("", WithTransport(transport.NewMemoryBus().Connect()))
+    defer c.Close()
+
+    const workers = 3
+    var mu sync.Mutex
+    active, peak := 0, 0
+    release := make(chan struct{})
+    done := make(chan struct{}, 6)
+    sub, err := c.SubscribeWithOptions("tasks", SubscribeOptions{Concurrency: workers}, func(data map[string]interface{}) {
+        mu.Lock()
+        active++
+        if active > peak {
+            peak = active
+        }
+        mu.Unlock()
+        <-release
+        mu.Lock()
+        active--
+        mu.Unlock()
+        done <- struct{}{}
+    })
+    if err != nil {
+        t.Fatalf("SubscribeWithOptions failed: %v", err)
+    }
+    defer sub.Unsubscribe()
+
+    for n := 0; n < 6; n++ {
+        c.Publish("tasks", map[string]interface{}{"n": n})
+    }
+    deadline := time.Now().Add(time.Second)
+    for {
+        mu.Lock()
+        running := active
+        mu.Unlock()
+        if running == workers || time.Now().After(deadline) {
+            break
+        }
+        time.Sleep(time.Millisecond)
+    }
+    close(release)
+    for n := 0; n < 6; n++ {
+        select {
+        case <-done:
+        case <-time.After(time.Second):
+            t.Fatalf("Only %d of 6 tasks handled", n)
+        }
+    }
+    if peak != workers {
+        t.Errorf("Expected %d handlers in parallel, got %d", workers, peak)
+    }
+
+    if _, err := c.SubscribeWithOptions("tasks", SubscribeOptions{Concurrency: -1}, func(map[string]interface{}) {}); err == nil {
+        t.Error("Expected negative concurrency to fail")
+    }
+}
+
+func TestSubscribeMsg(t *testing.T) {
+    c, _ := NewClient("", WithTransport(transport.NewMemoryBus().Connect()))
+    defer c.Close()
+
+    received := make(chan *Message, 2)
+    sub, err := c.SubscribeMsg("sensors.*", SubscribeOptions{}, func(msg *Message) error {
+        received <- msg
+        if msg.Reply != "" {
+            return msg.Respond(map[string]interface{}{"seen": msg.Subject})
+        }
+        return nil
+    })
+    if err != nil {
+        t.Fatalf("SubscribeMsg failed: %v", err)
+    }
+    defer sub.Unsubscribe()
+
+    before := time.Now()
+    c.Publish("sensors.temp", map[string]interface{}{"value": 21.5})
+    select {
+    case msg := <-received:
+        if msg.Subject != "sensors.temp" || msg.Data["value"] != 21.5 || string(msg.Raw) != `{"value":21.5}` {
+            t.Errorf("Unexpected message %+v", msg)
+        }
+        if msg.Timestamp.Before(before) {
+            t.Errorf("Timestamp %v before publish", msg.Timestamp)
+        }
+        if err := msg.Respond(nil); !errors.Is(err, ErrNoReply) {
+            t.Errorf("Expected ErrNoReply, got %v", err)
+        }
+    case <-time.After(time.Second):
+        t.Fatal("Message not delivered")
+    }
+
+    request := nats.NewMsg("sensors.humidity")
+    request.Header.Set("X-Unit", "percent")
+    request.Data = []byte(`{"value":40}`)
+    ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+    defer cancel()
+    reply, err := c.Transport().RequestMsgWithContext(ctx, request)
+    if err != nil {
+        t.Fatalf("Request failed: %v", err)
+    }
+    if string(reply.Data) != `{"seen":"sensors.humidity"}` {
+        t.Errorf("Unexpected reply %s", reply.Data)
+    }
+    if msg := <-received; msg.Header.Get("X-Unit") != "percent" {
+        t.Errorf("Expected the request headers, got %v", msg.Header)
+    }
+}
+
+func TestSubscribeDecodeError(t *testing.T) {
+    c, _ := NewClient("", WithTransport(transport.NewMemoryBus().Connect()))
+    defer c.Close()
+
+    dead, _ := c.Transport().SubscribeSync("events.dead")
+    errs := make(chan error, 2)
+    handled := make(chan map[string]interface{}, 1)
+    sub, err := c.SubscribeWithOptions("events", SubscribeOptions{
+        DeadLetterSubject: "events.dead",
+        OnError: func(msg *Message, err error) {
+            errs <- err
+        },
+    }, func(data map[string]interface{}) {
+        handled <- data
+    })
+    if err != nil {
+        t.Fatalf("SubscribeWithOptions failed: %v", err)
+    }
+    defer sub.Unsubscribe()
+
+    c.Transport().Publish("events", []byte("not json"))
+    select {
+    case err := <-errs:
+        var decodeErr *DecodeError
+        if !errors.As(err, &decodeErr) || decodeErr.Subject != "events" {
+            t.Errorf("Expected a DecodeError, got %v", err)
+        }
+    case <-time.After(time.Second):
+        t.Fatal("OnError not called")
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+    defer cancel()
+    msg, err := dead.NextMsgWithContext(ctx)
+    if err != nil {
+        t.Fatalf("Dead letter not published: %v", err)
+    }
+    if string(msg.Data) != "not json" || msg.Header.Get(types.HeaderDeadLetterSubject) != "events" || msg.Header.Get(types.HeaderDeadLetterReason) == "" {
+        t.Errorf("Unexpected dead letter %q, %v", msg.Data, msg.Header)
+    }
+
+    c.Publish("events", map[string]interface{}{"ok": true})
+    select {
+    case data := <-handled:
+        if data["ok"] != true {
+            t.Errorf("Unexpected data %v", data)
+        }
+    case <-time.After(time.Second):
+        t.Fatal("Valid message not handled")
+    }
+}
+
+func TestSubscribeDeadLetterLoop(t *testing.T) {
+    c, _ := NewClient("", WithTransport(transport.NewMemoryBus().Connect()))
+    defer c.Close()
+
+    _, err := c.SubscribeMsg("events.>", SubscribeOptions{DeadLetterSubject: "events.dead"}, func(msg *Message) error {
+        return nil
+    })
+    if err == nil {
+        t.Error("Expected an error for a dead-letter subject matching the subscription")
+    }
+}
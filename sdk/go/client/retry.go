@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/WQGroup/logger"
+	"github.com/nats-io/nats.go"
+)
+
+// RetryPolicy configures how failed RPC calls are retried
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first one
+	MaxAttempts int
+	// InitialBackoff is the wait time before the first retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait time between retries
+	MaxBackoff time.Duration
+	// Multiplier grows the backoff after every retry
+	Multiplier float64
+	// Jitter randomizes each backoff by +/- this fraction (0 to 1)
+	Jitter float64
+	// PerAttemptTimeout bounds a single attempt; zero lets one attempt use the whole deadline
+	PerAttemptTimeout time.Duration
+	// Retryable decides whether an error is retried; nil uses IsRetryable
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy returns a policy suited to riding out rolling restarts
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// WithRetryPolicy enables retries of failed RPC calls.
+// All attempts of a call share the same request ID and idempotency key, so a
+// service using WithDedupeCache runs the handler at most once.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) error {
+		c.retryPolicy = &policy
+		return nil
+	}
+}
+
+// IsRetryable reports whether err is transient: a transport error such as no
+// responders (e.g. all instances restarting) or a timed out attempt, or an
+// RPC error the service marked as retryable
+func IsRetryable(err error) bool {
+	var rpcErr *types.RPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Retryable
+	}
+	return errors.Is(err, nats.ErrNoResponders) ||
+		errors.Is(err, nats.ErrTimeout) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+// idempotencyKeyCtxKey is the context key of a caller-supplied idempotency key
+type idempotencyKeyCtxKey struct{}
+
+// ContextWithIdempotencyKey returns a context whose RPC calls carry key as idempotency key
+// instead of the generated request ID
+func ContextWithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtxKey{}, key)
+}
+
+// idempotencyKeyFromContext returns the caller-supplied idempotency key, if any
+func idempotencyKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyCtxKey{}).(string)
+	return key
+}
+
+// backoff returns the wait time before retry number n (starting at 1)
+func (p *RetryPolicy) backoff(n int) time.Duration {
+	d := float64(p.InitialBackoff)
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	for i := 1; i < n; i++ {
+		d *= multiplier
+	}
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(d)
+}
+
+// retryable reports whether err should be retried under this policy
+func (p *RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+// requestWithRetry performs attempt, retrying transient failures according
+// to the client's retry policy until ctx is done
+func (c *Client) requestWithRetry(ctx context.Context, attempt func(ctx context.Context) (map[string]interface{}, error)) (map[string]interface{}, error) {
+	policy := c.retryPolicy
+	if policy == nil || policy.MaxAttempts <= 1 {
+		return attempt(ctx)
+	}
+
+	for n := 1; ; n++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if policy.PerAttemptTimeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, policy.PerAttemptTimeout)
+		}
+		result, err := attempt(attemptCtx)
+		cancel()
+
+		if err == nil || n >= policy.MaxAttempts || ctx.Err() != nil || !policy.retryable(err) {
+			return result, err
+		}
+
+		wait := policy.backoff(n)
+		logger.Debugf("RPC attempt %d failed: %v, retrying in %v", n, err, wait)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		}
+	}
+}
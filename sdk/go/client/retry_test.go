@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     300 * time.Millisecond,
+		Multiplier:     2,
+	}
+
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+	for i, w := range want {
+		if got := policy.backoff(i + 1); got != w {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		got := policy.backoff(1)
+		if got < 50*time.Millisecond || got > 150*time.Millisecond {
+			t.Fatalf("backoff with jitter out of range: %v", got)
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nats.ErrNoResponders, true},
+		{nats.ErrTimeout, true},
+		{fmt.Errorf("RPC request failed: %w", context.DeadlineExceeded), true},
+		{errors.New("RPC error: division by zero"), false},
+		{context.Canceled, false},
+		{fmt.Errorf("RPC error: %w", types.NewRPCError(types.CodeUnavailable, "draining")), true},
+		{fmt.Errorf("RPC error: %w", types.NewRPCError(types.CodeNotFound, "no such user")), false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryable(tt.err); got != tt.want {
+			t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestCallRetriesUntilResponder(t *testing.T) {
+	nc, err := nats.Connect(nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer nc.Close()
+
+	c, err := NewClient(nats.DefaultURL, WithRetryPolicy(RetryPolicy{
+		MaxAttempts:    20,
+		InitialBackoff: 50 * time.Millisecond,
+		Multiplier:     1,
+	}))
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer c.Close()
+
+	keys := make(chan string, 1)
+	go func() {
+		// Simulate an instance coming back after a restart
+		time.Sleep(200 * time.Millisecond)
+		nc.Subscribe("$SRV.retry-service.echo", func(msg *nats.Msg) {
+			var req types.RPCRequest
+			json.Unmarshal(msg.Data, &req)
+			keys <- msg.Header.Get(types.HeaderIdempotencyKey)
+			data, _ := json.Marshal(types.RPCResponse{ID: req.ID, Success: true, Result: map[string]interface{}{"ok": true}})
+			msg.Respond(data)
+		})
+	}()
+
+	ctx := ContextWithIdempotencyKey(context.Background(), "order-42")
+	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	result, err := c.CallContext(ctx, "retry-service", "echo", nil)
+	if err != nil {
+		t.Fatalf("CallContext failed: %v", err)
+	}
+	if result["ok"] != true {
+		t.Errorf("Unexpected result: %v", result)
+	}
+	if key := <-keys; key != "order-42" {
+		t.Errorf("Expected idempotency key 'order-42', got '%s'", key)
+	}
+}
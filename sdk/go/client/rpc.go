@@ -1,27 +1,106 @@
 package client
 
 import (
-    "encoding/json"
+    "context"
+    "errors"
     "fmt"
+    "strconv"
     "time"
 
     "github.com/WQGroup/logger"
     "github.com/google/uuid"
+    "github.com/nats-io/nats.go"
+    "github.com/LiteHomeLab/light_link/sdk/go/tracing"
     "github.com/LiteHomeLab/light_link/sdk/go/types"
 )
 
+// DefaultCallTimeout is the timeout used by Call and by CallContext when ctx has no deadline
+const DefaultCallTimeout = 5 * time.Second
+
 // Call makes a synchronous RPC call
 func (c *Client) Call(service, method string, args map[string]interface{}) (map[string]interface{}, error) {
-    return c.CallWithTimeout(service, method, args, 5*time.Second)
+    return c.CallWithTimeout(service, method, args, DefaultCallTimeout)
 }
 
 // CallWithTimeout makes an RPC call with timeout
 func (c *Client) CallWithTimeout(service, method string, args map[string]interface{}, timeout time.Duration) (map[string]interface{}, error) {
+    ctx, cancel := context.WithTimeout(context.Background(), timeout)
+    defer cancel()
+
+    return c.CallContext(ctx, service, method, args)
+}
+
+// CallContext makes an RPC call bound to ctx.
+// The remaining deadline and the request ID are sent as NATS headers, and a
+// cancel notice follows when ctx is cancelled before the response, so the
+// service handler can stop working once the caller has given up.
+func (c *Client) CallContext(ctx context.Context, service, method string, args map[string]interface{}) (map[string]interface{}, error) {
+    info := &CallInfo{
+        Kind:    CallKindUnary,
+        Service: service,
+        Method:  method,
+        Subject: types.ServiceRPCSubject(service, method),
+        Header:  nats.Header{},
+    }
+    return c.request(ctx, info, args, c.breaker(service, method))
+}
+
+// CallInstance makes an RPC call to one specific service instance
+func (c *Client) CallInstance(instanceKey, method string, args map[string]interface{}) (map[string]interface{}, error) {
+    ctx, cancel := context.WithTimeout(context.Background(), DefaultCallTimeout)
+    defer cancel()
+
+    return c.CallInstanceContext(ctx, instanceKey, method, args)
+}
+
+// CallInstanceContext makes an RPC call bound to ctx to one specific service instance
+func (c *Client) CallInstanceContext(ctx context.Context, instanceKey, method string, args map[string]interface{}) (map[string]interface{}, error) {
+    info := &CallInfo{
+        Kind:        CallKindUnary,
+        InstanceKey: instanceKey,
+        Method:      method,
+        Subject:     types.InstanceRPCSubject(instanceKey, method),
+        Header:      nats.Header{},
+    }
+    return c.request(ctx, info, args, nil)
+}
+
+// request sends an RPC request described by info through the interceptor chain
+// and waits for the response. A non-nil breaker rejects the call while open
+// and records its outcome.
+func (c *Client) request(ctx context.Context, info *CallInfo, args map[string]interface{}, breaker *circuitBreaker) (map[string]interface{}, error) {
+    ctx, span := c.tracer.Start(ctx, info.Subject, tracing.SpanKindClient)
+    span.SetAttribute("rpc.method", info.Method)
+    result, err := c.intercept(ctx, info, args, func(ctx context.Context, info *CallInfo, args map[string]interface{}) (map[string]interface{}, error) {
+        return c.doRequest(ctx, info, args, breaker)
+    })
+    span.SetError(err)
+    span.End()
+    return result, err
+}
+
+// doRequest performs the RPC request at the end of the interceptor chain
+func (c *Client) doRequest(ctx context.Context, info *CallInfo, args map[string]interface{}, breaker *circuitBreaker) (map[string]interface{}, error) {
+    subject, method := info.Subject, info.Method
+    var ticket breakerTicket
+    if breaker != nil {
+        var err error
+        if ticket, err = breaker.allow(); err != nil {
+            return nil, err
+        }
+    }
+
+    if _, ok := ctx.Deadline(); !ok {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, DefaultCallTimeout)
+        defer cancel()
+    }
+
     // Generate request ID
     requestID := uuid.New().String()
 
     // Debug logging
-    logger.Debugf("Calling %s.%s with args: %+v", service, method, args)
+    logger.Debugf("Calling %s with args: %+v", subject, args)
 
     // Build request
     request := types.RPCRequest{
@@ -30,31 +109,90 @@ func (c *Client) CallWithTimeout(service, method string, args map[string]interfa
         Args:   args,
     }
 
-    reqData, err := json.Marshal(request)
+    cd := c.payloadCodec()
+    reqData, err := cd.Marshal(request)
     if err != nil {
         return nil, fmt.Errorf("marshal request: %w", err)
     }
 
-    // Build subject
-    subject := fmt.Sprintf("$SRV.%s.%s", service, method)
+    reqData, encoding := c.compressRequest(info, reqData)
+
+    idempotencyKey := idempotencyKeyFromContext(ctx)
+    if idempotencyKey == "" {
+        idempotencyKey = requestID
+    }
+
+    // Send request and wait for response, every attempt gets a fresh deadline header
+    result, err := c.requestWithRetry(ctx, func(attemptCtx context.Context) (map[string]interface{}, error) {
+        msg := nats.NewMsg(subject)
+        msg.Data = reqData
+        for key, values := range info.Header {
+            msg.Header[key] = values
+        }
+        msg.Header.Set(types.HeaderContentType, cd.ContentType())
+        setEncodingHeaders(msg, encoding)
+        setRequestHeaders(attemptCtx, msg, requestID)
+        msg.Header.Set(types.HeaderIdempotencyKey, idempotencyKey)
 
-    // Send request and wait for response
-    msg, err := c.nc.Request(subject, reqData, timeout)
+        respMsg, err := c.conn.RequestMsgWithContext(attemptCtx, msg)
+        if err != nil {
+            if errors.Is(attemptCtx.Err(), context.Canceled) {
+                c.sendCancel(requestID)
+            }
+            return nil, fmt.Errorf("RPC request failed: %w", err)
+        }
+        c.learnAcceptEncoding(info, respMsg)
+        return parseResponse(respMsg)
+    })
+    if breaker != nil {
+        breaker.record(ticket, err)
+    }
     if err != nil {
-        return nil, fmt.Errorf("RPC request failed: %w", err)
+        var rpcErr *types.RPCError
+        if errors.As(err, &rpcErr) {
+            logger.Errorf("RPC error: %v", rpcErr)
+        }
+        return nil, err
     }
 
-    // Parse response
+    logger.Debugf("RPC response: %+v", result)
+    return result, nil
+}
+
+// parseResponse decodes an RPC response. A failed call returns its
+// *types.RPCError, which matches the types.Err* sentinels with errors.Is and
+// unwraps to a *types.ValidationError for validation failures.
+func parseResponse(msg *nats.Msg) (map[string]interface{}, error) {
     var response types.RPCResponse
-    if err := json.Unmarshal(msg.Data, &response); err != nil {
+    if err := decodeResponse(msg, &response); err != nil {
         return nil, fmt.Errorf("unmarshal response: %w", err)
     }
+    if err := response.Err(); err != nil {
+        return nil, fmt.Errorf("RPC error: %w", err)
+    }
+    return response.Result, nil
+}
 
-    if !response.Success {
-        logger.Errorf("RPC error: %s", response.Error)
-        return nil, fmt.Errorf("RPC error: %s", response.Error)
+// sendCancel tells the services handling requestID that the caller gave up,
+// so their handler contexts are cancelled. Deadlines need no notice, they
+// travel with the request.
+func (c *Client) sendCancel(requestID string) {
+    msg := nats.NewMsg(types.CancelSubject(requestID))
+    msg.Header.Set(types.HeaderRequestID, requestID)
+    if err := c.conn.PublishMsg(msg); err != nil {
+        logger.Errorf("Send cancel notice failed: %v", err)
     }
+}
 
-    logger.Debugf("RPC response: %+v", response.Result)
-    return response.Result, nil
+// setRequestHeaders sets the request ID, trace context and remaining deadline headers on msg
+func setRequestHeaders(ctx context.Context, msg *nats.Msg, requestID string) {
+    msg.Header.Set(types.HeaderRequestID, requestID)
+    setTraceHeader(ctx, msg)
+    if deadline, ok := ctx.Deadline(); ok {
+        remaining := time.Until(deadline).Milliseconds()
+        if remaining < 0 {
+            remaining = 0
+        }
+        msg.Header.Set(types.HeaderTimeout, strconv.FormatInt(remaining, 10))
+    }
 }
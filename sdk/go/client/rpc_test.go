@@ -3,19 +3,15 @@ package client
 import (
     "testing"
     "time"
+
+    "github.com/LiteHomeLab/light_link/sdk/go/testutil"
 )
 
 func TestCall(t *testing.T) {
-    // This test requires a running NATS server and service
-    config := &TLSConfig{
-        CaFile:     "../../../deploy/nats/tls/ca.crt",
-        CertFile:   "../../../deploy/nats/tls/demo-service.crt",
-        KeyFile:    "../../../deploy/nats/tls/demo-service.key",
-        ServerName: "nats-server",
-    }
-    c, err := NewClient("nats://172.18.200.47:4222", WithTLS(config))
+    srv := testutil.StartNATS(t, testutil.Options{TLS: true})
+    c, err := NewClient(srv.URL, WithTLS(TLSConfigFrom(srv.ClientTLS)))
     if err != nil {
-        t.Skip("Need running NATS server:", err)
+        t.Fatalf("NewClient failed: %v", err)
     }
     defer c.Close()
 
@@ -29,15 +25,10 @@ func TestCall(t *testing.T) {
 }
 
 func TestCallWithTimeout(t *testing.T) {
-    config := &TLSConfig{
-        CaFile:     "../../../deploy/nats/tls/ca.crt",
-        CertFile:   "../../../deploy/nats/tls/demo-service.crt",
-        KeyFile:    "../../../deploy/nats/tls/demo-service.key",
-        ServerName: "nats-server",
-    }
-    c, err := NewClient("nats://172.18.200.47:4222", WithTLS(config))
+    srv := testutil.StartNATS(t, testutil.Options{TLS: true})
+    c, err := NewClient(srv.URL, WithTLS(TLSConfigFrom(srv.ClientTLS)))
     if err != nil {
-        t.Skip("Need running NATS server:", err)
+        t.Fatalf("NewClient failed: %v", err)
     }
     defer c.Close()
 
@@ -3,24 +3,15 @@ package client
 import (
     "context"
     "encoding/json"
-
-    "github.com/nats-io/nats.go/jetstream"
 )
 
 // SetState sets state
 func (c *Client) SetState(key string, value map[string]interface{}) error {
-    js, err := jetstream.New(c.nc)
-    if err != nil {
-        return err
-    }
-
     // Get or create KV bucket
-    kv, err := js.KeyValue(context.Background(), "light_link_state")
+    kv, err := c.conn.KeyValue(context.Background(), "light_link_state")
     if err != nil {
         // Create bucket
-        kv, err = js.CreateKeyValue(context.Background(), jetstream.KeyValueConfig{
-            Bucket: "light_link_state",
-        })
+        kv, err = c.conn.CreateKeyValue(context.Background(), "light_link_state")
         if err != nil {
             return err
         }
@@ -39,12 +30,7 @@ func (c *Client) SetState(key string, value map[string]interface{}) error {
 
 // GetState gets state
 func (c *Client) GetState(key string) (map[string]interface{}, error) {
-    js, err := jetstream.New(c.nc)
-    if err != nil {
-        return nil, err
-    }
-
-    kv, err := js.KeyValue(context.Background(), "light_link_state")
+    kv, err := c.conn.KeyValue(context.Background(), "light_link_state")
     if err != nil {
         return nil, err
     }
@@ -64,24 +50,17 @@ func (c *Client) GetState(key string) (map[string]interface{}, error) {
 
 // WatchState watches state changes
 func (c *Client) WatchState(key string, handler func(map[string]interface{})) (func(), error) {
-    js, err := jetstream.New(c.nc)
-    if err != nil {
-        return nil, err
-    }
-
     // Get or create KV bucket
-    kv, err := js.KeyValue(context.Background(), "light_link_state")
+    kv, err := c.conn.KeyValue(context.Background(), "light_link_state")
     if err != nil {
         // Create bucket
-        kv, err = js.CreateKeyValue(context.Background(), jetstream.KeyValueConfig{
-            Bucket: "light_link_state",
-        })
+        kv, err = c.conn.CreateKeyValue(context.Background(), "light_link_state")
         if err != nil {
             return nil, err
         }
     }
 
-    watcher, err := kv.Watch(context.Background(), key, jetstream.IgnoreDeletes())
+    watcher, err := kv.Watch(context.Background(), key)
     if err != nil {
         return nil, err
     }
@@ -0,0 +1,327 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/tracing"
+	"github.com/LiteHomeLab/light_link/sdk/go/transport"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/WQGroup/logger"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// DefaultStreamWindow is the number of stream results a caller buffers before
+	// the service has to wait for it to catch up
+	DefaultStreamWindow = 32
+	// DefaultStreamIdleTimeout is how long Recv waits for the next frame when
+	// the stream context has no deadline
+	DefaultStreamIdleTimeout = 30 * time.Second
+)
+
+var (
+	// ErrStreamClosed is returned by Recv after Close
+	ErrStreamClosed = errors.New("stream closed")
+	// ErrStreamStalled is returned by Recv when the service sent no frame
+	// within the idle timeout
+	ErrStreamStalled = errors.New("stream producer stalled")
+)
+
+// WithStreamWindow sets the credit window of streaming calls
+func WithStreamWindow(window int) Option {
+	return func(c *Client) error {
+		if window < 1 {
+			return fmt.Errorf("stream window must be at least 1, got %d", window)
+		}
+		c.streamWindow = window
+		return nil
+	}
+}
+
+// WithStreamIdleTimeout sets how long Recv waits for the next frame of a
+// streaming call whose context has no deadline
+func WithStreamIdleTimeout(timeout time.Duration) Option {
+	return func(c *Client) error {
+		if timeout <= 0 {
+			return fmt.Errorf("stream idle timeout must be positive, got %v", timeout)
+		}
+		c.streamIdleTimeout = timeout
+		return nil
+	}
+}
+
+// Stream receives the results of a server-streaming RPC call. Recv must be
+// called from one goroutine at a time; Close may be called from any goroutine,
+// also while Recv is waiting, which then returns ErrStreamClosed.
+type Stream struct {
+	conn       transport.Conn
+	sub        transport.SyncSubscription
+	ctx        context.Context
+	ackSubject string
+	window     int
+	consumed   int
+	seq        int
+
+	mu  sync.Mutex
+	err error // final error, set once
+
+	// idleTimeout bounds the wait for each frame when ctx has no deadline
+	idleTimeout time.Duration
+	// span is the client span of the call, ended with the stream
+	span *tracing.Span
+
+	closeOnce sync.Once
+}
+
+// CallStream starts a server-streaming RPC call. ctx bounds the whole stream;
+// without a deadline the stream opening is bounded by DefaultCallTimeout and
+// each following frame by the stream idle timeout. Read the results with Recv
+// and call Close when done. Interceptors wrap the opening of the stream.
+func (c *Client) CallStream(ctx context.Context, service, method string, args map[string]interface{}) (*Stream, error) {
+	info := &CallInfo{
+		Kind:    CallKindStream,
+		Service: service,
+		Method:  method,
+		Subject: types.ServiceRPCSubject(service, method),
+		Header:  nats.Header{},
+	}
+	ctx, span := c.tracer.Start(ctx, info.Subject, tracing.SpanKindClient)
+	span.SetAttribute("rpc.method", method)
+
+	var stream *Stream
+	_, err := c.intercept(ctx, info, args, func(ctx context.Context, info *CallInfo, args map[string]interface{}) (map[string]interface{}, error) {
+		var err error
+		stream, err = c.openStream(ctx, info, args)
+		return nil, err
+	})
+	if err != nil {
+		if stream != nil {
+			stream.Close()
+		}
+		span.SetError(err)
+		span.End()
+		return nil, err
+	}
+	stream.span = span
+	return stream, nil
+}
+
+// openStream sends the streaming request at the end of the interceptor chain
+// and waits for the open frame
+func (c *Client) openStream(ctx context.Context, info *CallInfo, args map[string]interface{}) (*Stream, error) {
+	requestID := uuid.New().String()
+	cd := c.payloadCodec()
+	reqData, err := cd.Marshal(types.RPCRequest{
+		ID:     requestID,
+		Method: info.Method,
+		Args:   args,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	window := c.streamWindow
+	if window <= 0 {
+		window = DefaultStreamWindow
+	}
+	idleTimeout := c.streamIdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultStreamIdleTimeout
+	}
+
+	inbox := c.conn.NewRespInbox()
+	sub, err := c.conn.SubscribeSync(inbox)
+	if err != nil {
+		return nil, fmt.Errorf("subscribe stream inbox: %w", err)
+	}
+
+	msg := nats.NewMsg(info.Subject)
+	msg.Reply = inbox
+	msg.Data = reqData
+	for key, values := range info.Header {
+		msg.Header[key] = values
+	}
+	msg.Header.Set(types.HeaderContentType, cd.ContentType())
+	setEncodingHeaders(msg, "")
+	setRequestHeaders(ctx, msg, requestID)
+	msg.Header.Set(types.HeaderStream, strconv.Itoa(window))
+
+	logger.Debugf("Calling stream %s with args: %+v", info.Subject, args)
+	if err := c.conn.PublishMsg(msg); err != nil {
+		sub.Unsubscribe()
+		return nil, fmt.Errorf("publish request: %w", err)
+	}
+
+	// Wait for the open frame, which carries the subject for credit
+	openCtx := ctx
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		openCtx, cancel = context.WithTimeout(ctx, DefaultCallTimeout)
+		defer cancel()
+	}
+	open, err := sub.NextMsgWithContext(openCtx)
+	if err != nil {
+		sub.Unsubscribe()
+		return nil, fmt.Errorf("RPC request failed: %w", err)
+	}
+	if open.Header.Get("Status") == "503" {
+		sub.Unsubscribe()
+		return nil, fmt.Errorf("RPC request failed: %w", nats.ErrNoResponders)
+	}
+	if open.Header.Get(types.HeaderStreamEnd) != "" {
+		sub.Unsubscribe()
+		if err := parseStreamEnd(open); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("stream ended before opening")
+	}
+
+	return &Stream{
+		conn:        c.conn,
+		sub:         sub,
+		ctx:         ctx,
+		ackSubject:  open.Header.Get(types.HeaderStreamAck),
+		window:      window,
+		idleTimeout: idleTimeout,
+	}, nil
+}
+
+// Recv returns the next result of the stream. It returns io.EOF after the
+// last result, or the error the handler ended the stream with. Without a
+// deadline on the stream context it fails with ErrStreamStalled when no frame
+// arrives within the idle timeout.
+func (s *Stream) Recv() (map[string]interface{}, error) {
+	if err := s.finalErr(); err != nil {
+		return nil, err
+	}
+
+	msg, err := s.next()
+	if err != nil {
+		s.fail(fmt.Errorf("receive stream frame: %w", err))
+		return nil, s.finalErr()
+	}
+
+	seq, _ := strconv.Atoi(msg.Header.Get(types.HeaderStreamSeq))
+	if seq != s.seq+1 {
+		s.fail(fmt.Errorf("stream frame out of order: expected %d, got %d", s.seq+1, seq))
+		return nil, s.finalErr()
+	}
+	s.seq = seq
+
+	if msg.Header.Get(types.HeaderStreamEnd) != "" {
+		err := parseStreamEnd(msg)
+		if err == nil {
+			err = io.EOF
+		}
+		s.end(err)
+		s.sub.Unsubscribe()
+		return nil, s.finalErr()
+	}
+
+	var response types.RPCResponse
+	if err := decodeResponse(msg, &response); err != nil {
+		s.fail(fmt.Errorf("unmarshal stream frame: %w", err))
+		return nil, s.finalErr()
+	}
+
+	// Grant more credit once half of the window has been consumed
+	s.consumed++
+	if s.consumed >= (s.window+1)/2 {
+		s.sendAck(types.HeaderStreamCredit, strconv.Itoa(s.consumed))
+		s.consumed = 0
+	}
+
+	return response.Result, nil
+}
+
+// next waits for the next frame, bounded by the idle timeout when the stream
+// context has no deadline
+func (s *Stream) next() (*nats.Msg, error) {
+	if _, ok := s.ctx.Deadline(); ok || s.idleTimeout <= 0 {
+		return s.sub.NextMsgWithContext(s.ctx)
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, s.idleTimeout)
+	defer cancel()
+	msg, err := s.sub.NextMsgWithContext(ctx)
+	if errors.Is(err, context.DeadlineExceeded) && s.ctx.Err() == nil {
+		return nil, ErrStreamStalled
+	}
+	return msg, err
+}
+
+// Close stops the stream. The service is told to stop sending if it has not finished.
+func (s *Stream) Close() error {
+	s.closeOnce.Do(func() {
+		if s.end(ErrStreamClosed) {
+			s.sendAck(types.HeaderStreamCancel, "1")
+		}
+		s.sub.Unsubscribe()
+	})
+	return nil
+}
+
+// fail ends the stream locally with err and tells the service to stop
+func (s *Stream) fail(err error) {
+	if s.end(err) {
+		s.sendAck(types.HeaderStreamCancel, "1")
+	}
+	s.sub.Unsubscribe()
+}
+
+// end records err as the final error unless the stream already ended, e.g.
+// by a concurrent Close, and ends its span. It reports whether it did.
+func (s *Stream) end(err error) bool {
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return false
+	}
+	s.err = err
+	s.mu.Unlock()
+
+	if err == io.EOF || err == ErrStreamClosed {
+		s.span.SetError(nil)
+	} else {
+		s.span.SetError(err)
+	}
+	s.span.End()
+	return true
+}
+
+// finalErr returns the error the stream ended with, nil while it is open
+func (s *Stream) finalErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// sendAck sends a credit or cancellation message to the service
+func (s *Stream) sendAck(header, value string) {
+	if s.ackSubject == "" {
+		return
+	}
+	msg := nats.NewMsg(s.ackSubject)
+	msg.Header.Set(header, value)
+	if err := s.conn.PublishMsg(msg); err != nil {
+		logger.Errorf("Send stream ack failed: %v", err)
+	}
+}
+
+// parseStreamEnd returns the error carried by an end-of-stream frame
+func parseStreamEnd(msg *nats.Msg) error {
+	var response types.RPCResponse
+	if err := decodeResponse(msg, &response); err != nil {
+		return fmt.Errorf("unmarshal stream end: %w", err)
+	}
+	if err := response.Err(); err != nil {
+		return fmt.Errorf("RPC error: %w", err)
+	}
+	return nil
+}
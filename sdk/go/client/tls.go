@@ -0,0 +1,87 @@
+package client
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// ErrCertificateNotPinned is returned by the PinCertificates hook when the
+// server certificate matches none of the pinned fingerprints
+var ErrCertificateNotPinned = errors.New("server certificate not pinned")
+
+// BuildTLSConfig creates the tls.Config used to connect to NATS.
+// The server chain is verified against CaFile (system roots when empty) and
+// the server name against ServerName, falling back to the host of the
+// connected URL. Certificates must carry the name or IP in their SANs.
+func BuildTLSConfig(config *TLSConfig) (*tls.Config, error) {
+	cert, roots, err := loadTLSFiles(config)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := newTLSConfig(config, func() *x509.CertPool { return roots })
+	tlsConfig.RootCAs = roots
+	if cert != nil {
+		tlsConfig.Certificates = []tls.Certificate{*cert}
+	}
+	return tlsConfig, nil
+}
+
+// newTLSConfig creates the verification settings of config without the
+// certificates; roots returns the CA pool in use at handshake time
+func newTLSConfig(config *TLSConfig, roots func() *x509.CertPool) *tls.Config {
+	tlsConfig := &tls.Config{
+		ServerName:            config.ServerName,
+		MinVersion:            tls.VersionTLS12,
+		VerifyPeerCertificate: config.VerifyPeerCertificate,
+	}
+
+	// Development opt-out: the chain is still verified, only the name is not
+	if config.InsecureSkipVerify {
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = verifyChain(roots)
+	}
+	return tlsConfig
+}
+
+// verifyChain verifies the peer chain against roots without checking the server name
+func verifyChain(roots func() *x509.CertPool) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("server sent no certificate")
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range cs.PeerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+			Roots:         roots(),
+			Intermediates: intermediates,
+		})
+		return err
+	}
+}
+
+// PinCertificates returns a VerifyPeerCertificate hook accepting only servers
+// whose leaf certificate has one of the given SHA-256 fingerprints (hex, colons allowed)
+func PinCertificates(fingerprints ...string) func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	pinned := make(map[string]bool, len(fingerprints))
+	for _, fp := range fingerprints {
+		pinned[strings.ToLower(strings.ReplaceAll(fp, ":", ""))] = true
+	}
+
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return ErrCertificateNotPinned
+		}
+		sum := sha256.Sum256(rawCerts[0])
+		if !pinned[hex.EncodeToString(sum[:])] {
+			return ErrCertificateNotPinned
+		}
+		return nil
+	}
+}
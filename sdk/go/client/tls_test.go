@@ -0,0 +1,157 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA creates a CA and a server certificate for localhost and 127.0.0.1
+func testCA(t *testing.T, dir string) (caFile string, server tls.Certificate) {
+	t.Helper()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	serverKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	serverDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "nats-server"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, caCert, &serverKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("create server certificate: %v", err)
+	}
+
+	caFile = filepath.Join(dir, "ca.crt")
+	os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0644)
+	return caFile, tls.Certificate{Certificate: [][]byte{serverDER}, PrivateKey: serverKey}
+}
+
+// handshake connects to a TLS server presenting cert with the client config built from config
+func handshake(t *testing.T, cert tls.Certificate, config *TLSConfig) error {
+	t.Helper()
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	tlsConfig, err := BuildTLSConfig(config)
+	if err != nil {
+		t.Fatalf("BuildTLSConfig failed: %v", err)
+	}
+	conn, err := tls.Dial("tcp", ln.Addr().String(), tlsConfig)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func TestBuildTLSConfigVerification(t *testing.T) {
+	dir := t.TempDir()
+	caFile, serverCert := testCA(t, dir)
+	otherCA, _ := testCA(t, t.TempDir())
+	sum := sha256.Sum256(serverCert.Certificate[0])
+	fingerprint := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name    string
+		config  TLSConfig
+		wantErr bool
+	}{
+		{"matching DNS SAN", TLSConfig{CaFile: caFile, ServerName: "localhost"}, false},
+		{"IP SAN from address", TLSConfig{CaFile: caFile}, false},
+		{"wrong server name", TLSConfig{CaFile: caFile, ServerName: "nats-server"}, true},
+		{"unknown CA", TLSConfig{CaFile: otherCA, ServerName: "localhost"}, true},
+		{"skip name check", TLSConfig{CaFile: caFile, ServerName: "nats-server", InsecureSkipVerify: true}, false},
+		{"skip name check keeps chain check", TLSConfig{CaFile: otherCA, ServerName: "nats-server", InsecureSkipVerify: true}, true},
+		{"pinned", TLSConfig{CaFile: caFile, ServerName: "localhost", VerifyPeerCertificate: PinCertificates(fingerprint)}, false},
+		{"not pinned", TLSConfig{CaFile: caFile, ServerName: "localhost", VerifyPeerCertificate: PinCertificates("00:11")}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := handshake(t, serverCert, &tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("handshake error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	err := handshake(t, serverCert, &TLSConfig{CaFile: caFile, ServerName: "localhost", VerifyPeerCertificate: PinCertificates("00")})
+	if !errors.Is(err, ErrCertificateNotPinned) {
+		t.Errorf("Expected ErrCertificateNotPinned, got %v", err)
+	}
+}
+
+func TestBuildTLSConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := BuildTLSConfig(&TLSConfig{CaFile: filepath.Join(dir, "missing.crt")}); err == nil {
+		t.Error("Expected error for missing CA file")
+	}
+	empty := filepath.Join(dir, "empty.crt")
+	os.WriteFile(empty, []byte("not a certificate"), 0644)
+	if _, err := BuildTLSConfig(&TLSConfig{CaFile: empty}); err == nil {
+		t.Error("Expected error for CA file without certificates")
+	}
+	if _, err := BuildTLSConfig(&TLSConfig{CertFile: filepath.Join(dir, "client.crt")}); err == nil {
+		t.Error("Expected error for missing client key pair")
+	}
+}
+
+func TestBuildTLSConfigPKCS12(t *testing.T) {
+	bundle := filepath.Join("..", "types", "testdata", "client.p12")
+	tlsConfig, err := BuildTLSConfig(&TLSConfig{PKCS12File: bundle, PKCS12Password: "secret"})
+	if err != nil {
+		t.Fatalf("BuildTLSConfig failed: %v", err)
+	}
+	if len(tlsConfig.Certificates) != 1 || tlsConfig.Certificates[0].Leaf.Subject.CommonName != "test-client" {
+		t.Errorf("Expected the bundled client certificate")
+	}
+	// Without CaFile the CA in the bundle is trusted
+	if tlsConfig.RootCAs == nil {
+		t.Error("Expected the bundled CA as root")
+	}
+
+	if _, err := BuildTLSConfig(&TLSConfig{PKCS12File: bundle, PKCS12Password: "wrong"}); err == nil {
+		t.Error("Expected error for a wrong bundle password")
+	}
+}
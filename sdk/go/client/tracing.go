@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/tracing"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+// WithTracer records a client span for every RPC call with tracer.
+// The trace context is propagated in the traceparent header either way, so a
+// call made from a traced service handler continues the handler's trace.
+func WithTracer(tracer *tracing.Tracer) Option {
+	return func(c *Client) error {
+		c.tracer = tracer
+		return nil
+	}
+}
+
+// WithTraceReporting reports spans to the manager over NATS, creating a tracer
+// named after the client if WithTracer was not used
+func WithTraceReporting() Option {
+	return func(c *Client) error {
+		c.traceReporting = true
+		return nil
+	}
+}
+
+// Tracer returns the tracer of the client, or nil if tracing is disabled
+func (c *Client) Tracer() *tracing.Tracer {
+	return c.tracer
+}
+
+// setupTracing attaches the NATS span exporter once connected
+func (c *Client) setupTracing() {
+	if !c.traceReporting {
+		return
+	}
+	if c.tracer == nil {
+		c.tracer = tracing.NewTracer(c.name)
+		c.ownsTracer = true
+	}
+	c.tracer.AddExporter(tracing.NewNATSExporter(c.conn))
+}
+
+// shutdownTracing exports the remaining spans before the connection closes.
+// A tracer created by the client is shut down; one passed with WithTracer
+// belongs to the caller and is only flushed.
+func (c *Client) shutdownTracing() {
+	if c.tracer == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if c.ownsTracer {
+		c.tracer.Shutdown(ctx)
+		return
+	}
+	c.tracer.Flush(ctx)
+}
+
+// setTraceHeader propagates the span context carried by ctx in msg
+func setTraceHeader(ctx context.Context, msg *nats.Msg) {
+	if sc := tracing.SpanContextFromContext(ctx); sc.IsValid() {
+		msg.Header.Set(types.HeaderTraceparent, sc.Traceparent())
+	}
+}
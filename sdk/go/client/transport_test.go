@@ -0,0 +1,79 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/transport"
+)
+
+func TestClientOnMemoryTransport(t *testing.T) {
+	bus := transport.NewMemoryBus()
+	c, err := NewClient("", WithTransport(bus.Connect()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer c.Close()
+	if c.GetNATSConn() != nil {
+		t.Error("Expected no NATS connection")
+	}
+
+	// Publish / subscribe
+	received := make(chan map[string]interface{}, 1)
+	sub, err := c.Subscribe("events.test", func(data map[string]interface{}) { received <- data })
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer sub.Unsubscribe()
+	c.Publish("events.test", map[string]interface{}{"n": 1.0})
+	select {
+	case data := <-received:
+		if data["n"] != 1.0 {
+			t.Errorf("Unexpected message %v", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Message not received")
+	}
+
+	// State in the KV bucket, watched from a second client
+	other, _ := NewClient("", WithTransport(bus.Connect()))
+	defer other.Close()
+	changes := make(chan map[string]interface{}, 2)
+	stop, err := other.WatchState("device.1", func(value map[string]interface{}) { changes <- value })
+	if err != nil {
+		t.Fatalf("WatchState failed: %v", err)
+	}
+	defer stop()
+	if err := c.SetState("device.1", map[string]interface{}{"on": true}); err != nil {
+		t.Fatalf("SetState failed: %v", err)
+	}
+	if value, err := other.GetState("device.1"); err != nil || value["on"] != true {
+		t.Errorf("Unexpected state %v, %v", value, err)
+	}
+	select {
+	case value := <-changes:
+		if value["on"] != true {
+			t.Errorf("Unexpected change %v", value)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("State change not watched")
+	}
+
+	// Files in the object store
+	dir := t.TempDir()
+	src := filepath.Join(dir, "in.txt")
+	os.WriteFile(src, []byte("payload"), 0644)
+	fileID, err := c.UploadFile(src, "in.txt")
+	if err != nil {
+		t.Fatalf("UploadFile failed: %v", err)
+	}
+	dst := filepath.Join(dir, "out.txt")
+	if err := other.DownloadFile(fileID, dst); err != nil {
+		t.Fatalf("DownloadFile failed: %v", err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "payload" {
+		t.Errorf("Unexpected file content %q", data)
+	}
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// CallTyped makes an RPC call with a typed request and response.
+// Req is encoded as the call arguments and the result is decoded into Resp,
+// both using their JSON struct tags.
+func CallTyped[Req, Resp any](ctx context.Context, c *Client, service, method string, req Req) (Resp, error) {
+	var resp Resp
+
+	args, err := StructToArgs(req)
+	if err != nil {
+		return resp, fmt.Errorf("encode request: %w", err)
+	}
+
+	result, err := c.CallContext(ctx, service, method, args)
+	if err != nil {
+		return resp, err
+	}
+
+	if err := ArgsToStruct(result, &resp); err != nil {
+		return resp, fmt.Errorf("decode response: %w", err)
+	}
+	return resp, nil
+}
+
+// StructToArgs converts a struct into an RPC argument map using its JSON tags
+func StructToArgs(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var args map[string]interface{}
+	if err := json.Unmarshal(data, &args); err != nil {
+		return nil, fmt.Errorf("value of type %T does not encode to a JSON object", v)
+	}
+	return args, nil
+}
+
+// ArgsToStruct decodes an RPC argument or result map into the struct pointed to by v
+func ArgsToStruct(args map[string]interface{}, v interface{}) error {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
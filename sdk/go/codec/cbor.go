@@ -0,0 +1,35 @@
+package codec
+
+import (
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// CBOR is the CBOR codec (RFC 8949). Struct fields use their json tags.
+var CBOR Codec = newCBORCodec()
+
+type cborCodec struct {
+	enc cbor.EncMode
+	dec cbor.DecMode
+}
+
+func newCBORCodec() *cborCodec {
+	enc, err := cbor.EncOptions{}.EncMode()
+	if err != nil {
+		panic(err)
+	}
+	dec, err := cbor.DecOptions{
+		DefaultMapType: reflect.TypeOf(map[string]interface{}(nil)),
+	}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+	return &cborCodec{enc: enc, dec: dec}
+}
+
+func (c *cborCodec) ContentType() string { return ContentTypeCBOR }
+
+func (c *cborCodec) Marshal(v interface{}) ([]byte, error) { return c.enc.Marshal(v) }
+
+func (c *cborCodec) Unmarshal(data []byte, v interface{}) error { return c.dec.Unmarshal(data, v) }
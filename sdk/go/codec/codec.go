@@ -0,0 +1,115 @@
+// Package codec encodes RPC payloads.
+//
+// JSON is the default and the only codec every LightLink SDK understands.
+// MessagePack and CBOR keep integers as integers and carry []byte as raw
+// binary instead of base64. The codec of a message is named by its
+// Content-Type header; a message without one is JSON.
+package codec
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Content types of the built-in codecs
+const (
+	ContentTypeJSON    = "application/json"
+	ContentTypeMsgPack = "application/msgpack"
+	ContentTypeCBOR    = "application/cbor"
+)
+
+// Codec marshals and unmarshals payloads
+type Codec interface {
+	// ContentType returns the value of the Content-Type header for this codec
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Codec{}
+)
+
+func init() {
+	Register(JSON)
+	Register(MsgPack)
+	Register(CBOR)
+}
+
+// Register makes a codec available by its content type, replacing any codec
+// registered for the same content type
+func Register(c Codec) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[c.ContentType()] = c
+}
+
+// ForContentType returns the codec for a Content-Type header value.
+// An empty value selects JSON; parameters such as "; charset=utf-8" are ignored.
+func ForContentType(contentType string) (Codec, error) {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	if contentType == "" {
+		return JSON, nil
+	}
+
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	c, ok := registry[contentType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported content type: %s", contentType)
+	}
+	return c, nil
+}
+
+// Float64 converts a decoded number to float64.
+// JSON decodes every number as float64, MessagePack and CBOR decode integers as
+// int64 or uint64, so handlers use this to accept arguments from any codec.
+func Float64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// Bytes converts a decoded binary value to []byte.
+// JSON carries []byte as a base64 string, MessagePack and CBOR as raw bytes.
+func Bytes(v interface{}) ([]byte, error) {
+	switch b := v.(type) {
+	case []byte:
+		return b, nil
+	case string:
+		return base64.StdEncoding.DecodeString(b)
+	default:
+		return nil, fmt.Errorf("expected binary data, got %T", v)
+	}
+}
@@ -0,0 +1,103 @@
+package codec
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+)
+
+func TestRoundTrip(t *testing.T) {
+	request := types.RPCRequest{
+		ID:     "req-1",
+		Method: "backup.create",
+		Args: map[string]interface{}{
+			"count": 42,
+			"ratio": 0.5,
+			"name":  "daily",
+			"data":  []byte{0x00, 0xff, 0x10},
+			"tags":  []interface{}{"a", "b"},
+			"opts":  map[string]interface{}{"compress": true},
+		},
+	}
+
+	for _, c := range []Codec{JSON, MsgPack, CBOR} {
+		t.Run(c.ContentType(), func(t *testing.T) {
+			data, err := c.Marshal(request)
+			if err != nil {
+				t.Fatalf("Marshal failed: %v", err)
+			}
+
+			var decoded types.RPCRequest
+			if err := c.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+
+			if decoded.ID != "req-1" || decoded.Method != "backup.create" {
+				t.Errorf("Unexpected request fields: %+v", decoded)
+			}
+			if n, ok := Float64(decoded.Args["count"]); !ok || n != 42 {
+				t.Errorf("Unexpected count: %#v", decoded.Args["count"])
+			}
+			if n, ok := Float64(decoded.Args["ratio"]); !ok || n != 0.5 {
+				t.Errorf("Unexpected ratio: %#v", decoded.Args["ratio"])
+			}
+			if decoded.Args["name"] != "daily" {
+				t.Errorf("Unexpected name: %#v", decoded.Args["name"])
+			}
+			if b, err := Bytes(decoded.Args["data"]); err != nil || !bytes.Equal(b, []byte{0x00, 0xff, 0x10}) {
+				t.Errorf("Unexpected data: %#v (%v)", decoded.Args["data"], err)
+			}
+			if tags, ok := decoded.Args["tags"].([]interface{}); !ok || len(tags) != 2 {
+				t.Errorf("Unexpected tags: %#v", decoded.Args["tags"])
+			}
+			if opts, ok := decoded.Args["opts"].(map[string]interface{}); !ok || opts["compress"] != true {
+				t.Errorf("Unexpected opts: %#v", decoded.Args["opts"])
+			}
+		})
+	}
+}
+
+func TestBinaryCodecsKeepIntegers(t *testing.T) {
+	for _, c := range []Codec{MsgPack, CBOR} {
+		data, _ := c.Marshal(map[string]interface{}{"id": int64(1) << 60})
+		var decoded map[string]interface{}
+		if err := c.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("%s: Unmarshal failed: %v", c.ContentType(), err)
+		}
+		switch n := decoded["id"].(type) {
+		case int64:
+			if n != 1<<60 {
+				t.Errorf("%s: got %d", c.ContentType(), n)
+			}
+		case uint64:
+			if n != 1<<60 {
+				t.Errorf("%s: got %d", c.ContentType(), n)
+			}
+		default:
+			t.Errorf("%s: expected an integer, got %T", c.ContentType(), n)
+		}
+	}
+}
+
+func TestForContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        Codec
+	}{
+		{"", JSON},
+		{"application/json; charset=utf-8", JSON},
+		{"application/msgpack", MsgPack},
+		{"Application/CBOR", CBOR},
+	}
+	for _, tt := range tests {
+		got, err := ForContentType(tt.contentType)
+		if err != nil || got != tt.want {
+			t.Errorf("ForContentType(%q) = %v, %v", tt.contentType, got, err)
+		}
+	}
+
+	if _, err := ForContentType("application/xml"); err == nil {
+		t.Error("Expected error for unsupported content type")
+	}
+}
@@ -0,0 +1,149 @@
+package codec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content encodings used to compress payloads
+const (
+	EncodingGzip = "gzip"
+	EncodingZstd = "zstd"
+)
+
+const (
+	// DefaultCompressionThreshold is the payload size from which payloads are compressed
+	DefaultCompressionThreshold = 32 * 1024
+	// MaxDecompressedSize bounds the size of a decompressed payload
+	MaxDecompressedSize = 64 * 1024 * 1024
+)
+
+// SupportedEncodings lists the encodings this SDK can decompress, most preferred first
+var SupportedEncodings = []string{EncodingZstd, EncodingGzip}
+
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+// initZstd creates the shared zstd encoder and decoder, which are safe for concurrent use
+func initZstd() {
+	zstdEncoder, zstdErr = zstd.NewWriter(nil)
+	if zstdErr != nil {
+		return
+	}
+	zstdDecoder, zstdErr = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(MaxDecompressedSize))
+}
+
+// AcceptEncoding returns the value advertising SupportedEncodings to peers
+func AcceptEncoding() string {
+	return strings.Join(SupportedEncodings, ", ")
+}
+
+// IsSupportedEncoding reports whether encoding can be used for compression
+func IsSupportedEncoding(encoding string) bool {
+	for _, e := range SupportedEncodings {
+		if e == encoding {
+			return true
+		}
+	}
+	return false
+}
+
+// NegotiateEncoding picks the encoding to compress a payload for a peer that
+// advertised accept. It returns preferred if the peer accepts it, otherwise
+// the first supported encoding the peer accepts, or "" if there is none.
+func NegotiateEncoding(preferred, accept string) string {
+	if accept == "" {
+		return ""
+	}
+	accepted := map[string]bool{}
+	for _, e := range strings.Split(accept, ",") {
+		accepted[strings.ToLower(strings.TrimSpace(e))] = true
+	}
+	if accepted[preferred] {
+		return preferred
+	}
+	for _, e := range SupportedEncodings {
+		if accepted[e] {
+			return e
+		}
+	}
+	return ""
+}
+
+// CompressPayload compresses data with encoding if it is at least threshold
+// bytes and compression makes it smaller. It returns the payload to send and
+// the encoding applied, "" if the payload was left as is.
+func CompressPayload(data []byte, encoding string, threshold int) ([]byte, string) {
+	if encoding == "" || len(data) < threshold {
+		return data, ""
+	}
+	compressed, err := Compress(encoding, data)
+	if err != nil || len(compressed) >= len(data) {
+		return data, ""
+	}
+	return compressed, encoding
+}
+
+// Compress compresses data with encoding
+func Compress(encoding string, data []byte) ([]byte, error) {
+	switch encoding {
+	case EncodingGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case EncodingZstd:
+		zstdOnce.Do(initZstd)
+		if zstdErr != nil {
+			return nil, zstdErr
+		}
+		return zstdEncoder.EncodeAll(data, nil), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %s", encoding)
+	}
+}
+
+// Decompress reverses Compress. An empty encoding returns data unchanged.
+func Decompress(encoding string, data []byte) ([]byte, error) {
+	switch encoding {
+	case "":
+		return data, nil
+	case EncodingGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		out, err := io.ReadAll(io.LimitReader(r, MaxDecompressedSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(out) > MaxDecompressedSize {
+			return nil, fmt.Errorf("decompressed payload exceeds %d bytes", MaxDecompressedSize)
+		}
+		return out, nil
+	case EncodingZstd:
+		zstdOnce.Do(initZstd)
+		if zstdErr != nil {
+			return nil, zstdErr
+		}
+		return zstdDecoder.DecodeAll(data, nil)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding: %s", encoding)
+	}
+}
@@ -0,0 +1,60 @@
+package codec
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	data := []byte(strings.Repeat(`{"sensor":"temp","value":21.5},`, 1000))
+
+	for _, encoding := range SupportedEncodings {
+		compressed, applied := CompressPayload(data, encoding, 1024)
+		if applied != encoding {
+			t.Fatalf("%s: expected payload to be compressed", encoding)
+		}
+		if len(compressed) >= len(data)/5 {
+			t.Errorf("%s: poor compression %d -> %d", encoding, len(data), len(compressed))
+		}
+
+		decompressed, err := Decompress(applied, compressed)
+		if err != nil {
+			t.Fatalf("%s: Decompress failed: %v", encoding, err)
+		}
+		if !bytes.Equal(decompressed, data) {
+			t.Errorf("%s: round trip mismatch", encoding)
+		}
+	}
+}
+
+func TestCompressPayloadThreshold(t *testing.T) {
+	data := []byte(strings.Repeat("a", 100))
+
+	if _, applied := CompressPayload(data, EncodingGzip, 1024); applied != "" {
+		t.Error("Expected payload below threshold to stay uncompressed")
+	}
+	if _, applied := CompressPayload(data, "", 0); applied != "" {
+		t.Error("Expected no compression without an encoding")
+	}
+	if out, err := Decompress("", data); err != nil || !bytes.Equal(out, data) {
+		t.Error("Expected empty encoding to pass data through")
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		preferred, accept, want string
+	}{
+		{EncodingGzip, "zstd, gzip", EncodingGzip},
+		{EncodingGzip, "zstd", EncodingZstd},
+		{EncodingZstd, "br, gzip", EncodingGzip},
+		{EncodingZstd, "", ""},
+		{EncodingZstd, "br", ""},
+	}
+	for _, tt := range tests {
+		if got := NegotiateEncoding(tt.preferred, tt.accept); got != tt.want {
+			t.Errorf("NegotiateEncoding(%q, %q) = %q, want %q", tt.preferred, tt.accept, got, tt.want)
+		}
+	}
+}
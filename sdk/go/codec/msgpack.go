@@ -0,0 +1,35 @@
+package codec
+
+import (
+	"reflect"
+
+	ugorji "github.com/ugorji/go/codec"
+)
+
+// MsgPack is the MessagePack codec. Struct fields use their json tags, so the
+// RPC types encode with the same field names as in JSON.
+var MsgPack Codec = newMsgPackCodec()
+
+type msgPackCodec struct {
+	handle *ugorji.MsgpackHandle
+}
+
+func newMsgPackCodec() *msgPackCodec {
+	h := &ugorji.MsgpackHandle{}
+	h.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	h.WriteExt = true // use the str8 and bin types of the current spec
+	h.SignedInteger = true
+	return &msgPackCodec{handle: h}
+}
+
+func (c *msgPackCodec) ContentType() string { return ContentTypeMsgPack }
+
+func (c *msgPackCodec) Marshal(v interface{}) ([]byte, error) {
+	var data []byte
+	err := ugorji.NewEncoderBytes(&data, c.handle).Encode(v)
+	return data, err
+}
+
+func (c *msgPackCodec) Unmarshal(data []byte, v interface{}) error {
+	return ugorji.NewDecoderBytes(data, c.handle).Decode(v)
+}
@@ -2,7 +2,6 @@ package service
 
 import (
 	"crypto/sha256"
-	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -13,6 +12,7 @@ import (
 
 	"github.com/LiteHomeLab/light_link/sdk/go/backup"
 	"github.com/LiteHomeLab/light_link/sdk/go/client"
+	"github.com/LiteHomeLab/light_link/sdk/go/codec"
 	"github.com/LiteHomeLab/light_link/sdk/go/types"
 )
 
@@ -191,17 +191,17 @@ func (s *BackupService) handleCreateBackup(args map[string]interface{}) (map[str
 
 	// Get max_versions parameter
 	maxVersions := 0
-	if mv, ok := args["max_versions"].(float64); ok {
+	if mv, ok := codec.Float64(args["max_versions"]); ok {
 		maxVersions = int(mv)
 	}
 
-	// Decode base64 data
-	dataBase64, ok := args["data"].(string)
+	// Decode binary data, base64 encoded in JSON
+	dataRaw, ok := args["data"]
 	if !ok {
 		return nil, fmt.Errorf("missing data")
 	}
 
-	data, err := base64.StdEncoding.DecodeString(dataBase64)
+	data, err := codec.Bytes(dataRaw)
 	if err != nil {
 		return nil, fmt.Errorf("decode data: %w", err)
 	}
@@ -295,17 +295,17 @@ func (s *BackupService) handleCreateIncrementalBackup(args map[string]interface{
 
 	// Get max_versions parameter
 	maxVersions := 0
-	if mv, ok := args["max_versions"].(float64); ok {
+	if mv, ok := codec.Float64(args["max_versions"]); ok {
 		maxVersions = int(mv)
 	}
 
-	// Decode base64 data
-	dataBase64, ok := args["data"].(string)
+	// Decode binary data, base64 encoded in JSON
+	dataRaw, ok := args["data"]
 	if !ok {
 		return nil, fmt.Errorf("missing data")
 	}
 
-	data, err := base64.StdEncoding.DecodeString(dataBase64)
+	data, err := codec.Bytes(dataRaw)
 	if err != nil {
 		return nil, fmt.Errorf("decode data: %w", err)
 	}
@@ -464,7 +464,7 @@ func (s *BackupService) handleGetBackup(args map[string]interface{}) (map[string
 		return nil, fmt.Errorf("missing backup_name")
 	}
 
-	versionFloat, ok := args["version"].(float64)
+	versionFloat, ok := codec.Float64(args["version"])
 	if !ok {
 		return nil, fmt.Errorf("missing version")
 	}
@@ -507,9 +507,9 @@ func (s *BackupService) handleGetBackup(args map[string]interface{}) (map[string
 		return nil, fmt.Errorf("checksum mismatch for version %d", version)
 	}
 
-	// Return base64 encoded data
+	// Return binary data, base64 encoded in JSON
 	return map[string]interface{}{
-		"data":     base64.StdEncoding.EncodeToString(data),
+		"data":     data,
 		"size":     len(data),
 		"checksum": checksum,
 	}, nil
@@ -527,7 +527,7 @@ func (s *BackupService) handleDeleteBackup(args map[string]interface{}) (map[str
 		return nil, fmt.Errorf("missing backup_name")
 	}
 
-	versionFloat, ok := args["version"].(float64)
+	versionFloat, ok := codec.Float64(args["version"])
 	if !ok {
 		return nil, fmt.Errorf("missing version")
 	}
@@ -663,17 +663,17 @@ func (s *BackupService) handleUploadInit(args map[string]interface{}) (map[strin
 
 	// Get max_versions parameter
 	maxVersions := 0
-	if mv, ok := args["max_versions"].(float64); ok {
+	if mv, ok := codec.Float64(args["max_versions"]); ok {
 		maxVersions = int(mv)
 	}
 
 	// Get metadata
-	metadataBase64, ok := args["metadata"].(string)
+	metadataRaw, ok := args["metadata"]
 	if !ok {
 		return nil, fmt.Errorf("missing metadata")
 	}
 
-	metadataBytes, err := base64.StdEncoding.DecodeString(metadataBase64)
+	metadataBytes, err := codec.Bytes(metadataRaw)
 	if err != nil {
 		return nil, fmt.Errorf("decode metadata: %w", err)
 	}
@@ -721,12 +721,12 @@ func (s *BackupService) handleUploadChunk(args map[string]interface{}) (map[stri
 		return nil, fmt.Errorf("missing transfer_id")
 	}
 
-	chunkBase64, ok := args["chunk"].(string)
+	chunkRaw, ok := args["chunk"]
 	if !ok {
 		return nil, fmt.Errorf("missing chunk")
 	}
 
-	chunkBytes, err := base64.StdEncoding.DecodeString(chunkBase64)
+	chunkBytes, err := codec.Bytes(chunkRaw)
 	if err != nil {
 		return nil, fmt.Errorf("decode chunk: %w", err)
 	}
@@ -868,7 +868,7 @@ func (s *BackupService) handleDownloadInit(args map[string]interface{}) (map[str
 		return nil, fmt.Errorf("missing backup_name")
 	}
 
-	versionFloat, ok := args["version"].(float64)
+	versionFloat, ok := codec.Float64(args["version"])
 	if !ok {
 		return nil, fmt.Errorf("missing version")
 	}
@@ -876,7 +876,7 @@ func (s *BackupService) handleDownloadInit(args map[string]interface{}) (map[str
 
 	// Get optional chunk_size parameter
 	chunkSize := backup.ChunkSize
-	if cs, ok := args["chunk_size"].(float64); ok {
+	if cs, ok := codec.Float64(args["chunk_size"]); ok {
 		chunkSize = int(cs)
 	}
 
@@ -933,7 +933,7 @@ func (s *BackupService) handleDownloadInit(args map[string]interface{}) (map[str
 		"total_chunks": float64(chunkMetadata.TotalChunks),
 		"total_size":   float64(chunkMetadata.TotalSize),
 		"chunk_size":   float64(chunkSize),
-		"metadata":     base64.StdEncoding.EncodeToString(metadataBytes),
+		"metadata":     metadataBytes,
 	}, nil
 }
 
@@ -944,7 +944,7 @@ func (s *BackupService) handleDownloadChunk(args map[string]interface{}) (map[st
 		return nil, fmt.Errorf("missing transfer_id")
 	}
 
-	chunkIndexFloat, ok := args["chunk_index"].(float64)
+	chunkIndexFloat, ok := codec.Float64(args["chunk_index"])
 	if !ok {
 		return nil, fmt.Errorf("missing chunk_index")
 	}
@@ -987,7 +987,7 @@ func (s *BackupService) handleDownloadChunk(args map[string]interface{}) (map[st
 	}
 
 	return map[string]interface{}{
-		"chunk": base64.StdEncoding.EncodeToString(chunkBytes),
+		"chunk": chunkBytes,
 		"index": float64(chunk.Index),
 		"size":  float64(chunk.Size),
 	}, nil
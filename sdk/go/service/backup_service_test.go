@@ -7,6 +7,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/LiteHomeLab/light_link/sdk/go/codec"
+	"github.com/LiteHomeLab/light_link/sdk/go/testutil"
 	"github.com/LiteHomeLab/light_link/sdk/go/types"
 )
 
@@ -19,9 +21,9 @@ func TestBackupService_CreateAndList(t *testing.T) {
 	defer os.RemoveAll(tempDir)
 
 	// Create service
-	svc, err := NewBackupService("test-backup-agent", "nats://localhost:4222", nil, tempDir)
+	svc, err := NewBackupService("test-backup-agent", testutil.StartNATS(t, testutil.Options{}).URL, nil, tempDir)
 	if err != nil {
-		t.Skip("Need running NATS server:", err)
+		t.Fatal(err)
 	}
 	defer svc.Stop()
 
@@ -68,9 +70,9 @@ func TestBackupService_GetAndDelete(t *testing.T) {
 	}
 	defer os.RemoveAll(tempDir)
 
-	svc, err := NewBackupService("test-backup-agent", "nats://localhost:4222", nil, tempDir)
+	svc, err := NewBackupService("test-backup-agent", testutil.StartNATS(t, testutil.Options{}).URL, nil, tempDir)
 	if err != nil {
-		t.Skip("Need running NATS server:", err)
+		t.Fatal(err)
 	}
 	defer svc.Stop()
 
@@ -100,12 +102,13 @@ func TestBackupService_GetAndDelete(t *testing.T) {
 		t.Fatal("get backup failed:", err)
 	}
 
-	dataBase64, ok := getResult["data"].(string)
+	rawData, ok := getResult["data"]
 	if !ok {
 		t.Fatal("expected data in result")
 	}
 
-	retrievedData, err := base64.StdEncoding.DecodeString(dataBase64)
+	// Binary results are []byte in process and base64 strings once sent as JSON
+	retrievedData, err := codec.Bytes(rawData)
 	if err != nil {
 		t.Fatal("decode data failed:", err)
 	}
@@ -147,9 +150,9 @@ func TestBackupService_MultipleVersions(t *testing.T) {
 	}
 	defer os.RemoveAll(tempDir)
 
-	svc, err := NewBackupService("test-backup-agent", "nats://localhost:4222", nil, tempDir)
+	svc, err := NewBackupService("test-backup-agent", testutil.StartNATS(t, testutil.Options{}).URL, nil, tempDir)
 	if err != nil {
-		t.Skip("Need running NATS server:", err)
+		t.Fatal(err)
 	}
 	defer svc.Stop()
 
@@ -207,9 +210,9 @@ func TestBackupService_MetadataPersistence(t *testing.T) {
 	}
 	defer os.RemoveAll(tempDir)
 
-	svc, err := NewBackupService("test-backup-agent", "nats://localhost:4222", nil, tempDir)
+	svc, err := NewBackupService("test-backup-agent", testutil.StartNATS(t, testutil.Options{}).URL, nil, tempDir)
 	if err != nil {
-		t.Skip("Need running NATS server:", err)
+		t.Fatal(err)
 	}
 
 	if err := svc.Start(); err != nil {
@@ -285,9 +288,9 @@ func TestBackupService_ErrorCases(t *testing.T) {
 	}
 	defer os.RemoveAll(tempDir)
 
-	svc, err := NewBackupService("test-backup-agent", "nats://localhost:4222", nil, tempDir)
+	svc, err := NewBackupService("test-backup-agent", testutil.StartNATS(t, testutil.Options{}).URL, nil, tempDir)
 	if err != nil {
-		t.Skip("Need running NATS server:", err)
+		t.Fatal(err)
 	}
 	defer svc.Stop()
 
@@ -341,9 +344,9 @@ func TestBackupService_VersionListOrder(t *testing.T) {
 	}
 	defer os.RemoveAll(tempDir)
 
-	svc, err := NewBackupService("test-backup-agent", "nats://localhost:4222", nil, tempDir)
+	svc, err := NewBackupService("test-backup-agent", testutil.StartNATS(t, testutil.Options{}).URL, nil, tempDir)
 	if err != nil {
-		t.Skip("Need running NATS server:", err)
+		t.Fatal(err)
 	}
 	defer svc.Stop()
 
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+// cancelRegistry holds the cancel functions of the requests being handled,
+// so a cancel notice from the caller can stop the handler
+type cancelRegistry struct {
+	mu      sync.Mutex
+	cancels map[string]*context.CancelFunc
+}
+
+func newCancelRegistry() *cancelRegistry {
+	return &cancelRegistry{cancels: make(map[string]*context.CancelFunc)}
+}
+
+// track registers cancel under requestID until the returned func is called
+func (r *cancelRegistry) track(requestID string, cancel context.CancelFunc) func() {
+	if requestID == "" {
+		return func() {}
+	}
+	entry := &cancel
+	r.mu.Lock()
+	r.cancels[requestID] = entry
+	r.mu.Unlock()
+
+	return func() {
+		r.mu.Lock()
+		// A retry with the same request ID may have replaced the entry
+		if r.cancels[requestID] == entry {
+			delete(r.cancels, requestID)
+		}
+		r.mu.Unlock()
+	}
+}
+
+// cancel cancels the handler of requestID if it is still running
+func (r *cancelRegistry) cancel(requestID string) {
+	r.mu.Lock()
+	entry := r.cancels[requestID]
+	r.mu.Unlock()
+	if entry != nil {
+		(*entry)()
+	}
+}
+
+// handleCancel cancels the handler context of the request a caller gave up on
+func (s *Service) handleCancel(msg *nats.Msg) {
+	if msg.Header == nil {
+		return
+	}
+	s.cancels.cancel(msg.Header.Get(types.HeaderRequestID))
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/client"
+)
+
+// WithServiceCertEventHandler calls fn when the TLS certificate is reloaded or about to expire
+func WithServiceCertEventHandler(fn func(client.CertEvent)) ServiceOption {
+	return func(s *Service) error {
+		s.onCertEvent = fn
+		return nil
+	}
+}
+
+// CertExpiry returns the expiry of the TLS client certificate, zero without one
+func (s *Service) CertExpiry() time.Time {
+	if s.certs == nil {
+		return time.Time{}
+	}
+	return s.certs.NotAfter()
+}
+
+// handleCertEvent re-registers the instance after a certificate rotation so
+// the manager sees the new expiry, then calls the user handler
+func (s *Service) handleCertEvent(event client.CertEvent) {
+	if event.Type == client.CertReloaded {
+		if metadata := s.GetMetadata(); metadata != nil {
+			if err := s.RegisterMetadata(metadata); err != nil {
+				log.Printf("[Service] Failed to re-register after certificate reload: %v", err)
+			}
+		}
+	}
+	if s.onCertEvent != nil {
+		s.onCertEvent(event)
+	}
+}
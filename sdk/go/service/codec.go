@@ -0,0 +1,25 @@
+package service
+
+import (
+	"github.com/LiteHomeLab/light_link/sdk/go/codec"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+// requestCodec returns the codec named by the Content-Type header of a request
+func requestCodec(msg *nats.Msg) (codec.Codec, error) {
+	if msg.Header == nil {
+		return codec.JSON, nil
+	}
+	return codec.ForContentType(msg.Header.Get(types.HeaderContentType))
+}
+
+// responseCodec returns the codec to answer a request in, falling back to
+// JSON when the caller asked for a codec this service does not support
+func responseCodec(msg *nats.Msg) codec.Codec {
+	cd, err := requestCodec(msg)
+	if err != nil {
+		return codec.JSON
+	}
+	return cd
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/client"
+	"github.com/LiteHomeLab/light_link/sdk/go/codec"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+func TestCodecNegotiation(t *testing.T) {
+	svc, err := NewService("test-codec-service", nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer svc.Stop()
+
+	svc.RegisterRPC("echo", func(args map[string]interface{}) (map[string]interface{}, error) {
+		return args, nil
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	blob := []byte{0x00, 0x01, 0xfe, 0xff}
+	args := map[string]interface{}{"blob": blob, "n": 1 << 53}
+
+	for _, cd := range []codec.Codec{codec.JSON, codec.MsgPack, codec.CBOR} {
+		t.Run(cd.ContentType(), func(t *testing.T) {
+			cli, err := client.NewClient(nats.DefaultURL, client.WithCodec(cd))
+			if err != nil {
+				t.Skip("NATS not available:", err)
+			}
+			defer cli.Close()
+
+			result, err := cli.Call("test-codec-service", "echo", args)
+			if err != nil {
+				t.Fatalf("Call failed: %v", err)
+			}
+			if got, err := codec.Bytes(result["blob"]); err != nil || !bytes.Equal(got, blob) {
+				t.Errorf("Unexpected blob: %#v (%v)", result["blob"], err)
+			}
+			if n, ok := codec.Float64(result["n"]); !ok || n != 1<<53 {
+				t.Errorf("Unexpected n: %#v", result["n"])
+			}
+			if cd != codec.JSON {
+				if _, isBytes := result["blob"].([]byte); !isBytes {
+					t.Errorf("Expected raw bytes with %s, got %T", cd.ContentType(), result["blob"])
+				}
+			}
+		})
+	}
+
+	// An unsupported codec is answered with a JSON error
+	nc, err := nats.Connect(nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer nc.Close()
+
+	msg := nats.NewMsg("$SRV.test-codec-service.echo")
+	msg.Data = []byte("<request/>")
+	msg.Header.Set(types.HeaderContentType, "application/xml")
+	resp, err := nc.RequestMsg(msg, 2*time.Second)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	var response types.RPCResponse
+	if err := codec.JSON.Unmarshal(resp.Data, &response); err != nil {
+		t.Fatalf("Expected JSON error response: %v", err)
+	}
+	if response.Success {
+		t.Error("Expected unsupported content type to fail")
+	}
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/codec"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+// compressionConfig configures response compression
+type compressionConfig struct {
+	encoding  string
+	threshold int
+}
+
+// WithServiceCompression compresses response payloads of at least threshold
+// bytes with encoding (codec.EncodingGzip or codec.EncodingZstd) for callers
+// that advertise support. A threshold of zero selects codec.DefaultCompressionThreshold.
+// Compressed requests are always decompressed, with or without this option.
+func WithServiceCompression(encoding string, threshold int) ServiceOption {
+	return func(s *Service) error {
+		if !codec.IsSupportedEncoding(encoding) {
+			return fmt.Errorf("unsupported content encoding: %s", encoding)
+		}
+		if threshold <= 0 {
+			threshold = codec.DefaultCompressionThreshold
+		}
+		s.compression = &compressionConfig{encoding: encoding, threshold: threshold}
+		return nil
+	}
+}
+
+// responseEncoding returns the encoding to compress responses to msg with, "" for none
+func (s *Service) responseEncoding(msg *nats.Msg) string {
+	if s.compression == nil || msg.Header == nil {
+		return ""
+	}
+	return codec.NegotiateEncoding(s.compression.encoding, msg.Header.Get(types.HeaderAcceptEncoding))
+}
+
+// compressionThreshold returns the size from which responses are compressed
+func (s *Service) compressionThreshold() int {
+	if s.compression == nil {
+		return 0
+	}
+	return s.compression.threshold
+}
+
+// requestPayload returns the decompressed payload of a request
+func requestPayload(msg *nats.Msg) ([]byte, error) {
+	if msg.Header == nil {
+		return msg.Data, nil
+	}
+	return codec.Decompress(msg.Header.Get(types.HeaderContentEncoding), msg.Data)
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/client"
+	"github.com/LiteHomeLab/light_link/sdk/go/codec"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+func TestCompressedResponses(t *testing.T) {
+	svc, err := NewService("test-compress-service", nats.DefaultURL, WithServiceCompression(codec.EncodingZstd, 1024))
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer svc.Stop()
+
+	report := strings.Repeat("all systems nominal. ", 1000)
+	svc.RegisterRPC("report", func(args map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{"report": report}, nil
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	nc, err := nats.Connect(nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer nc.Close()
+
+	request := func(accept string) *nats.Msg {
+		data, _ := json.Marshal(types.RPCRequest{ID: "req", Method: "report"})
+		msg := nats.NewMsg("$SRV.test-compress-service.report")
+		msg.Data = data
+		if accept != "" {
+			msg.Header.Set(types.HeaderAcceptEncoding, accept)
+		}
+		resp, err := nc.RequestMsg(msg, 2*time.Second)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		return resp
+	}
+
+	// Callers that do not advertise support get plain payloads
+	plain := request("")
+	if enc := plain.Header.Get(types.HeaderContentEncoding); enc != "" {
+		t.Errorf("Expected uncompressed response, got %s", enc)
+	}
+	if len(plain.Data) < len(report) {
+		t.Errorf("Expected full size response, got %d bytes", len(plain.Data))
+	}
+
+	compressed := request("gzip")
+	if enc := compressed.Header.Get(types.HeaderContentEncoding); enc != codec.EncodingGzip {
+		t.Fatalf("Expected gzip response, got %q", enc)
+	}
+	data, err := codec.Decompress(codec.EncodingGzip, compressed.Data)
+	if err != nil {
+		t.Fatalf("Decompress failed: %v", err)
+	}
+	var response types.RPCResponse
+	if err := json.Unmarshal(data, &response); err != nil || response.Result["report"] != report {
+		t.Errorf("Unexpected decompressed response: %v", err)
+	}
+
+	// The Go client decompresses transparently
+	cli, err := client.NewClient(nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer cli.Close()
+
+	result, err := cli.Call("test-compress-service", "report", nil)
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if result["report"] != report {
+		t.Error("Unexpected report from compressed response")
+	}
+}
+
+func TestCompressedRequests(t *testing.T) {
+	svc, err := NewService("test-compress-req-service", nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer svc.Stop()
+
+	svc.RegisterRPC("size", func(args map[string]interface{}) (map[string]interface{}, error) {
+		payload, _ := args["payload"].(string)
+		return map[string]interface{}{"size": len(payload)}, nil
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	// Record the encoding of every request on the wire
+	nc, err := nats.Connect(nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer nc.Close()
+
+	var mu sync.Mutex
+	var encodings []string
+	nc.Subscribe(types.InstanceRPCSubject(svc.InstanceKey(), "size"), func(msg *nats.Msg) {
+		mu.Lock()
+		encodings = append(encodings, msg.Header.Get(types.HeaderContentEncoding))
+		mu.Unlock()
+	})
+	nc.Flush()
+	time.Sleep(100 * time.Millisecond)
+
+	cli, err := client.NewClient(nats.DefaultURL, client.WithCompression(codec.EncodingGzip, 1024))
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer cli.Close()
+
+	payload := strings.Repeat("x", 64*1024)
+	for i := 0; i < 2; i++ {
+		result, err := cli.CallInstance(svc.InstanceKey(), "size", map[string]interface{}{"payload": payload})
+		if err != nil {
+			t.Fatalf("Call failed: %v", err)
+		}
+		if size, _ := codec.Float64(result["size"]); int(size) != len(payload) {
+			t.Errorf("Expected size %d, got %v", len(payload), result["size"])
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// The first request learns that the instance accepts compression
+	mu.Lock()
+	defer mu.Unlock()
+	if len(encodings) != 2 || encodings[0] != "" || encodings[1] != codec.EncodingGzip {
+		t.Errorf("Expected plain then gzip request, got %q", encodings)
+	}
+}
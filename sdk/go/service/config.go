@@ -0,0 +1,56 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/client"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+)
+
+// NewServiceFromConfig creates a service from a YAML or JSON config file with
+// the LIGHTLINK_* environment overrides applied, see types.LoadConfig.
+// service_name is required. opts are applied after the config.
+func NewServiceFromConfig(path string, opts ...ServiceOption) (*Service, error) {
+	cfg, err := types.LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ServiceName == "" {
+		return nil, &types.ConfigError{Field: "service_name", Message: "required for services"}
+	}
+	return NewService(cfg.ServiceName, cfg.NATSURL, append([]ServiceOption{WithServiceConfig(cfg)}, opts...)...)
+}
+
+// WithServiceConfig applies the connection settings, TLS, heartbeat interval
+// and metadata of cfg. The metadata is registered when the service starts,
+// with the methods registered by then.
+func WithServiceConfig(cfg *types.Config) ServiceOption {
+	return func(s *Service) error {
+		s.connect.ApplyConfig(cfg)
+		if cfg.Heartbeat.Interval > 0 {
+			s.heartbeatInterval = time.Duration(cfg.Heartbeat.Interval)
+		}
+		s.configMetadata = cfg.Metadata
+		if cfg.AutoTLS {
+			return WithServiceAutoTLS()(s)
+		}
+		if cfg.TLS != nil {
+			s.tlsConfig = client.TLSConfigFrom(cfg.TLS)
+		}
+		return nil
+	}
+}
+
+// registerConfigMetadata registers the metadata of the config file unless
+// metadata was registered already
+func (s *Service) registerConfigMetadata() {
+	if s.configMetadata == nil || s.GetMetadata() != nil {
+		return
+	}
+	m := s.configMetadata
+	metadata := s.BuildCurrentMetadata(s.name, m.Version, m.Description, m.Author, m.Tags)
+	if err := s.RegisterMetadata(metadata); err != nil {
+		log.Printf("[Service] Failed to register config metadata: %v", err)
+	}
+}
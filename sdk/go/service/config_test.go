@@ -0,0 +1,82 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+func TestNewServiceFromConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lightlink.yaml")
+	os.WriteFile(path, []byte(`
+nats_url: `+nats.DefaultURL+`
+service_name: test-config-service
+heartbeat:
+  interval: 1s
+metadata:
+  version: v2.0.0
+  author: tester
+`), 0644)
+
+	nc, err := nats.Connect(nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer nc.Close()
+
+	registered := make(chan types.RegisterMessage, 1)
+	regSub, _ := nc.Subscribe("$LL.register.test-config-service", func(msg *nats.Msg) {
+		var register types.RegisterMessage
+		json.Unmarshal(msg.Data, &register)
+		registered <- register
+	})
+	defer regSub.Unsubscribe()
+	heartbeats, _ := nc.SubscribeSync("$LL.heartbeat.test-config-service")
+	defer heartbeats.Unsubscribe()
+
+	svc, err := NewServiceFromConfig(path)
+	if err != nil {
+		t.Fatalf("NewServiceFromConfig failed: %v", err)
+	}
+	defer svc.Stop()
+	svc.RegisterMethodWithMetadata("ping", func(args map[string]interface{}) (map[string]interface{}, error) {
+		return nil, nil
+	}, &types.MethodMetadata{Name: "ping"})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	// Metadata from the config is registered on start
+	select {
+	case register := <-registered:
+		if register.Version != "v2.0.0" || register.Metadata.Author != "tester" || len(register.Metadata.Methods) != 1 {
+			t.Errorf("Unexpected registration %+v", register)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Metadata not registered")
+	}
+
+	// Heartbeats follow the configured interval
+	for i := 0; i < 2; i++ {
+		if _, err := heartbeats.NextMsg(1500 * time.Millisecond); err != nil {
+			t.Fatalf("Heartbeat %d not received: %v", i+1, err)
+		}
+	}
+}
+
+func TestNewServiceFromConfigRequiresName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lightlink.yaml")
+	os.WriteFile(path, []byte("nats_url: "+nats.DefaultURL+"\n"), 0644)
+
+	_, err := NewServiceFromConfig(path)
+	var cfgErr *types.ConfigError
+	if !errors.As(err, &cfgErr) || cfgErr.Field != "service_name" {
+		t.Errorf("Expected service_name config error, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/client"
+)
+
+// WithServiceServers adds seed server URLs tried besides the URL passed to NewService
+func WithServiceServers(urls ...string) ServiceOption {
+	return func(s *Service) error {
+		s.connect.Servers = append(s.connect.Servers, urls...)
+		return nil
+	}
+}
+
+// WithServiceReconnect sets the wait time between reconnect attempts and their
+// maximum number. Services reconnect forever by default.
+func WithServiceReconnect(wait time.Duration, maxReconnects int) ServiceOption {
+	return func(s *Service) error {
+		if maxReconnects < client.InfiniteReconnects {
+			return fmt.Errorf("max reconnects must be at least %d, got %d", client.InfiniteReconnects, maxReconnects)
+		}
+		s.connect.ReconnectWait = wait
+		s.connect.MaxReconnects = maxReconnects
+		return nil
+	}
+}
+
+// WithServiceUserInfo authenticates with a username and password
+func WithServiceUserInfo(user, password string) ServiceOption {
+	return func(s *Service) error {
+		s.connect.User = user
+		s.connect.Password = password
+		return nil
+	}
+}
+
+// WithServiceToken authenticates with a token
+func WithServiceToken(token string) ServiceOption {
+	return func(s *Service) error {
+		s.connect.Token = token
+		return nil
+	}
+}
+
+// WithServiceNKeyFile authenticates with the NKey seed stored in seedFile
+func WithServiceNKeyFile(seedFile string) ServiceOption {
+	return func(s *Service) error {
+		s.connect.NKeySeedFile = seedFile
+		return nil
+	}
+}
+
+// WithServiceCredentials authenticates with a .creds file holding a user JWT and NKey seed
+func WithServiceCredentials(credsFile string) ServiceOption {
+	return func(s *Service) error {
+		s.connect.CredsFile = credsFile
+		return nil
+	}
+}
+
+// WithServiceInboxPrefix replaces the _INBOX prefix of reply subjects
+func WithServiceInboxPrefix(prefix string) ServiceOption {
+	return func(s *Service) error {
+		s.connect.InboxPrefix = prefix
+		return nil
+	}
+}
+
+// WithServiceDisconnectHandler calls fn when the connection is lost
+func WithServiceDisconnectHandler(fn func(err error)) ServiceOption {
+	return func(s *Service) error {
+		s.connect.OnDisconnect = fn
+		return nil
+	}
+}
+
+// WithServiceReconnectHandler calls fn with the server URL after a reconnect.
+// Subscriptions are restored by the NATS client before fn runs.
+func WithServiceReconnectHandler(fn func(url string)) ServiceOption {
+	return func(s *Service) error {
+		s.connect.OnReconnect = fn
+		return nil
+	}
+}
+
+// WithServiceClosedHandler calls fn once the connection is closed for good,
+// either by Stop or after the reconnect attempts ran out
+func WithServiceClosedHandler(fn func()) ServiceOption {
+	return func(s *Service) error {
+		s.connect.OnClosed = fn
+		return nil
+	}
+}
+
+// WithServiceErrorHandler calls fn for asynchronous connection errors such as slow consumers
+func WithServiceErrorHandler(fn func(subject string, err error)) ServiceOption {
+	return func(s *Service) error {
+		s.connect.OnError = fn
+		return nil
+	}
+}
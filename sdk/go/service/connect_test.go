@@ -0,0 +1,44 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/client"
+	"github.com/nats-io/nats.go"
+)
+
+func TestServiceConnectOptions(t *testing.T) {
+	svc, err := NewService("test-connect-service", nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	// Services keep reconnecting by default
+	if svc.nc.Opts.MaxReconnect != client.InfiniteReconnects {
+		t.Errorf("Expected infinite reconnects, got %d", svc.nc.Opts.MaxReconnect)
+	}
+	svc.Stop()
+
+	closed := make(chan struct{})
+	svc, err = NewService("test-connect-service", "",
+		WithServiceServers(nats.DefaultURL),
+		WithServiceReconnect(time.Second, 5),
+		WithServiceInboxPrefix("_LL_SVC_INBOX"),
+		WithServiceClosedHandler(func() { close(closed) }))
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	if svc.nc.Opts.MaxReconnect != 5 || svc.nc.Opts.InboxPrefix != "_LL_SVC_INBOX" {
+		t.Errorf("Unexpected options: %d, %q", svc.nc.Opts.MaxReconnect, svc.nc.Opts.InboxPrefix)
+	}
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	svc.Stop()
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Error("Closed handler not called")
+	}
+}
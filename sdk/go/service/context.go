@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/tracing"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+// contextKey is the type of context keys defined by this package
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	callInfoKey
+)
+
+// RequestIDFromContext returns the RPC request ID carried by a handler context
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// newRequestContext builds the handler context for an incoming RPC request.
+// The caller's remaining time budget from the LL-Timeout header becomes the
+// context deadline, so handlers see ctx.Done() once the caller has given up.
+// A caller cancelling early sends a cancel notice instead, see handleCancel.
+// The caller's traceparent is carried along, so calls made with the context
+// continue the caller's trace.
+func newRequestContext(msg *nats.Msg, requestID string) (context.Context, context.CancelFunc) {
+	if requestID == "" && msg.Header != nil {
+		requestID = msg.Header.Get(types.HeaderRequestID)
+	}
+	ctx := context.WithValue(context.Background(), requestIDKey, requestID)
+	if sc, ok := remoteSpanContext(msg); ok {
+		ctx = tracing.ContextWithRemoteSpanContext(ctx, sc)
+	}
+
+	if msg.Header != nil {
+		if v := msg.Header.Get(types.HeaderTimeout); v != "" {
+			if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+				return context.WithTimeout(ctx, time.Duration(ms)*time.Millisecond)
+			}
+		}
+	}
+
+	return context.WithCancel(ctx)
+}
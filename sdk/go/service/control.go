@@ -7,29 +7,50 @@ import (
 	"os"
 	"sync"
 
+	"github.com/LiteHomeLab/light_link/sdk/go/transport"
 	"github.com/LiteHomeLab/light_link/sdk/go/types"
 	"github.com/nats-io/nats.go"
 )
 
+// TrafficController adjusts how much RPC traffic an instance receives
+type TrafficController interface {
+	SetWeight(weight int) error
+	Drain() error
+	Resume() error
+}
+
 // ControlHandler handles control messages from the management platform
 type ControlHandler struct {
-	nc          *nats.Conn
+	conn        transport.Conn
 	serviceName string
 	instanceKey string
-	sub         *nats.Subscription
+	sub         transport.Subscription
+	traffic     TrafficController
 	mu          sync.RWMutex
 	running     bool
 }
 
 // NewControlHandler creates a new control handler
 func NewControlHandler(nc *nats.Conn, serviceName, instanceKey string) *ControlHandler {
+	return newControlHandler(transport.NewNATS(nc), serviceName, instanceKey)
+}
+
+// newControlHandler creates a control handler on any transport
+func newControlHandler(conn transport.Conn, serviceName, instanceKey string) *ControlHandler {
 	return &ControlHandler{
-		nc:          nc,
+		conn:        conn,
 		serviceName: serviceName,
 		instanceKey: instanceKey,
 	}
 }
 
+// SetTrafficController sets the target of drain, resume and set_weight commands
+func (c *ControlHandler) SetTrafficController(tc TrafficController) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.traffic = tc
+}
+
 // Subscribe subscribes to control messages for this service instance
 func (c *ControlHandler) Subscribe() error {
 	c.mu.Lock()
@@ -41,7 +62,7 @@ func (c *ControlHandler) Subscribe() error {
 
 	// Subscribe to service-specific control messages
 	subject := fmt.Sprintf("$LL.control.%s.>", c.serviceName)
-	sub, err := c.nc.Subscribe(subject, c.handleControl)
+	sub, err := c.conn.Subscribe(subject, c.handleControl)
 	if err != nil {
 		return fmt.Errorf("failed to subscribe to control messages: %w", err)
 	}
@@ -97,11 +118,42 @@ func (c *ControlHandler) handleControl(msg *nats.Msg) {
 		// Exit code 99 indicates restart
 		os.Exit(99)
 
+	case "drain", "resume", "set_weight":
+		c.handleTrafficCommand(&control)
+
 	default:
 		log.Printf("[Control] Unknown command: %s", control.Command)
 	}
 }
 
+// handleTrafficCommand applies a traffic command to the traffic controller
+func (c *ControlHandler) handleTrafficCommand(control *types.ControlMessage) {
+	c.mu.RLock()
+	tc := c.traffic
+	c.mu.RUnlock()
+
+	if tc == nil {
+		log.Printf("[Control] No traffic controller, ignoring command: %s", control.Command)
+		return
+	}
+
+	var err error
+	switch control.Command {
+	case "drain":
+		log.Printf("[Control] Draining instance...")
+		err = tc.Drain()
+	case "resume":
+		log.Printf("[Control] Resuming instance...")
+		err = tc.Resume()
+	case "set_weight":
+		log.Printf("[Control] Setting weight to %d...", control.Weight)
+		err = tc.SetWeight(control.Weight)
+	}
+	if err != nil {
+		log.Printf("[Control] Command %s failed: %v", control.Command, err)
+	}
+}
+
 // IsRunning returns whether the control handler is running
 func (c *ControlHandler) IsRunning() bool {
 	c.mu.RLock()
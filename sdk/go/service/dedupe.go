@@ -0,0 +1,194 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// DefaultDedupeTTL is how long a response stays in the dedupe cache
+	DefaultDedupeTTL = 5 * time.Minute
+	// DefaultDedupeEntries is the default maximum number of cached responses
+	DefaultDedupeEntries = 10000
+)
+
+// WithDedupeCache enables the idempotency cache.
+// A request whose idempotency key (or request ID) was already handled within
+// ttl is answered with the cached response instead of running the handler again.
+// Keys are scoped to the method. Requests still in flight are never evicted, so
+// the cache may briefly hold more than maxEntries.
+// Zero values select DefaultDedupeTTL and DefaultDedupeEntries.
+func WithDedupeCache(ttl time.Duration, maxEntries int) ServiceOption {
+	return func(s *Service) error {
+		s.dedupe = newDedupeCache(ttl, maxEntries)
+		return nil
+	}
+}
+
+// idempotencyKey returns the dedupe key of a request. It is scoped to the
+// method, so one caller key reused for two methods runs both.
+func idempotencyKey(msg *nats.Msg, request *types.RPCRequest) string {
+	key := request.ID
+	if msg.Header != nil {
+		if header := msg.Header.Get(types.HeaderIdempotencyKey); header != "" {
+			key = header
+		}
+	}
+	if key == "" {
+		return ""
+	}
+	return request.Method + "\x00" + key
+}
+
+// handleDeduped runs a request at most once per idempotency key.
+// A duplicate arriving while the first attempt is still running waits for it.
+func (s *Service) handleDeduped(ctx context.Context, msg *nats.Msg, request *types.RPCRequest, key string) {
+	entry, owner := s.dedupe.acquire(key)
+	if !owner {
+		if respData, ok := entry.wait(ctx); ok {
+			s.reply(msg, respData)
+		}
+		return
+	}
+
+	response := s.processRPC(ctx, request)
+	respData, _ := responseCodec(msg).Marshal(response)
+	if cacheable(response) {
+		s.dedupe.complete(entry, respData)
+	} else {
+		s.dedupe.release(entry, respData)
+	}
+	s.reply(msg, respData)
+}
+
+// cacheable reports whether a response may answer retries of its request.
+// Retryable failures are not cached, so a retry runs the handler again.
+func cacheable(response types.RPCResponse) bool {
+	if response.Success || response.ErrorInfo == nil {
+		return true
+	}
+	info := response.ErrorInfo
+	return !info.Retryable && !types.IsRetryableCode(info.Code) && info.Code != types.CodeDeadlineExceeded
+}
+
+// dedupeEntry is a cached response, or a placeholder while the request is in flight
+type dedupeEntry struct {
+	key      string
+	done     chan struct{}
+	response []byte
+	expires  time.Time
+	elem     *list.Element
+}
+
+// wait blocks until the entry is completed or ctx is done
+func (e *dedupeEntry) wait(ctx context.Context) ([]byte, bool) {
+	select {
+	case <-e.done:
+		return e.response, true
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+// dedupeCache is a bounded TTL cache of RPC responses keyed by idempotency key
+type dedupeCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*dedupeEntry
+	order      *list.List // oldest first
+}
+
+// newDedupeCache creates a dedupe cache
+func newDedupeCache(ttl time.Duration, maxEntries int) *dedupeCache {
+	if ttl <= 0 {
+		ttl = DefaultDedupeTTL
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultDedupeEntries
+	}
+	return &dedupeCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*dedupeEntry),
+		order:      list.New(),
+	}
+}
+
+// acquire returns the entry for key. owner is true when the caller created
+// the entry and must run the request and call complete.
+func (c *dedupeCache) acquire(key string) (entry *dedupeEntry, owner bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if e, ok := c.entries[key]; ok {
+		if e.expires.IsZero() || now.Before(e.expires) {
+			return e, false
+		}
+		c.removeLocked(e)
+	}
+
+	c.evictLocked()
+
+	e := &dedupeEntry{key: key, done: make(chan struct{})}
+	e.elem = c.order.PushBack(e)
+	c.entries[key] = e
+	return e, true
+}
+
+// evictLocked drops the oldest completed entries until there is room for one
+// more. Entries in flight are kept, so the cache grows past maxEntries while
+// they are pending. Caller must hold mu.
+func (c *dedupeCache) evictLocked() {
+	elem := c.order.Front()
+	for c.order.Len() >= c.maxEntries && elem != nil {
+		next := elem.Next()
+		if e := elem.Value.(*dedupeEntry); !e.expires.IsZero() {
+			c.removeLocked(e)
+		}
+		elem = next
+	}
+}
+
+// complete stores the response of an entry and wakes up waiting duplicates
+func (c *dedupeCache) complete(e *dedupeEntry, response []byte) {
+	c.mu.Lock()
+	e.response = response
+	e.expires = time.Now().Add(c.ttl)
+	c.mu.Unlock()
+
+	close(e.done)
+}
+
+// release wakes up waiting duplicates with the response without caching it
+func (c *dedupeCache) release(e *dedupeEntry, response []byte) {
+	c.mu.Lock()
+	e.response = response
+	if c.entries[e.key] == e {
+		c.removeLocked(e)
+	}
+	c.mu.Unlock()
+
+	close(e.done)
+}
+
+// removeLocked removes an entry. Caller must hold mu.
+func (c *dedupeCache) removeLocked(e *dedupeEntry) {
+	c.order.Remove(e.elem)
+	if c.entries[e.key] == e {
+		delete(c.entries, e.key)
+	}
+}
+
+// len returns the number of cached entries
+func (c *dedupeCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/client"
+	"github.com/LiteHomeLab/light_link/sdk/go/transport"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+func TestDedupeCacheAcquire(t *testing.T) {
+	cache := newDedupeCache(time.Minute, 2)
+
+	entry, owner := cache.acquire("a")
+	if !owner {
+		t.Fatal("Expected first acquire to own the entry")
+	}
+
+	dup, owner := cache.acquire("a")
+	if owner {
+		t.Fatal("Expected duplicate acquire not to own the entry")
+	}
+
+	go cache.complete(entry, []byte("response"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, ok := dup.wait(ctx)
+	if !ok || string(resp) != "response" {
+		t.Errorf("Expected cached response, got %q (%v)", resp, ok)
+	}
+
+	// Capacity eviction drops the oldest entry
+	cache.acquire("b")
+	cache.acquire("c")
+	if cache.len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", cache.len())
+	}
+	if _, owner := cache.acquire("a"); !owner {
+		t.Error("Expected evicted key to be acquired again")
+	}
+}
+
+func TestDedupeCacheExpiry(t *testing.T) {
+	cache := newDedupeCache(10*time.Millisecond, 10)
+
+	entry, _ := cache.acquire("a")
+	cache.complete(entry, []byte("old"))
+	time.Sleep(20 * time.Millisecond)
+
+	if _, owner := cache.acquire("a"); !owner {
+		t.Error("Expected expired entry to be replaced")
+	}
+}
+
+func TestDedupeCacheRelease(t *testing.T) {
+	cache := newDedupeCache(time.Minute, 10)
+
+	entry, _ := cache.acquire("a")
+	dup, _ := cache.acquire("a")
+	cache.release(entry, []byte("unavailable"))
+
+	if resp, ok := dup.wait(context.Background()); !ok || string(resp) != "unavailable" {
+		t.Errorf("Expected waiter to get the response, got %q (%v)", resp, ok)
+	}
+	if _, owner := cache.acquire("a"); !owner {
+		t.Error("Expected released key to be acquired again")
+	}
+}
+
+func TestDedupeCacheKeepsInFlight(t *testing.T) {
+	cache := newDedupeCache(time.Minute, 1)
+
+	pending, _ := cache.acquire("a")
+	cache.acquire("b")
+	if cache.len() != 2 {
+		t.Errorf("Expected the cache to grow while requests are in flight, got %d entries", cache.len())
+	}
+	if _, owner := cache.acquire("a"); owner {
+		t.Error("Expected the in-flight entry to stay")
+	}
+
+	// Completed entries are evicted again once over capacity
+	cache.complete(pending, []byte("response"))
+	cache.acquire("c")
+	if _, owner := cache.acquire("a"); !owner {
+		t.Error("Expected the completed entry to be evicted")
+	}
+}
+
+func TestDedupeRetriedRequest(t *testing.T) {
+	nc, err := nats.Connect(nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer nc.Close()
+
+	svc, err := NewService("test-dedupe-service", nats.DefaultURL, WithDedupeCache(time.Minute, 100))
+	if err != nil {
+		t.Fatal("NewService failed:", err)
+	}
+	defer svc.Stop()
+
+	var calls int32
+	svc.RegisterRPC("charge", func(args map[string]interface{}) (map[string]interface{}, error) {
+		n := atomic.AddInt32(&calls, 1)
+		return map[string]interface{}{"charge_id": n}, nil
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	send := func(requestID string) types.RPCResponse {
+		data, _ := json.Marshal(types.RPCRequest{ID: requestID, Method: "charge"})
+		msg := nats.NewMsg("$SRV.test-dedupe-service.charge")
+		msg.Data = data
+		msg.Header.Set(types.HeaderIdempotencyKey, "payment-1")
+		resp, err := nc.RequestMsg(msg, 2*time.Second)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		var response types.RPCResponse
+		json.Unmarshal(resp.Data, &response)
+		return response
+	}
+
+	first := send("req-1")
+	second := send("req-1")
+
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected handler to run once, ran %d times", calls)
+	}
+	if first.Result["charge_id"] != second.Result["charge_id"] {
+		t.Errorf("Expected cached response, got %v and %v", first.Result, second.Result)
+	}
+}
+
+func TestDedupeRetriesRetryableFailure(t *testing.T) {
+	bus := transport.NewMemoryBus()
+	svc, err := NewService("test-dedupe-retry", "", WithServiceTransport(bus.Connect()), WithDedupeCache(time.Minute, 100))
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	var calls int32
+	svc.RegisterRPC("charge", func(args map[string]interface{}) (map[string]interface{}, error) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			return nil, types.ErrUnavailable
+		case 2:
+			return map[string]interface{}{"charge_id": "ch-1"}, nil
+		}
+		return nil, types.NewRPCError(types.CodeFailedPrecondition, "charged twice")
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer svc.Stop()
+
+	policy := client.DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	cli, err := client.NewClient("", client.WithTransport(bus.Connect()), client.WithRetryPolicy(policy))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer cli.Close()
+
+	// The unavailable failure is not cached, the retry runs the handler again
+	ctx := client.ContextWithIdempotencyKey(context.Background(), "payment-1")
+	result, err := cli.CallContext(ctx, "test-dedupe-retry", "charge", nil)
+	if err != nil || result["charge_id"] != "ch-1" {
+		t.Fatalf("Expected the retried call to succeed, got %v, %v", result, err)
+	}
+	// The success is cached
+	if result, err = cli.CallContext(ctx, "test-dedupe-retry", "charge", nil); err != nil || result["charge_id"] != "ch-1" {
+		t.Errorf("Expected the cached result, got %v, %v", result, err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("Expected 2 handler calls, got %d", n)
+	}
+}
+
+func TestDedupeKeyScopedToMethod(t *testing.T) {
+	bus := transport.NewMemoryBus()
+	svc, err := NewService("test-dedupe-methods", "", WithServiceTransport(bus.Connect()), WithDedupeCache(time.Minute, 100))
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	svc.RegisterRPC("charge", func(args map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{"method": "charge"}, nil
+	})
+	svc.RegisterRPC("refund", func(args map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{"method": "refund"}, nil
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer svc.Stop()
+
+	cli, err := client.NewClient("", client.WithTransport(bus.Connect()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer cli.Close()
+
+	ctx := client.ContextWithIdempotencyKey(context.Background(), "order-1")
+	for _, method := range []string{"charge", "refund"} {
+		result, err := cli.CallContext(ctx, "test-dedupe-methods", method, nil)
+		if err != nil || result["method"] != method {
+			t.Errorf("Expected the %s handler to answer, got %v, %v", method, result, err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+)
+
+// Error returns a coded error for a handler to return, e.g.
+//
+//	return nil, service.Error(types.CodeNotFound, "user not found")
+//
+// Callers receive it as a *types.RPCError matching the types.Err* sentinel
+// of the code. Errors without a code reach callers as types.CodeUnknown.
+func Error(code, message string) *types.RPCError {
+	return types.NewRPCError(code, message)
+}
+
+// Errorf is Error with a formatted message
+func Errorf(code, format string, args ...interface{}) *types.RPCError {
+	return types.NewRPCError(code, fmt.Sprintf(format, args...))
+}
+
+// ErrorWithDetails returns a coded error carrying structured details for the caller
+func ErrorWithDetails(code, message string, details map[string]interface{}) *types.RPCError {
+	err := types.NewRPCError(code, message)
+	err.Details = details
+	return err
+}
+
+// RetryableError returns a coded error telling callers the request may
+// succeed when sent again, whatever the code
+func RetryableError(code, message string) *types.RPCError {
+	err := types.NewRPCError(code, message)
+	err.Retryable = true
+	return err
+}
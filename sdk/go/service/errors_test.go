@@ -0,0 +1,96 @@
+package service
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/client"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+func TestStructuredErrors(t *testing.T) {
+	svc, err := NewService("test-errors-service", nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer svc.Stop()
+
+	svc.RegisterMethodWithMetadata("add", func(args map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{}, nil
+	}, &types.MethodMetadata{
+		Name:   "add",
+		Params: []types.ParameterMetadata{{Name: "a", Type: "number", Required: true}},
+	})
+	svc.RegisterRPC("getUser", func(args map[string]interface{}) (map[string]interface{}, error) {
+		return nil, ErrorWithDetails(types.CodeNotFound, "user not found", map[string]interface{}{"id": args["id"]})
+	})
+	svc.RegisterRPC("fail", func(args map[string]interface{}) (map[string]interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	var attempts int32
+	svc.RegisterRPC("flaky", func(args map[string]interface{}) (map[string]interface{}, error) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			return nil, RetryableError(types.CodeFailedPrecondition, "warming up")
+		}
+		return map[string]interface{}{"ok": true}, nil
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	cli, err := client.NewClient(nats.DefaultURL, client.WithRetryPolicy(client.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     50 * time.Millisecond,
+		Multiplier:     2,
+	}))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer cli.Close()
+
+	// Unknown method
+	_, err = cli.Call("test-errors-service", "missing", nil)
+	if !errors.Is(err, types.ErrMethodNotFound) {
+		t.Errorf("Expected ErrMethodNotFound, got %v", err)
+	}
+
+	// Validation failure keeps the parameter details
+	_, err = cli.Call("test-errors-service", "add", map[string]interface{}{"a": "x"})
+	var validationErr *types.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected *types.ValidationError, got %v", err)
+	}
+	if validationErr.ParameterName != "a" || !errors.Is(err, types.ErrValidation) {
+		t.Errorf("Unexpected validation error %+v", validationErr)
+	}
+
+	// Coded handler error with details
+	_, err = cli.Call("test-errors-service", "getUser", map[string]interface{}{"id": "42"})
+	var rpcErr *types.RPCError
+	if !errors.As(err, &rpcErr) {
+		t.Fatalf("Expected *types.RPCError, got %v", err)
+	}
+	if rpcErr.Code != types.CodeNotFound || rpcErr.Message != "user not found" || rpcErr.Details["id"] != "42" {
+		t.Errorf("Unexpected RPC error %+v", rpcErr)
+	}
+
+	// Plain handler errors have no code
+	_, err = cli.Call("test-errors-service", "fail", nil)
+	if !errors.As(err, &rpcErr) || rpcErr.Code != types.CodeUnknown || rpcErr.Message != "boom" {
+		t.Errorf("Expected unknown error \"boom\", got %v", err)
+	}
+
+	// Errors marked retryable are retried by the client
+	result, err := cli.Call("test-errors-service", "flaky", nil)
+	if err != nil {
+		t.Fatalf("Expected retries to succeed, got %v", err)
+	}
+	if result["ok"] != true || atomic.LoadInt32(&attempts) != 3 {
+		t.Errorf("Expected success on attempt 3, got %v after %d attempts", result, attempts)
+	}
+}
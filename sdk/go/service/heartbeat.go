@@ -24,7 +24,7 @@ func (s *Service) startHeartbeat() error {
 
 	// Start heartbeat goroutine
 	go func() {
-		ticker := time.NewTicker(DefaultHeartbeatInterval)
+		ticker := time.NewTicker(s.heartbeatPeriod())
 		defer ticker.Stop()
 
 		for {
@@ -54,21 +54,48 @@ func (s *Service) sendHeartbeat(subject string) error {
 		Version:   version,
 		Timestamp: time.Now().Unix(),
 	}
+	if s.heartbeatMetrics {
+		snapshot := s.metrics.snapshot()
+		msg.Metrics = &snapshot
+	}
 
 	data, err := json.Marshal(msg)
 	if err != nil {
 		return fmt.Errorf("marshal heartbeat: %w", err)
 	}
 
-	return s.nc.Publish(subject, data)
+	if err := s.conn.Publish(subject, data); err != nil {
+		s.metrics.incr(&s.metrics.heartbeatFailures)
+		return err
+	}
+	s.metrics.incr(&s.metrics.heartbeats)
+	return nil
 }
 
-// SetHeartbeatInterval sets a custom heartbeat interval (for testing)
+// WithHeartbeatInterval sets the interval between heartbeats. The manager
+// marks instances offline after its heartbeat timeout, so keep it well below that.
+func WithHeartbeatInterval(interval time.Duration) ServiceOption {
+	return func(s *Service) error {
+		if interval <= 0 {
+			return fmt.Errorf("heartbeat interval must be positive, got %v", interval)
+		}
+		s.heartbeatInterval = interval
+		return nil
+	}
+}
+
+// SetHeartbeatInterval sets a custom heartbeat interval
 // Note: This must be called before Start()
 func (s *Service) SetHeartbeatInterval(interval time.Duration) {
-	// This can be used to customize the interval for testing purposes
-	// In production, use the default interval
-	_ = interval
+	s.heartbeatInterval = interval
+}
+
+// heartbeatPeriod returns the heartbeat interval in use
+func (s *Service) heartbeatPeriod() time.Duration {
+	if s.heartbeatInterval <= 0 {
+		return DefaultHeartbeatInterval
+	}
+	return s.heartbeatInterval
 }
 
 // GetHeartbeatSubject returns the heartbeat subject for this service
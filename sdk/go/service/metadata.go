@@ -29,6 +29,14 @@ func (s *Service) RegisterMetadata(metadata *types.ServiceMetadata) error {
 			WorkingDir: s.hostInfo.WorkingDir,
 		},
 	}
+	if expiry := s.CertExpiry(); !expiry.IsZero() {
+		msg.InstanceInfo.CertExpiry = expiry.Unix()
+	}
+	s.subMu.Lock()
+	msg.InstanceInfo.Weight = s.weight
+	msg.InstanceInfo.Draining = s.draining
+	msg.InstanceInfo.Broadcast = s.broadcast
+	s.subMu.Unlock()
 
 	data, err := json.Marshal(msg)
 	if err != nil {
@@ -36,7 +44,7 @@ func (s *Service) RegisterMetadata(metadata *types.ServiceMetadata) error {
 	}
 
 	subject := fmt.Sprintf("$LL.register.%s", s.name)
-	if err := s.nc.Publish(subject, data); err != nil {
+	if err := s.conn.Publish(subject, data); err != nil {
 		return fmt.Errorf("publish metadata: %w", err)
 	}
 
@@ -55,6 +63,15 @@ func (s *Service) RegisterMethodWithMetadata(
 	name string,
 	handler RPCHandler,
 	metadata *types.MethodMetadata,
+) error {
+	return s.RegisterMethodWithMetadataCtx(name, withoutContext(handler), metadata)
+}
+
+// RegisterMethodWithMetadataCtx registers a context-aware method with its metadata
+func (s *Service) RegisterMethodWithMetadataCtx(
+	name string,
+	handler RPCHandlerCtx,
+	metadata *types.MethodMetadata,
 ) error {
 	// Store method metadata
 	s.metaMutex.Lock()
@@ -65,7 +82,7 @@ func (s *Service) RegisterMethodWithMetadata(
 	s.metaMutex.Unlock()
 
 	// Register the RPC handler
-	return s.RegisterRPC(name, handler)
+	return s.RegisterRPCCtx(name, handler)
 }
 
 // GetMethodMetadata returns the metadata for a method
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/WQGroup/logger"
+)
+
+// Error types counted by the lightlink_rpc_errors_total metric
+const (
+	ErrorTypeValidation     = "validation"
+	ErrorTypeNotFound       = "not_found"
+	ErrorTypeHandler        = "handler"
+	ErrorTypeDeadline       = "deadline_exceeded"
+	ErrorTypeInvalidRequest = "invalid_request"
+)
+
+// unknownMethod labels calls to unregistered methods, so callers cannot
+// create unbounded label values
+const unknownMethod = "_unknown"
+
+// DefaultLatencyBuckets are the upper bounds in seconds of the latency histogram
+var DefaultLatencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// WithMetricsListener serves the service metrics in Prometheus text format at
+// http://addr/metrics while the service runs. Metrics are collected with or
+// without this option; use MetricsHandler to serve them from your own server.
+func WithMetricsListener(addr string) ServiceOption {
+	return func(s *Service) error {
+		s.metricsAddr = addr
+		return nil
+	}
+}
+
+// WithHeartbeatMetrics includes the metrics snapshot in every heartbeat
+func WithHeartbeatMetrics() ServiceOption {
+	return func(s *Service) error {
+		s.heartbeatMetrics = true
+		return nil
+	}
+}
+
+// methodLabel returns the metrics label of an RPC method, unknownMethod when
+// it is not registered
+func (s *Service) methodLabel(method string) string {
+	s.rpcMutex.RLock()
+	defer s.rpcMutex.RUnlock()
+	if _, ok := s.rpcMap[method]; ok {
+		return method
+	}
+	return unknownMethod
+}
+
+// MetricsSnapshot returns the current metrics of the service
+func (s *Service) MetricsSnapshot() types.MetricsSnapshot {
+	return s.metrics.snapshot()
+}
+
+// MetricsHandler returns an HTTP handler serving the metrics in Prometheus text format
+func (s *Service) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WritePrometheus(w, s.name, s.metrics.snapshot())
+	})
+}
+
+// MetricsAddr returns the address the metrics listener is bound to, "" if not running
+func (s *Service) MetricsAddr() string {
+	if s.metricsListener == nil {
+		return ""
+	}
+	return s.metricsListener.Addr().String()
+}
+
+// startMetricsListener starts the /metrics HTTP listener if configured
+func (s *Service) startMetricsListener() error {
+	if s.metricsAddr == "" {
+		return nil
+	}
+	ln, err := net.Listen("tcp", s.metricsAddr)
+	if err != nil {
+		return fmt.Errorf("listen metrics: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s.MetricsHandler())
+	s.metricsListener = ln
+	s.metricsServer = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func(srv *http.Server) {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Errorf("Metrics listener stopped: %v", err)
+		}
+	}(s.metricsServer)
+	return nil
+}
+
+// stopMetricsListener stops the /metrics HTTP listener
+func (s *Service) stopMetricsListener() {
+	if s.metricsServer == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	s.metricsServer.Shutdown(ctx)
+	s.metricsServer = nil
+	s.metricsListener = nil
+}
+
+// serviceMetrics collects the RPC and connection metrics of a service
+type serviceMetrics struct {
+	mu                sync.Mutex
+	methods           map[string]*methodMetrics
+	heartbeats        uint64
+	heartbeatFailures uint64
+	disconnects       uint64
+	reconnects        uint64
+}
+
+// methodMetrics collects the metrics of one method
+type methodMetrics struct {
+	requests uint64
+	errors   map[string]uint64
+	inFlight int64
+	buckets  []uint64 // per bucket counts, the last one is +Inf
+	count    uint64
+	sum      float64
+}
+
+// newServiceMetrics creates an empty metrics collector
+func newServiceMetrics() *serviceMetrics {
+	return &serviceMetrics{methods: make(map[string]*methodMetrics)}
+}
+
+// methodLocked returns the metrics of method, creating them. Caller must hold mu.
+func (m *serviceMetrics) methodLocked(method string) *methodMetrics {
+	mm, ok := m.methods[method]
+	if !ok {
+		mm = &methodMetrics{
+			errors:  make(map[string]uint64),
+			buckets: make([]uint64, len(DefaultLatencyBuckets)+1),
+		}
+		m.methods[method] = mm
+	}
+	return mm
+}
+
+// begin records the start of a request and returns the function recording its
+// end with the error type, "" for success
+func (m *serviceMetrics) begin(method string) func(errorType string) {
+	start := time.Now()
+	m.mu.Lock()
+	mm := m.methodLocked(method)
+	mm.requests++
+	mm.inFlight++
+	m.mu.Unlock()
+
+	return func(errorType string) {
+		elapsed := time.Since(start).Seconds()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		mm.inFlight--
+		if errorType != "" {
+			mm.errors[errorType]++
+		}
+		i := sort.SearchFloat64s(DefaultLatencyBuckets, elapsed)
+		mm.buckets[i]++
+		mm.count++
+		mm.sum += elapsed
+	}
+}
+
+// reject records a request that failed before reaching a handler
+func (m *serviceMetrics) reject(method, errorType string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mm := m.methodLocked(method)
+	mm.requests++
+	mm.errors[errorType]++
+}
+
+// incr increments one of the connection counters
+func (m *serviceMetrics) incr(counter *uint64) {
+	m.mu.Lock()
+	*counter++
+	m.mu.Unlock()
+}
+
+// snapshot copies the current metrics
+func (m *serviceMetrics) snapshot() types.MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := types.MetricsSnapshot{
+		Methods:           make(map[string]*types.MethodMetrics, len(m.methods)),
+		Heartbeats:        m.heartbeats,
+		HeartbeatFailures: m.heartbeatFailures,
+		Disconnects:       m.disconnects,
+		Reconnects:        m.reconnects,
+	}
+	for name, mm := range m.methods {
+		method := &types.MethodMetrics{
+			Requests: mm.requests,
+			InFlight: mm.inFlight,
+			Latency: types.Histogram{
+				Buckets: make([]types.HistogramBucket, len(DefaultLatencyBuckets)),
+				Count:   mm.count,
+				Sum:     mm.sum,
+			},
+		}
+		if len(mm.errors) > 0 {
+			method.Errors = make(map[string]uint64, len(mm.errors))
+			for errorType, n := range mm.errors {
+				method.Errors[errorType] = n
+			}
+		}
+		var cumulative uint64
+		for i, bound := range DefaultLatencyBuckets {
+			cumulative += mm.buckets[i]
+			method.Latency.Buckets[i] = types.HistogramBucket{UpperBound: bound, Count: cumulative}
+		}
+		snapshot.Methods[name] = method
+	}
+	return snapshot
+}
+
+// WritePrometheus writes a metrics snapshot of service in Prometheus text exposition format
+func WritePrometheus(w io.Writer, service string, snapshot types.MetricsSnapshot) {
+	methods := make([]string, 0, len(snapshot.Methods))
+	for name := range snapshot.Methods {
+		methods = append(methods, name)
+	}
+	sort.Strings(methods)
+
+	svc := `service="` + escapeLabel(service) + `"`
+	labels := func(method string) string {
+		return svc + `,method="` + escapeLabel(method) + `"`
+	}
+
+	writeHeader(w, "lightlink_rpc_requests_total", "counter", "Total RPC requests received.")
+	for _, name := range methods {
+		fmt.Fprintf(w, "lightlink_rpc_requests_total{%s} %d\n", labels(name), snapshot.Methods[name].Requests)
+	}
+
+	writeHeader(w, "lightlink_rpc_errors_total", "counter", "Total failed RPC requests by error type.")
+	for _, name := range methods {
+		errorTypes := make([]string, 0, len(snapshot.Methods[name].Errors))
+		for errorType := range snapshot.Methods[name].Errors {
+			errorTypes = append(errorTypes, errorType)
+		}
+		sort.Strings(errorTypes)
+		for _, errorType := range errorTypes {
+			fmt.Fprintf(w, "lightlink_rpc_errors_total{%s,type=\"%s\"} %d\n",
+				labels(name), escapeLabel(errorType), snapshot.Methods[name].Errors[errorType])
+		}
+	}
+
+	writeHeader(w, "lightlink_rpc_in_flight", "gauge", "RPC requests currently being handled.")
+	for _, name := range methods {
+		fmt.Fprintf(w, "lightlink_rpc_in_flight{%s} %d\n", labels(name), snapshot.Methods[name].InFlight)
+	}
+
+	writeHeader(w, "lightlink_rpc_duration_seconds", "histogram", "RPC handling latency in seconds.")
+	for _, name := range methods {
+		latency := snapshot.Methods[name].Latency
+		for _, bucket := range latency.Buckets {
+			fmt.Fprintf(w, "lightlink_rpc_duration_seconds_bucket{%s,le=\"%s\"} %d\n",
+				labels(name), strconv.FormatFloat(bucket.UpperBound, 'g', -1, 64), bucket.Count)
+		}
+		fmt.Fprintf(w, "lightlink_rpc_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels(name), latency.Count)
+		fmt.Fprintf(w, "lightlink_rpc_duration_seconds_sum{%s} %s\n", labels(name), strconv.FormatFloat(latency.Sum, 'g', -1, 64))
+		fmt.Fprintf(w, "lightlink_rpc_duration_seconds_count{%s} %d\n", labels(name), latency.Count)
+	}
+
+	counters := []struct {
+		name, help string
+		value      uint64
+	}{
+		{"lightlink_heartbeats_total", "Heartbeats sent.", snapshot.Heartbeats},
+		{"lightlink_heartbeat_failures_total", "Heartbeats that could not be sent.", snapshot.HeartbeatFailures},
+		{"lightlink_disconnects_total", "Disconnections from NATS.", snapshot.Disconnects},
+		{"lightlink_reconnects_total", "Reconnections to NATS.", snapshot.Reconnects},
+	}
+	for _, c := range counters {
+		writeHeader(w, c.name, "counter", c.help)
+		fmt.Fprintf(w, "%s{%s} %d\n", c.name, svc, c.value)
+	}
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric
+func writeHeader(w io.Writer, name, metricType, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// labelEscaper escapes label values as required by the text format
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel escapes a label value
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/client"
+	"github.com/LiteHomeLab/light_link/sdk/go/transport"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+func TestServiceMetricsSnapshot(t *testing.T) {
+	m := newServiceMetrics()
+
+	done := m.begin("add")
+	if got := m.snapshot().Methods["add"].InFlight; got != 1 {
+		t.Errorf("Expected 1 in flight, got %d", got)
+	}
+	done("")
+	m.begin("add")(ErrorTypeHandler)
+	m.reject("add", ErrorTypeValidation)
+	m.incr(&m.heartbeats)
+
+	snapshot := m.snapshot()
+	add := snapshot.Methods["add"]
+	if add.Requests != 3 || add.InFlight != 0 {
+		t.Errorf("Expected 3 requests and none in flight, got %+v", add)
+	}
+	if add.Errors[ErrorTypeHandler] != 1 || add.Errors[ErrorTypeValidation] != 1 {
+		t.Errorf("Unexpected errors %v", add.Errors)
+	}
+	if add.Latency.Count != 2 || add.Latency.Buckets[len(add.Latency.Buckets)-1].Count != 2 {
+		t.Errorf("Expected 2 latency observations, got %+v", add.Latency)
+	}
+	if snapshot.Heartbeats != 1 {
+		t.Errorf("Expected 1 heartbeat, got %d", snapshot.Heartbeats)
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	snapshot := types.MetricsSnapshot{
+		Methods: map[string]*types.MethodMetrics{
+			"add": {
+				Requests: 3,
+				Errors:   map[string]uint64{ErrorTypeValidation: 1},
+				Latency: types.Histogram{
+					Buckets: []types.HistogramBucket{{UpperBound: 0.005, Count: 1}, {UpperBound: 0.1, Count: 2}},
+					Count:   2,
+					Sum:     0.05,
+				},
+			},
+		},
+		Reconnects: 2,
+	}
+
+	var buf bytes.Buffer
+	WritePrometheus(&buf, `math"service`, snapshot)
+	out := buf.String()
+
+	expected := []string{
+		"# TYPE lightlink_rpc_requests_total counter",
+		`lightlink_rpc_requests_total{service="math\"service",method="add"} 3`,
+		`lightlink_rpc_errors_total{service="math\"service",method="add",type="validation"} 1`,
+		`lightlink_rpc_in_flight{service="math\"service",method="add"} 0`,
+		"# TYPE lightlink_rpc_duration_seconds histogram",
+		`lightlink_rpc_duration_seconds_bucket{service="math\"service",method="add",le="0.005"} 1`,
+		`lightlink_rpc_duration_seconds_bucket{service="math\"service",method="add",le="+Inf"} 2`,
+		`lightlink_rpc_duration_seconds_sum{service="math\"service",method="add"} 0.05`,
+		`lightlink_rpc_duration_seconds_count{service="math\"service",method="add"} 2`,
+		`lightlink_reconnects_total{service="math\"service"} 2`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Expected line %q in output:\n%s", line, out)
+		}
+	}
+}
+
+func TestMetricsListener(t *testing.T) {
+	svc, err := NewService("test-metrics-service", nats.DefaultURL, WithMetricsListener("127.0.0.1:0"))
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer svc.Stop()
+
+	svc.RegisterRPC("fail", func(args map[string]interface{}) (map[string]interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	svc.RegisterMethodWithMetadata("add", func(args map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{}, nil
+	}, &types.MethodMetadata{
+		Name:   "add",
+		Params: []types.ParameterMetadata{{Name: "a", Type: "number", Required: true}},
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	cli, err := client.NewClient(nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer cli.Close()
+
+	cli.Call("test-metrics-service", "add", map[string]interface{}{"a": "not a number"})
+	cli.Call("test-metrics-service", "fail", nil)
+	cli.Call("test-metrics-service", "missing", nil)
+
+	resp, err := http.Get("http://" + svc.MetricsAddr() + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	out := string(body)
+
+	for _, line := range []string{
+		`lightlink_rpc_errors_total{service="test-metrics-service",method="add",type="validation"} 1`,
+		`lightlink_rpc_errors_total{service="test-metrics-service",method="fail",type="handler"} 1`,
+		`lightlink_rpc_errors_total{service="test-metrics-service",method="_unknown",type="not_found"} 1`,
+		`lightlink_heartbeats_total{service="test-metrics-service"} 1`,
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("Expected %q in metrics:\n%s", line, out)
+		}
+	}
+}
+
+func TestHeartbeatMetrics(t *testing.T) {
+	nc, err := nats.Connect(nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer nc.Close()
+
+	sub, err := nc.SubscribeSync("$LL.heartbeat.test-heartbeat-metrics")
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	nc.Flush()
+
+	svc, err := NewService("test-heartbeat-metrics", nats.DefaultURL, WithHeartbeatMetrics())
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer svc.Stop()
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	msg, err := sub.NextMsg(2 * time.Second)
+	if err != nil {
+		t.Fatalf("Expected heartbeat: %v", err)
+	}
+	var heartbeat types.HeartbeatMessage
+	if err := json.Unmarshal(msg.Data, &heartbeat); err != nil {
+		t.Fatalf("Unmarshal heartbeat failed: %v", err)
+	}
+	if heartbeat.Metrics == nil || heartbeat.Metrics.Methods == nil {
+		t.Errorf("Expected metrics snapshot in heartbeat, got %s", msg.Data)
+	}
+}
+
+func TestExpiredRequestMetricsLabel(t *testing.T) {
+	bus := transport.NewMemoryBus()
+	svc, err := NewService("test-expired-metrics", "", WithServiceTransport(bus.Connect()))
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	svc.RegisterRPC("add", func(args map[string]interface{}) (map[string]interface{}, error) {
+		return nil, nil
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer svc.Stop()
+
+	conn := bus.Connect()
+	defer conn.Close()
+	for _, method := range []string{"add", "caller-chosen-1234"} {
+		data, _ := json.Marshal(types.RPCRequest{ID: method, Method: method})
+		msg := nats.NewMsg("$SRV.test-expired-metrics." + method)
+		msg.Data = data
+		msg.Header.Set(types.HeaderTimeout, "0")
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		_, err := conn.RequestMsgWithContext(ctx, msg)
+		cancel()
+		if err != nil {
+			t.Fatalf("Request %s failed: %v", method, err)
+		}
+	}
+
+	methods := svc.MetricsSnapshot().Methods
+	if methods["add"] == nil || methods["add"].Errors[ErrorTypeDeadline] != 1 {
+		t.Errorf("Expected a deadline error for add, got %+v", methods["add"])
+	}
+	if methods["caller-chosen-1234"] != nil {
+		t.Error("Expected no label for an unregistered method")
+	}
+	if methods[unknownMethod] == nil || methods[unknownMethod].Errors[ErrorTypeDeadline] != 1 {
+		t.Errorf("Expected the unregistered method under %s, got %+v", unknownMethod, methods[unknownMethod])
+	}
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/WQGroup/logger"
+	"github.com/nats-io/nats.go"
+)
+
+// CallInfo describes the RPC call being handled
+type CallInfo struct {
+	Service     string
+	InstanceKey string
+	Method      string
+	Subject     string
+	RequestID   string
+	// Header holds the request headers, nil when the caller sent none
+	Header nats.Header
+}
+
+// Middleware wraps the handling of every RPC call. It may inspect or replace
+// the args, call next to continue the chain, and inspect or replace the result
+// and error. Middleware runs after parameter validation; a middleware that
+// does not call next short-circuits the handler. Stream methods run through
+// the same chain: next returns once the stream ended, with a nil result.
+type Middleware func(ctx context.Context, info *CallInfo, args map[string]interface{}, next RPCHandlerCtx) (map[string]interface{}, error)
+
+// WithMiddleware appends middleware to the chain. The first middleware is
+// the outermost: it runs first and sees the final result.
+func WithMiddleware(middleware ...Middleware) ServiceOption {
+	return func(s *Service) error {
+		s.middleware = append(s.middleware, middleware...)
+		return nil
+	}
+}
+
+// CallInfoFromContext returns the description of the call a handler context belongs to, or nil
+func CallInfoFromContext(ctx context.Context) *CallInfo {
+	info, _ := ctx.Value(callInfoKey).(*CallInfo)
+	return info
+}
+
+// newCallInfo describes an incoming call to the service
+func (s *Service) newCallInfo(msg *nats.Msg, method, requestID string) *CallInfo {
+	return &CallInfo{
+		Service:     s.name,
+		InstanceKey: s.instanceKey,
+		Method:      method,
+		Subject:     msg.Subject,
+		RequestID:   requestID,
+		Header:      msg.Header,
+	}
+}
+
+// invoke calls handler through the middleware chain
+func (s *Service) invoke(ctx context.Context, args map[string]interface{}, handler RPCHandlerCtx) (map[string]interface{}, error) {
+	info := CallInfoFromContext(ctx)
+	if info == nil {
+		info = &CallInfo{Service: s.name, InstanceKey: s.instanceKey}
+	}
+	for i := len(s.middleware) - 1; i >= 0; i-- {
+		mw, next := s.middleware[i], handler
+		handler = func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+			return mw(ctx, info, args, next)
+		}
+	}
+	return handler(ctx, args)
+}
+
+// LoggingMiddleware logs every call with its method, request ID, duration and
+// outcome as structured fields
+func LoggingMiddleware() Middleware {
+	return func(ctx context.Context, info *CallInfo, args map[string]interface{}, next RPCHandlerCtx) (map[string]interface{}, error) {
+		start := time.Now()
+		result, err := next(ctx, args)
+
+		entry := logger.WithFields(map[string]interface{}{
+			"service":     info.Service,
+			"method":      info.Method,
+			"request_id":  info.RequestID,
+			"duration_ms": time.Since(start).Milliseconds(),
+		})
+		if err != nil {
+			entry.WithField("error", err.Error()).Warn("RPC call failed")
+		} else {
+			entry.Info("RPC call handled")
+		}
+		return result, err
+	}
+}
+
+// RecoveryMiddleware turns a panic in the rest of the chain into an error
+// response and logs the stack. Handler panics are always recovered; this
+// also covers panics in middleware registered after it.
+func RecoveryMiddleware() Middleware {
+	return func(ctx context.Context, info *CallInfo, args map[string]interface{}, next RPCHandlerCtx) (result map[string]interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Errorf("Panic handling %s.%s: %v\n%s", info.Service, info.Method, r, debug.Stack())
+				result, err = nil, types.NewRPCError(types.CodeInternal, fmt.Sprintf("internal error: panic handling %s", info.Method))
+			}
+		}()
+		return next(ctx, args)
+	}
+}
+
+// TimingMiddleware reports the duration and error of every call to observe,
+// e.g. to feed a metrics system
+func TimingMiddleware(observe func(info *CallInfo, duration time.Duration, err error)) Middleware {
+	return func(ctx context.Context, info *CallInfo, args map[string]interface{}, next RPCHandlerCtx) (map[string]interface{}, error) {
+		start := time.Now()
+		result, err := next(ctx, args)
+		observe(info, time.Since(start), err)
+		return result, err
+	}
+}
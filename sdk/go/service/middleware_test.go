@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/client"
+	"github.com/LiteHomeLab/light_link/sdk/go/transport"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+func TestMiddlewareChain(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) Middleware {
+		return func(ctx context.Context, info *CallInfo, args map[string]interface{}, next RPCHandlerCtx) (map[string]interface{}, error) {
+			mu.Lock()
+			order = append(order, name+":before")
+			mu.Unlock()
+			result, err := next(ctx, args)
+			mu.Lock()
+			order = append(order, name+":after")
+			mu.Unlock()
+			return result, err
+		}
+	}
+
+	// Auth policy: reject calls without a token header
+	auth := func(ctx context.Context, info *CallInfo, args map[string]interface{}, next RPCHandlerCtx) (map[string]interface{}, error) {
+		if info.Header.Get("X-Token") != "secret" {
+			return nil, errors.New("unauthorized")
+		}
+		return next(ctx, args)
+	}
+
+	var timed []string
+	timing := TimingMiddleware(func(info *CallInfo, duration time.Duration, err error) {
+		mu.Lock()
+		timed = append(timed, info.Method)
+		mu.Unlock()
+	})
+
+	svc, err := NewService("test-middleware-service", nats.DefaultURL,
+		WithMiddleware(RecoveryMiddleware(), LoggingMiddleware(), timing),
+		WithMiddleware(record("outer"), auth, record("inner")))
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer svc.Stop()
+
+	var handlerInfo *CallInfo
+	svc.RegisterRPCCtx("echo", func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+		mu.Lock()
+		order = append(order, "handler")
+		mu.Unlock()
+		handlerInfo = CallInfoFromContext(ctx)
+		return args, nil
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	withToken := func(ctx context.Context, info *client.CallInfo, args map[string]interface{}, invoker client.Invoker) (map[string]interface{}, error) {
+		info.Header.Set("X-Token", "secret")
+		return invoker(ctx, info, args)
+	}
+	authorized, err := client.NewClient(nats.DefaultURL, client.WithInterceptor(withToken))
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer authorized.Close()
+
+	result, err := authorized.Call("test-middleware-service", "echo", map[string]interface{}{"v": "x"})
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if result["v"] != "x" {
+		t.Errorf("Unexpected result %v", result)
+	}
+
+	expected := []string{"outer:before", "inner:before", "handler", "inner:after", "outer:after"}
+	if strings.Join(order, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected order %v, got %v", expected, order)
+	}
+	if handlerInfo == nil || handlerInfo.Method != "echo" || handlerInfo.Service != "test-middleware-service" || handlerInfo.RequestID == "" {
+		t.Errorf("Unexpected call info in handler: %+v", handlerInfo)
+	}
+
+	// Without the header the auth middleware short-circuits the handler
+	anonymous, err := client.NewClient(nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer anonymous.Close()
+
+	order = nil
+	if _, err := anonymous.Call("test-middleware-service", "echo", nil); err == nil || !strings.Contains(err.Error(), "unauthorized") {
+		t.Errorf("Expected unauthorized error, got %v", err)
+	}
+	for _, step := range order {
+		if step == "handler" {
+			t.Error("Expected handler not to run for unauthorized call")
+		}
+	}
+	if len(timed) != 2 {
+		t.Errorf("Expected 2 timed calls, got %v", timed)
+	}
+}
+
+func TestRecoveryMiddleware(t *testing.T) {
+	panicky := func(ctx context.Context, info *CallInfo, args map[string]interface{}, next RPCHandlerCtx) (map[string]interface{}, error) {
+		panic("middleware bug")
+	}
+
+	svc, err := NewService("test-recovery-service", nats.DefaultURL,
+		WithMiddleware(RecoveryMiddleware(), panicky))
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer svc.Stop()
+	svc.RegisterRPC("noop", func(args map[string]interface{}) (map[string]interface{}, error) {
+		return nil, nil
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	cli, err := client.NewClient(nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer cli.Close()
+
+	_, err = cli.Call("test-recovery-service", "noop", nil)
+	if err == nil || !strings.Contains(err.Error(), "internal error") {
+		t.Errorf("Expected internal error from recovered panic, got %v", err)
+	}
+}
+
+func TestMiddlewareGuardsStreams(t *testing.T) {
+	bus := transport.NewMemoryBus()
+	auth := func(ctx context.Context, info *CallInfo, args map[string]interface{}, next RPCHandlerCtx) (map[string]interface{}, error) {
+		if info.Header.Get("Authorization") != "Bearer secret" {
+			return nil, types.NewRPCError(types.CodeUnauthenticated, "unauthorized: "+info.Method)
+		}
+		return next(ctx, args)
+	}
+	svc, err := NewService("test-stream-auth", "", WithServiceTransport(bus.Connect()), WithMiddleware(auth))
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	var started int32
+	svc.RegisterStream("secrets", func(ctx context.Context, args map[string]interface{}, stream *ServerStream) error {
+		atomic.AddInt32(&started, 1)
+		return stream.Send(map[string]interface{}{"secret": 42})
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer svc.Stop()
+
+	cli, err := client.NewClient("", client.WithTransport(bus.Connect()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := cli.CallStream(ctx, "test-stream-auth", "secrets", nil)
+	if err == nil {
+		defer stream.Close()
+		_, err = stream.Recv()
+	}
+	if err == nil || !strings.Contains(err.Error(), "unauthorized: secrets") {
+		t.Errorf("Expected unauthorized error, got %v", err)
+	}
+	if n := atomic.LoadInt32(&started); n != 0 {
+		t.Errorf("Expected the stream handler not to run, ran %d times", n)
+	}
+}
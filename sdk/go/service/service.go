@@ -1,28 +1,41 @@
 package service
 
 import (
-    "encoding/json"
+    "context"
+    "errors"
     "fmt"
+    "log"
+    "net"
+    "net/http"
     "sync"
     "time"
 
     "github.com/nats-io/nats.go"
     "github.com/LiteHomeLab/light_link/sdk/go/client"
+    "github.com/LiteHomeLab/light_link/sdk/go/codec"
+    "github.com/LiteHomeLab/light_link/sdk/go/tracing"
+    "github.com/LiteHomeLab/light_link/sdk/go/transport"
     "github.com/LiteHomeLab/light_link/sdk/go/types"
 )
 
 // RPCHandler RPC handler function type
 type RPCHandler func(args map[string]interface{}) (map[string]interface{}, error)
 
+// RPCHandlerCtx is a context-aware RPC handler.
+// The context carries the request ID and is cancelled when the caller's deadline passes.
+type RPCHandlerCtx func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error)
+
 // ServiceOption is a function that configures a Service
 type ServiceOption func(*Service) error
 
 // Service represents a service
 type Service struct {
 	name           string
-	nc             *nats.Conn
+	nc             *nats.Conn // nil on a transport set with WithServiceTransport
+	conn           transport.Conn
 	tlsConfig      *client.TLSConfig
-	rpcMap         map[string]RPCHandler
+	rpcMap         map[string]RPCHandlerCtx
+	streamMap      map[string]StreamHandler
 	rpcMutex       sync.RWMutex
 	metadata       *types.ServiceMetadata
 	metaMutex      sync.RWMutex
@@ -30,41 +43,69 @@ type Service struct {
 	running        bool
 	heartbeatStop  chan struct{}
 	hostInfo       *client.HostInfo
+	instanceKey    string
 	controlHandler *ControlHandler
+	broadcast      bool
+	weight         int
+	draining       bool
+	rpcStarted     bool
+	rpcSubs        []transport.Subscription
+	subMu          sync.Mutex
+	dedupe         *dedupeCache
+	cancels        *cancelRegistry
+	compression    *compressionConfig
+	tracer         *tracing.Tracer
+	traceReporting bool
+	ownsTracer     bool // created by setupTracing, shut down by Stop
+	middleware     []Middleware
+	connect        client.ConnectOptions
+	certs          *client.CertReloader
+	onCertEvent    func(client.CertEvent)
+
+	metrics          *serviceMetrics
+	metricsAddr      string
+	metricsListener  net.Listener
+	metricsServer    *http.Server
+	heartbeatMetrics bool
+
+	heartbeatInterval time.Duration
+	configMetadata    *types.MetadataConfig
 }
 
 // WithServiceAutoTLS automatically discovers and uses server TLS certificates
-// Searches in ./nats-server directory
+// Searches $LIGHTLINK_CERT_DIR, ./nats-server and its parents, the user config dir and /etc/lightlink
 func WithServiceAutoTLS() ServiceOption {
+	return WithServiceAutoTLSOptions(types.CertDiscoveryOptions{})
+}
+
+// WithServiceAutoTLSOptions discovers the server certificates with custom
+// search directories and file names
+func WithServiceAutoTLSOptions(opts types.CertDiscoveryOptions) ServiceOption {
 	return func(s *Service) error {
-		result, err := types.DiscoverServerCerts()
+		result, err := types.DiscoverCerts(types.ServerCerts, opts)
 		if err != nil {
 			return fmt.Errorf("auto-discover server TLS failed: %w", err)
 		}
-		s.tlsConfig = &client.TLSConfig{
-			CaFile:     result.CaFile,
-			CertFile:   result.CertFile,
-			KeyFile:    result.KeyFile,
-			ServerName: result.ServerName,
-		}
+		s.tlsConfig = client.TLSConfigFrom(types.CertDiscoveryResultToTLSConfig(result))
 		return nil
 	}
 }
 
 // WithServiceClientAutoTLS automatically discovers and uses client TLS certificates
-// Searches in ./client directory
+// Searches $LIGHTLINK_CERT_DIR, ./client and its parents, the user config dir and /etc/lightlink
 func WithServiceClientAutoTLS() ServiceOption {
+	return WithServiceClientAutoTLSOptions(types.CertDiscoveryOptions{})
+}
+
+// WithServiceClientAutoTLSOptions discovers the client certificates with
+// custom search directories and file names
+func WithServiceClientAutoTLSOptions(opts types.CertDiscoveryOptions) ServiceOption {
 	return func(s *Service) error {
-		result, err := types.DiscoverClientCerts()
+		result, err := types.DiscoverCerts(types.ClientCerts, opts)
 		if err != nil {
 			return fmt.Errorf("auto-discover client TLS failed: %w", err)
 		}
-		s.tlsConfig = &client.TLSConfig{
-			CaFile:     result.CaFile,
-			CertFile:   result.CertFile,
-			KeyFile:    result.KeyFile,
-			ServerName: result.ServerName,
-		}
+		s.tlsConfig = client.TLSConfigFrom(types.CertDiscoveryResultToTLSConfig(result))
 		return nil
 	}
 }
@@ -77,13 +118,28 @@ func WithServiceTLS(tlsConfig *client.TLSConfig) ServiceOption {
 	}
 }
 
+// WithServiceTransport uses conn instead of connecting to NATS, e.g. a
+// transport.MemoryBus connection in tests. The URL and the connection and
+// TLS options are ignored.
+func WithServiceTransport(conn transport.Conn) ServiceOption {
+	return func(s *Service) error {
+		s.conn = conn
+		return nil
+	}
+}
+
 // NewService creates a new service with options
 func NewService(name, natsURL string, opts ...ServiceOption) (*Service, error) {
 	service := &Service{
 		name:          name,
-		rpcMap:        make(map[string]RPCHandler),
+		rpcMap:        make(map[string]RPCHandlerCtx),
+		streamMap:     make(map[string]StreamHandler),
 		methodsMeta:   make(map[string]*types.MethodMetadata),
 		heartbeatStop: make(chan struct{}),
+		weight:        DefaultWeight,
+		metrics:       newServiceMetrics(),
+		cancels:       newCancelRegistry(),
+		connect:       client.ConnectOptions{MaxReconnects: client.InfiniteReconnects},
 	}
 
 	// Apply options
@@ -93,46 +149,89 @@ func NewService(name, natsURL string, opts ...ServiceOption) (*Service, error) {
 		}
 	}
 
-	natsOpts := []nats.Option{
-		nats.Name("LightLink Service: "+name),
-		nats.ReconnectWait(2 * time.Second),
-		nats.MaxReconnects(10),
-	}
-
-	// Configure TLS
-	if service.tlsConfig != nil {
-		tlsOpt, err := client.CreateTLSOption(service.tlsConfig)
-		if err != nil {
+	if service.conn == nil {
+		if err := service.connectNATS(natsURL); err != nil {
 			return nil, err
 		}
-		natsOpts = append(natsOpts, tlsOpt)
-	}
-
-	nc, err := nats.Connect(natsURL, natsOpts...)
-	if err != nil {
-		return nil, err
 	}
-
-	service.nc = nc
+	service.setupTracing()
 
 	// Get host info for instance identification
 	hostInfo, err := client.GetHostInfo()
 	if err != nil {
-		nc.Close()
+		if service.certs != nil {
+			service.certs.Stop()
+		}
+		service.conn.Close()
 		return nil, fmt.Errorf("get host info: %w", err)
 	}
 	service.hostInfo = hostInfo
+	service.instanceKey = fmt.Sprintf("%s:%s:%s", hostInfo.IP, normalizeMAC(hostInfo.MAC), name)
 
 	return service, nil
 }
 
+// connectNATS connects to natsURL with the connection and TLS options
+func (s *Service) connectNATS(natsURL string) error {
+	natsOpts, err := s.connect.NATSOptions(client.ConnectHooks{
+		OnDisconnect: func(err error) {
+			s.metrics.incr(&s.metrics.disconnects)
+		},
+		OnReconnect: func(url string) {
+			s.metrics.incr(&s.metrics.reconnects)
+		},
+		OnClosed: func() {
+			log.Printf("[Service] NATS connection of %s closed", s.name)
+		},
+		OnError: func(subject string, err error) {
+			log.Printf("[Service] NATS error on %q: %v", subject, err)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	natsOpts = append(natsOpts, nats.Name("LightLink Service: "+s.name))
+
+	// Configure TLS, reloading rotated certificate files on reconnect
+	if s.tlsConfig != nil {
+		certs, err := client.NewCertReloader(s.tlsConfig, s.handleCertEvent)
+		if err != nil {
+			return err
+		}
+		s.certs = certs
+		natsOpts = append(natsOpts, certs.NATSOption())
+	}
+
+	nc, err := nats.Connect(s.connect.URL(natsURL), natsOpts...)
+	if err != nil {
+		return err
+	}
+	if s.certs != nil {
+		s.certs.Start()
+	}
+
+	s.nc = nc
+	s.conn = transport.NewNATS(nc)
+	return nil
+}
+
 // Name returns the service name
 func (s *Service) Name() string {
     return s.name
 }
 
+// InstanceKey returns the unique key of this service instance (ip:mac:service)
+func (s *Service) InstanceKey() string {
+    return s.instanceKey
+}
+
 // RegisterRPC registers an RPC method
 func (s *Service) RegisterRPC(method string, handler RPCHandler) error {
+    return s.RegisterRPCCtx(method, withoutContext(handler))
+}
+
+// RegisterRPCCtx registers a context-aware RPC method
+func (s *Service) RegisterRPCCtx(method string, handler RPCHandlerCtx) error {
     s.rpcMutex.Lock()
     defer s.rpcMutex.Unlock()
 
@@ -140,6 +239,13 @@ func (s *Service) RegisterRPC(method string, handler RPCHandler) error {
     return nil
 }
 
+// withoutContext adapts a plain RPCHandler to RPCHandlerCtx
+func withoutContext(handler RPCHandler) RPCHandlerCtx {
+    return func(_ context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+        return handler(args)
+    }
+}
+
 // HasRPC checks if an RPC method is registered
 func (s *Service) HasRPC(method string) bool {
     s.rpcMutex.RLock()
@@ -156,10 +262,34 @@ func (s *Service) Start() error {
     }
 
     // Subscribe to all RPC methods
-    subject := fmt.Sprintf("$SRV.%s.>", s.name)
-    _, err := s.nc.Subscribe(subject, s.handleRPC)
+    s.subMu.Lock()
+    err := s.subscribeRPC()
+    s.rpcStarted = err == nil
+    s.subMu.Unlock()
     if err != nil {
-        return fmt.Errorf("subscribe failed: %w", err)
+        return err
+    }
+
+    // Subscribe to instance-addressed RPC calls, also served while draining
+    instanceSubject := types.InstanceRPCSubject(s.instanceKey, ">")
+    if _, err := s.conn.Subscribe(instanceSubject, s.handleRPC); err != nil {
+        return fmt.Errorf("subscribe instance subject: %w", err)
+    }
+
+    // Subscribe to scatter-gather calls, every instance answers these
+    broadcastSubject := types.BroadcastRPCSubject(s.name, ">")
+    if _, err := s.conn.Subscribe(broadcastSubject, s.handleRPC); err != nil {
+        return fmt.Errorf("subscribe broadcast subject: %w", err)
+    }
+
+    // Subscribe to cancel notices of callers that gave up before the response
+    if _, err := s.conn.Subscribe(types.CancelSubjectPrefix+".*", s.handleCancel); err != nil {
+        return fmt.Errorf("subscribe cancel subject: %w", err)
+    }
+
+    // Serve metrics if requested
+    if err := s.startMetricsListener(); err != nil {
+        return err
     }
 
     // Start heartbeat
@@ -168,35 +298,105 @@ func (s *Service) Start() error {
     }
 
     // Start control handler
-    instanceKey := fmt.Sprintf("%s:%s:%s", s.hostInfo.IP, normalizeMAC(s.hostInfo.MAC), s.name)
-    s.controlHandler = NewControlHandler(s.nc, s.name, instanceKey)
+    s.controlHandler = newControlHandler(s.conn, s.name, s.instanceKey)
+    s.controlHandler.SetTrafficController(s)
     if err := s.controlHandler.Subscribe(); err != nil {
         return fmt.Errorf("start control handler: %w", err)
     }
 
+    s.registerConfigMetadata()
+
     s.running = true
     return nil
 }
 
 // handleRPC handles RPC requests
 func (s *Service) handleRPC(msg *nats.Msg) {
-    // Parse request
+    // Parse request in the codec named by its Content-Type header
+    cd, err := requestCodec(msg)
+    if err != nil {
+        s.metrics.reject(unknownMethod, ErrorTypeInvalidRequest)
+        s.respond(msg, errorResponse("", types.NewRPCError(types.CodeInvalidRequest, err.Error())))
+        return
+    }
+    payload, err := requestPayload(msg)
+    if err != nil {
+        s.metrics.reject(unknownMethod, ErrorTypeInvalidRequest)
+        s.respond(msg, errorResponse("", types.NewRPCError(types.CodeInvalidRequest, "invalid request: "+err.Error())))
+        return
+    }
     var request types.RPCRequest
-    if err := json.Unmarshal(msg.Data, &request); err != nil {
-        s.sendError(msg, "", "invalid request: "+err.Error())
+    if err := cd.Unmarshal(payload, &request); err != nil {
+        s.metrics.reject(unknownMethod, ErrorTypeInvalidRequest)
+        s.respond(msg, errorResponse("", types.NewRPCError(types.CodeInvalidRequest, "invalid request: "+err.Error())))
+        return
+    }
+
+    // Streaming calls run in their own goroutine
+    if msg.Header != nil && msg.Header.Get(types.HeaderStream) != "" {
+        s.handleStream(msg, &request)
         return
     }
 
+    // Build handler context from the caller's deadline and trace context
+    ctx, cancel := newRequestContext(msg, request.ID)
+    defer cancel()
+    defer s.cancels.track(RequestIDFromContext(ctx), cancel)()
+
+    ctx, span := s.startServerSpan(ctx, msg, &request)
+    defer span.End()
+    ctx = context.WithValue(ctx, callInfoKey, s.newCallInfo(msg, request.Method, request.ID))
+
+    if ctx.Err() != nil {
+        s.metrics.reject(s.methodLabel(request.Method), ErrorTypeDeadline)
+        span.SetStatus(tracing.StatusError, "deadline exceeded before handling")
+        s.respond(msg, errorResponse(request.ID, types.NewRPCError(types.CodeDeadlineExceeded, "deadline exceeded before handling: "+request.Method)))
+        return
+    }
+
+    // Answer retried requests from the dedupe cache
+    if s.dedupe != nil {
+        if key := idempotencyKey(msg, &request); key != "" {
+            s.handleDeduped(ctx, msg, &request, key)
+            return
+        }
+    }
+
+    s.respond(msg, s.processRPC(ctx, &request))
+}
+
+// processRPC dispatches a request to its handler and records the outcome on the server span
+func (s *Service) processRPC(ctx context.Context, request *types.RPCRequest) types.RPCResponse {
+    response := s.dispatchRPC(ctx, request)
+    if response.Success {
+        tracing.SpanFromContext(ctx).SetStatus(tracing.StatusOK, "")
+    } else {
+        tracing.SpanFromContext(ctx).SetStatus(tracing.StatusError, response.Error)
+    }
+    return response
+}
+
+// dispatchRPC dispatches a request to its handler and builds the response
+func (s *Service) dispatchRPC(ctx context.Context, request *types.RPCRequest) types.RPCResponse {
     // Find handler
     s.rpcMutex.RLock()
     handler, exists := s.rpcMap[request.Method]
     s.rpcMutex.RUnlock()
 
     if !exists {
-        s.sendError(msg, request.ID, "method not found: "+request.Method)
-        return
+        s.metrics.reject(unknownMethod, ErrorTypeNotFound)
+        return errorResponse(request.ID, types.NewRPCError(types.CodeMethodNotFound, "method not found: "+request.Method))
     }
 
+    done := s.metrics.begin(request.Method)
+    response, errorType := s.callMethod(ctx, request, handler)
+    done(errorType)
+    return response
+}
+
+// callMethod validates the arguments and calls the handler. It returns the
+// response and the error type counted by the metrics, "" on success.
+func (s *Service) callMethod(ctx context.Context, request *types.RPCRequest, handler RPCHandlerCtx) (types.RPCResponse, string) {
     // Get method metadata for validation
     s.metaMutex.RLock()
     methodMeta, hasMeta := s.methodsMeta[request.Method]
@@ -206,78 +406,73 @@ func (s *Service) handleRPC(msg *nats.Msg) {
     if hasMeta {
         validator := NewValidator(methodMeta)
         if err := validator.Validate(request.Args); err != nil {
-            // Handle ValidationError specifically
-            if validationErr, ok := err.(*types.ValidationError); ok {
-                s.sendValidationError(msg, request.ID, validationErr)
-                return
+            // Validation errors carry their details in the response
+            if _, ok := err.(*types.ValidationError); !ok {
+                err = types.NewRPCError(types.CodeValidation, err.Error())
             }
-            s.sendError(msg, request.ID, err.Error())
-            return
+            return errorResponse(request.ID, err), ErrorTypeValidation
         }
     }
 
-    // Call handler with panic recovery for type assertion errors
-    result, err := s.callHandlerSafely(handler, request.Args, request.Method, hasMeta, methodMeta)
+    // Call handler through the middleware chain, with panic recovery for type assertion errors
+    result, err := s.invoke(ctx, request.Args, func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+        return s.callHandlerSafely(ctx, handler, args, request.Method, hasMeta, methodMeta)
+    })
     if err != nil {
         // Check if it's a validation error from panic recovery
-        if validationErr, ok := err.(*types.ValidationError); ok {
-            s.sendValidationError(msg, request.ID, validationErr)
-            return
+        if _, ok := err.(*types.ValidationError); ok {
+            return errorResponse(request.ID, err), ErrorTypeValidation
         }
-        s.sendError(msg, request.ID, err.Error())
-        return
+        if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+            if types.ToRPCError(err).Code == types.CodeUnknown {
+                err = types.NewRPCError(types.CodeDeadlineExceeded, err.Error())
+            }
+            return errorResponse(request.ID, err), ErrorTypeDeadline
+        }
+        return errorResponse(request.ID, err), ErrorTypeHandler
     }
 
-    // Send response
-    response := types.RPCResponse{
+    return types.RPCResponse{
         ID:      request.ID,
         Success: true,
         Result:  result,
-    }
-
-    respData, _ := json.Marshal(response)
-    msg.Respond(respData)
+    }, ""
 }
 
-// sendError sends an error response
-func (s *Service) sendError(msg *nats.Msg, requestID, errMsg string) {
-    response := types.RPCResponse{
-        ID:      requestID,
-        Success: false,
-        Error:   errMsg,
-    }
-    respData, _ := json.Marshal(response)
-    msg.Respond(respData)
+// respond encodes a response in the caller's codec and sends it
+func (s *Service) respond(msg *nats.Msg, response types.RPCResponse) {
+    respData, _ := responseCodec(msg).Marshal(response)
+    s.reply(msg, respData)
 }
 
-// sendValidationError sends a validation error response with structured error info
-func (s *Service) sendValidationError(msg *nats.Msg, requestID string, err *types.ValidationError) {
-    // Build structured error response
-    errorDetail := map[string]interface{}{
-        "type":           types.ValidationErrorType,
-        "parameter_name": err.ParameterName,
-        "expected_type":  err.ExpectedType,
-        "actual_type":    err.ActualType,
-        "message":        err.Message,
-    }
-    if err.ActualValue != nil {
-        errorDetail["actual_value"] = err.ActualValue
+// reply sends an encoded response tagged with the instance key and content type,
+// compressing it if the caller accepts compressed responses
+func (s *Service) reply(msg *nats.Msg, respData []byte) {
+    if msg.Reply == "" {
+        return
     }
-
-    response := types.RPCResponse{
-        ID:      requestID,
-        Success: false,
-        Error:   err.Message,
-        Result:  errorDetail,
+    resp := nats.NewMsg(msg.Reply)
+    resp.Header.Set(types.HeaderInstanceKey, s.instanceKey)
+    resp.Header.Set(types.HeaderContentType, responseCodec(msg).ContentType())
+    resp.Header.Set(types.HeaderAcceptEncoding, codec.AcceptEncoding())
+
+    data, encoding := codec.CompressPayload(respData, s.responseEncoding(msg), s.compressionThreshold())
+    resp.Data = data
+    if encoding != "" {
+        resp.Header.Set(types.HeaderContentEncoding, encoding)
     }
+    s.conn.PublishMsg(resp)
+}
 
-    respData, _ := json.Marshal(response)
-    msg.Respond(respData)
+// errorResponse builds an error response carrying the coded form of err
+func errorResponse(requestID string, err error) types.RPCResponse {
+    return types.NewErrorResponse(requestID, err)
 }
 
 // callHandlerSafely calls the handler with panic recovery
 func (s *Service) callHandlerSafely(
-    handler RPCHandler,
+    ctx context.Context,
+    handler RPCHandlerCtx,
     args map[string]interface{},
     methodName string,
     hasMeta bool,
@@ -289,7 +484,7 @@ func (s *Service) callHandlerSafely(
         }
     }()
 
-    result, err = handler(args)
+    result, err = handler(ctx, args)
     return
 }
 
@@ -342,7 +537,17 @@ func (s *Service) Stop() error {
         s.controlHandler = nil
     }
 
-    s.nc.Close()
+    s.subMu.Lock()
+    s.rpcStarted = false
+    s.rpcSubs = nil
+    s.subMu.Unlock()
+
+    s.stopMetricsListener()
+    s.flushTracing()
+    if s.certs != nil {
+        s.certs.Stop()
+    }
+    s.conn.Close()
     s.running = false
     return nil
 }
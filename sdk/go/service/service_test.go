@@ -1,8 +1,12 @@
 package service
 
 import (
+    "context"
     "testing"
     "time"
+
+    "github.com/LiteHomeLab/light_link/sdk/go/client"
+    "github.com/LiteHomeLab/light_link/sdk/go/transport"
 )
 
 func TestNewService(t *testing.T) {
@@ -58,3 +62,122 @@ func TestStartStop(t *testing.T) {
         t.Fatalf("Stop failed: %v", err)
     }
 }
+
+func TestRegisterRPCCtxPropagatesDeadline(t *testing.T) {
+    svc, err := NewService("test-ctx-service", "nats://localhost:4222")
+    if err != nil {
+        t.Skip("Need running NATS server:", err)
+    }
+    defer svc.Stop()
+
+    handlerDone := make(chan error, 1)
+    err = svc.RegisterRPCCtx("echoDeadline", func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+        _, hasDeadline := ctx.Deadline()
+        return map[string]interface{}{
+            "has_deadline": hasDeadline,
+            "request_id":   RequestIDFromContext(ctx),
+        }, nil
+    })
+    if err != nil {
+        t.Fatalf("RegisterRPCCtx failed: %v", err)
+    }
+    err = svc.RegisterRPCCtx("wait", func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+        select {
+        case <-ctx.Done():
+            handlerDone <- ctx.Err()
+        case <-time.After(5 * time.Second):
+            handlerDone <- nil
+        }
+        return nil, ctx.Err()
+    })
+    if err != nil {
+        t.Fatalf("RegisterRPCCtx failed: %v", err)
+    }
+    if err := svc.Start(); err != nil {
+        t.Fatalf("Start failed: %v", err)
+    }
+
+    cli, err := client.NewClient("nats://localhost:4222")
+    if err != nil {
+        t.Skip("Need running NATS server:", err)
+    }
+    defer cli.Close()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+    defer cancel()
+    result, err := cli.CallContext(ctx, "test-ctx-service", "echoDeadline", nil)
+    if err != nil {
+        t.Fatalf("CallContext failed: %v", err)
+    }
+    if result["has_deadline"] != true {
+        t.Error("Expected handler context to carry the caller's deadline")
+    }
+    if id, _ := result["request_id"].(string); id == "" {
+        t.Error("Expected handler context to carry the request ID")
+    }
+
+    shortCtx, shortCancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+    defer shortCancel()
+    if _, err := cli.CallContext(shortCtx, "test-ctx-service", "wait", nil); err == nil {
+        t.Error("Expected CallContext to fail after the deadline")
+    }
+
+    select {
+    case err := <-handlerDone:
+        if err != context.DeadlineExceeded {
+            t.Errorf("Expected handler context to expire, got %v", err)
+        }
+    case <-time.After(3 * time.Second):
+        t.Error("Handler did not observe the caller's deadline")
+    }
+}
+
+func TestCallerCancellationCancelsHandler(t *testing.T) {
+    bus := transport.NewMemoryBus()
+    svc, err := NewService("cancel-service", "", WithServiceTransport(bus.Connect()))
+    if err != nil {
+        t.Fatalf("NewService failed: %v", err)
+    }
+    defer svc.Stop()
+
+    started := make(chan struct{})
+    handlerDone := make(chan error, 1)
+    svc.RegisterRPCCtx("wait", func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+        close(started)
+        select {
+        case <-ctx.Done():
+            handlerDone <- ctx.Err()
+        case <-time.After(3 * time.Second):
+            handlerDone <- nil
+        }
+        return nil, ctx.Err()
+    })
+    if err := svc.Start(); err != nil {
+        t.Fatalf("Start failed: %v", err)
+    }
+
+    cli, err := client.NewClient("", client.WithTransport(bus.Connect()))
+    if err != nil {
+        t.Fatalf("NewClient failed: %v", err)
+    }
+    defer cli.Close()
+
+    // A context without deadline only reaches the handler through the cancel notice
+    ctx, cancel := context.WithCancel(context.Background())
+    go func() {
+        <-started
+        cancel()
+    }()
+    if _, err := cli.CallContext(ctx, "cancel-service", "wait", nil); err == nil {
+        t.Error("Expected CallContext to fail after cancellation")
+    }
+
+    select {
+    case err := <-handlerDone:
+        if err != context.Canceled {
+            t.Errorf("Expected handler context to be cancelled, got %v", err)
+        }
+    case <-time.After(2 * time.Second):
+        t.Error("Handler did not observe the caller's cancellation")
+    }
+}
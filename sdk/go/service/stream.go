@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/codec"
+	"github.com/LiteHomeLab/light_link/sdk/go/transport"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// DefaultStreamWindow is the credit window used when the caller sends none
+	DefaultStreamWindow = 32
+	// DefaultStreamIdleTimeout is how long Send waits for credit before giving up
+	DefaultStreamIdleTimeout = 30 * time.Second
+)
+
+var (
+	// ErrStreamClosed is returned by Send after the caller closed the stream
+	ErrStreamClosed = errors.New("stream closed by caller")
+	// ErrStreamStalled is returned by Send when the caller stopped granting credit
+	ErrStreamStalled = errors.New("stream consumer stalled")
+)
+
+// StreamHandler handles a server-streaming RPC method.
+// Results are emitted in order with stream.Send. Returning ends the stream;
+// a non-nil error is delivered to the caller after the results already sent.
+type StreamHandler func(ctx context.Context, args map[string]interface{}, stream *ServerStream) error
+
+// RegisterStream registers a server-streaming RPC method, called with client.CallStream
+func (s *Service) RegisterStream(method string, handler StreamHandler) error {
+	s.rpcMutex.Lock()
+	defer s.rpcMutex.Unlock()
+
+	s.streamMap[method] = handler
+	return nil
+}
+
+// ServerStream sends the results of a streaming call.
+// The caller grants credit for a window of frames and tops it up as it consumes
+// them, so Send blocks instead of flooding a slow consumer.
+type ServerStream struct {
+	conn        transport.Conn
+	reply       string
+	ackSubject  string
+	requestID   string
+	instanceKey string
+	codec       codec.Codec
+	encoding    string // compression negotiated with the caller
+	threshold   int
+	idleTimeout time.Duration
+
+	ctx context.Context
+	seq int // only used by the handler goroutine
+
+	mu       sync.Mutex
+	credits  int
+	closed   bool
+	notify   chan struct{}
+	cancelFn context.CancelFunc
+}
+
+// Send emits one result. It blocks while the caller has no credit left and
+// fails once the caller closed the stream or its deadline passed.
+func (st *ServerStream) Send(item map[string]interface{}) error {
+	if err := st.acquireCredit(); err != nil {
+		return err
+	}
+
+	data, err := st.codec.Marshal(types.RPCResponse{
+		ID:      st.requestID,
+		Success: true,
+		Result:  item,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal stream item: %w", err)
+	}
+
+	st.seq++
+	return st.publish(st.seq, data, false)
+}
+
+// acquireCredit takes one credit, waiting for the caller to grant more if needed
+func (st *ServerStream) acquireCredit() error {
+	timer := time.NewTimer(st.idleTimeout)
+	defer timer.Stop()
+
+	for {
+		st.mu.Lock()
+		if st.closed {
+			st.mu.Unlock()
+			return ErrStreamClosed
+		}
+		if st.credits > 0 {
+			st.credits--
+			st.mu.Unlock()
+			return nil
+		}
+		st.mu.Unlock()
+
+		select {
+		case <-st.notify:
+		case <-st.ctx.Done():
+			return st.ctx.Err()
+		case <-timer.C:
+			return ErrStreamStalled
+		}
+	}
+}
+
+// handleAck processes credit and cancellation messages from the caller
+func (st *ServerStream) handleAck(msg *nats.Msg) {
+	if msg.Header == nil {
+		return
+	}
+
+	st.mu.Lock()
+	if msg.Header.Get(types.HeaderStreamCancel) != "" {
+		st.closed = true
+		st.cancelFn()
+	} else if n, err := strconv.Atoi(msg.Header.Get(types.HeaderStreamCredit)); err == nil && n > 0 {
+		st.credits += n
+	}
+	st.mu.Unlock()
+
+	select {
+	case st.notify <- struct{}{}:
+	default:
+	}
+}
+
+// end sends the end-of-stream frame carrying the handler error, if any
+func (st *ServerStream) end(err error) {
+	st.publish(st.seq+1, st.endData(err), true)
+}
+
+// reject ends a stream that could not be opened, in place of the open frame
+func (st *ServerStream) reject(err error) {
+	st.publish(0, st.endData(err), true)
+}
+
+// endData encodes the payload of an end-of-stream frame
+func (st *ServerStream) endData(err error) []byte {
+	response := types.RPCResponse{ID: st.requestID, Success: true}
+	if err != nil {
+		response = errorResponse(st.requestID, err)
+	}
+	data, _ := st.codec.Marshal(response)
+	return data
+}
+
+// publish sends one stream frame to the caller
+func (st *ServerStream) publish(seq int, data []byte, end bool) error {
+	msg := nats.NewMsg(st.reply)
+	data, encoding := codec.CompressPayload(data, st.encoding, st.threshold)
+	msg.Data = data
+	if encoding != "" {
+		msg.Header.Set(types.HeaderContentEncoding, encoding)
+	}
+	msg.Header.Set(types.HeaderStreamSeq, strconv.Itoa(seq))
+	msg.Header.Set(types.HeaderInstanceKey, st.instanceKey)
+	msg.Header.Set(types.HeaderContentType, st.codec.ContentType())
+	if st.ackSubject != "" {
+		msg.Header.Set(types.HeaderStreamAck, st.ackSubject)
+	}
+	if end {
+		msg.Header.Set(types.HeaderStreamEnd, "1")
+	}
+	return st.conn.PublishMsg(msg)
+}
+
+// handleStream starts a streaming call: it sends the open frame carrying the
+// ack subject and runs the handler in its own goroutine
+func (s *Service) handleStream(msg *nats.Msg, request *types.RPCRequest) {
+	if msg.Reply == "" {
+		return
+	}
+
+	st := &ServerStream{
+		conn:        s.conn,
+		reply:       msg.Reply,
+		requestID:   request.ID,
+		instanceKey: s.instanceKey,
+		codec:       responseCodec(msg),
+		encoding:    s.responseEncoding(msg),
+		threshold:   s.compressionThreshold(),
+		idleTimeout: DefaultStreamIdleTimeout,
+		credits:     DefaultStreamWindow,
+		notify:      make(chan struct{}, 1),
+	}
+	if n, err := strconv.Atoi(msg.Header.Get(types.HeaderStream)); err == nil && n > 0 {
+		st.credits = n
+	}
+
+	s.rpcMutex.RLock()
+	handler, exists := s.streamMap[request.Method]
+	s.rpcMutex.RUnlock()
+
+	if !exists {
+		s.metrics.reject(unknownMethod, ErrorTypeNotFound)
+		st.reject(types.NewRPCError(types.CodeMethodNotFound, "stream method not found: "+request.Method))
+		return
+	}
+
+	// Validate parameters if metadata exists
+	s.metaMutex.RLock()
+	methodMeta, hasMeta := s.methodsMeta[request.Method]
+	s.metaMutex.RUnlock()
+	if hasMeta {
+		if err := NewValidator(methodMeta).Validate(request.Args); err != nil {
+			s.metrics.reject(request.Method, ErrorTypeValidation)
+			st.reject(err)
+			return
+		}
+	}
+
+	ctx, cancel := newRequestContext(msg, request.ID)
+	ctx, span := s.startServerSpan(ctx, msg, request)
+	ctx = context.WithValue(ctx, callInfoKey, s.newCallInfo(msg, request.Method, request.ID))
+	st.ctx, st.cancelFn = ctx, cancel
+
+	st.ackSubject = s.conn.NewRespInbox()
+	ackSub, err := s.conn.Subscribe(st.ackSubject, st.handleAck)
+	if err != nil {
+		cancel()
+		st.ackSubject = ""
+		err = fmt.Errorf("subscribe stream ack subject: %w", err)
+		span.SetError(err)
+		span.End()
+		st.reject(err)
+		return
+	}
+
+	// Open frame: tells the caller where to send credit
+	if err := st.publish(0, nil, false); err != nil {
+		cancel()
+		ackSub.Unsubscribe()
+		span.SetError(err)
+		span.End()
+		return
+	}
+
+	go func() {
+		defer cancel()
+		defer ackSub.Unsubscribe()
+
+		done := s.metrics.begin(request.Method)
+		// The middleware chain wraps the whole stream; it sees a nil result
+		// and the error the stream ends with
+		_, err := s.invoke(ctx, request.Args, func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+			return nil, runStreamHandler(ctx, handler, args, st)
+		})
+		done(streamErrorType(ctx, err))
+		st.mu.Lock()
+		closed := st.closed
+		st.mu.Unlock()
+		if !closed {
+			st.end(err)
+		}
+		span.SetAttribute("rpc.stream_items", strconv.Itoa(st.seq))
+		span.SetError(err)
+		span.End()
+	}()
+}
+
+// streamErrorType returns the error type counted by the metrics for a stream ending with err
+func streamErrorType(ctx context.Context, err error) string {
+	switch {
+	case err == nil, errors.Is(err, ErrStreamClosed):
+		return ""
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return ErrorTypeDeadline
+	default:
+		return ErrorTypeHandler
+	}
+}
+
+// runStreamHandler calls a stream handler, turning a panic into an error
+func runStreamHandler(ctx context.Context, handler StreamHandler, args map[string]interface{}, st *ServerStream) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = types.NewRPCError(types.CodeInternal, fmt.Sprintf("stream handler panic: %v", r))
+		}
+	}()
+	return handler(ctx, args, st)
+}
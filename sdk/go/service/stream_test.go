@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/client"
+	"github.com/LiteHomeLab/light_link/sdk/go/transport"
+	"github.com/nats-io/nats.go"
+)
+
+// startStreamService starts a service with a "count" stream method emitting n results
+func startStreamService(t *testing.T, name string, sent *int32) *Service {
+	t.Helper()
+	svc, err := NewService(name, nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	svc.RegisterStream("count", func(ctx context.Context, args map[string]interface{}, stream *ServerStream) error {
+		n := int(args["n"].(float64))
+		for i := 1; i <= n; i++ {
+			if err := stream.Send(map[string]interface{}{"i": i}); err != nil {
+				return err
+			}
+			atomic.AddInt32(sent, 1)
+		}
+		if fail, _ := args["fail"].(bool); fail {
+			return errors.New("report generation failed")
+		}
+		return nil
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { svc.Stop() })
+	time.Sleep(100 * time.Millisecond)
+	return svc
+}
+
+func TestStreamDeliversInOrderWithFlowControl(t *testing.T) {
+	var sent int32
+	startStreamService(t, "test-stream-service", &sent)
+
+	const window = 4
+	cli, err := client.NewClient(nats.DefaultURL, client.WithStreamWindow(window))
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stream, err := cli.CallStream(ctx, "test-stream-service", "count", map[string]interface{}{"n": 20})
+	if err != nil {
+		t.Fatalf("CallStream failed: %v", err)
+	}
+	defer stream.Close()
+
+	for i := 1; ; i++ {
+		// A slow consumer must never have more than a window of results in flight
+		time.Sleep(5 * time.Millisecond)
+		if ahead := int(atomic.LoadInt32(&sent)) - (i - 1); ahead > window {
+			t.Fatalf("Service ran %d results ahead of a window of %d", ahead, window)
+		}
+
+		item, err := stream.Recv()
+		if err == io.EOF {
+			if i != 21 {
+				t.Fatalf("Expected 20 results, got %d", i-1)
+			}
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		if int(item["i"].(float64)) != i {
+			t.Fatalf("Expected result %d, got %v", i, item["i"])
+		}
+	}
+}
+
+func TestStreamPropagatesError(t *testing.T) {
+	var sent int32
+	startStreamService(t, "test-stream-error-service", &sent)
+
+	cli, err := client.NewClient(nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := cli.CallStream(ctx, "test-stream-error-service", "count", map[string]interface{}{"n": 2, "fail": true})
+	if err != nil {
+		t.Fatalf("CallStream failed: %v", err)
+	}
+	defer stream.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := stream.Recv(); err != nil {
+			t.Fatalf("Recv %d failed: %v", i, err)
+		}
+	}
+	if _, err := stream.Recv(); err == nil || !strings.Contains(err.Error(), "report generation failed") {
+		t.Errorf("Expected handler error, got %v", err)
+	}
+
+	if _, err := cli.CallStream(ctx, "test-stream-error-service", "missing", nil); err == nil {
+		t.Error("Expected error for unknown stream method")
+	}
+}
+
+func TestStreamCloseStopsHandler(t *testing.T) {
+	svc, err := NewService("test-stream-close-service", nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer svc.Stop()
+
+	stopped := make(chan error, 1)
+	svc.RegisterStream("tail", func(ctx context.Context, args map[string]interface{}, stream *ServerStream) error {
+		for {
+			if err := stream.Send(map[string]interface{}{"line": "log"}); err != nil {
+				stopped <- err
+				return err
+			}
+		}
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	cli, err := client.NewClient(nats.DefaultURL, client.WithStreamWindow(2))
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer cli.Close()
+
+	stream, err := cli.CallStream(context.Background(), "test-stream-close-service", "tail", nil)
+	if err != nil {
+		t.Fatalf("CallStream failed: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Recv failed: %v", err)
+	}
+	stream.Close()
+
+	select {
+	case err := <-stopped:
+		if !errors.Is(err, ErrStreamClosed) && !errors.Is(err, context.Canceled) {
+			t.Errorf("Unexpected Send error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Handler kept running after the stream was closed")
+	}
+
+	if _, err := stream.Recv(); !errors.Is(err, client.ErrStreamClosed) {
+		t.Errorf("Expected ErrStreamClosed after Close, got %v", err)
+	}
+}
+
+func TestStreamRecvIdleTimeout(t *testing.T) {
+	bus := transport.NewMemoryBus()
+	svc, err := NewService("test-stream-idle-service", "", WithServiceTransport(bus.Connect()))
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	cancelled := make(chan struct{})
+	svc.RegisterStream("hang", func(ctx context.Context, args map[string]interface{}, stream *ServerStream) error {
+		if err := stream.Send(map[string]interface{}{"i": 1}); err != nil {
+			return err
+		}
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer svc.Stop()
+
+	cli, err := client.NewClient("", client.WithTransport(bus.Connect()), client.WithStreamIdleTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer cli.Close()
+
+	// No deadline on the context, only the idle timeout ends the wait
+	stream, err := cli.CallStream(context.Background(), "test-stream-idle-service", "hang", nil)
+	if err != nil {
+		t.Fatalf("CallStream failed: %v", err)
+	}
+	defer stream.Close()
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Recv failed: %v", err)
+	}
+
+	start := time.Now()
+	if _, err := stream.Recv(); !errors.Is(err, client.ErrStreamStalled) {
+		t.Fatalf("Expected ErrStreamStalled, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Expected Recv to give up after the idle timeout, took %v", time.Since(start))
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Error("Handler not cancelled after the stream stalled")
+	}
+}
+
+func TestStreamCloseWhileReceiving(t *testing.T) {
+	bus := transport.NewMemoryBus()
+	svc, err := NewService("test-stream-close-recv", "", WithServiceTransport(bus.Connect()))
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	cancelled := make(chan struct{})
+	svc.RegisterStream("hang", func(ctx context.Context, args map[string]interface{}, stream *ServerStream) error {
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer svc.Stop()
+
+	cli, err := client.NewClient("", client.WithTransport(bus.Connect()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer cli.Close()
+
+	stream, err := cli.CallStream(context.Background(), "test-stream-close-recv", "hang", nil)
+	if err != nil {
+		t.Fatalf("CallStream failed: %v", err)
+	}
+	received := make(chan error, 1)
+	go func() {
+		_, err := stream.Recv()
+		received <- err
+	}()
+
+	// Close from another goroutine is the way to stop a blocked Recv
+	time.Sleep(20 * time.Millisecond)
+	stream.Close()
+	select {
+	case err := <-received:
+		if !errors.Is(err, client.ErrStreamClosed) {
+			t.Errorf("Expected ErrStreamClosed, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Recv still blocked after Close")
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Error("Handler not cancelled after Close")
+	}
+}
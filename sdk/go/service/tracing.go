@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/tracing"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+// WithServiceTracer records a server span for every handled RPC call with tracer.
+// Handlers reach the span with tracing.SpanFromContext; calls they make with
+// the handler context become its children.
+func WithServiceTracer(tracer *tracing.Tracer) ServiceOption {
+	return func(s *Service) error {
+		s.tracer = tracer
+		return nil
+	}
+}
+
+// WithServiceTraceReporting reports spans to the manager over NATS, creating a
+// tracer named after the service if WithServiceTracer was not used
+func WithServiceTraceReporting() ServiceOption {
+	return func(s *Service) error {
+		s.traceReporting = true
+		return nil
+	}
+}
+
+// Tracer returns the tracer of the service, or nil if tracing is disabled
+func (s *Service) Tracer() *tracing.Tracer {
+	return s.tracer
+}
+
+// setupTracing attaches the NATS span exporter once connected
+func (s *Service) setupTracing() {
+	if !s.traceReporting {
+		return
+	}
+	if s.tracer == nil {
+		s.tracer = tracing.NewTracer(s.name)
+		s.ownsTracer = true
+	}
+	s.tracer.AddExporter(tracing.NewNATSExporter(s.conn))
+}
+
+// flushTracing exports the remaining spans before the connection closes.
+// A tracer created by the service is shut down; one passed with
+// WithServiceTracer belongs to the caller and is only flushed.
+func (s *Service) flushTracing() {
+	if s.tracer == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if s.ownsTracer {
+		s.tracer.Shutdown(ctx)
+		return
+	}
+	s.tracer.Flush(ctx)
+}
+
+// startServerSpan starts the server span of an incoming call
+func (s *Service) startServerSpan(ctx context.Context, msg *nats.Msg, request *types.RPCRequest) (context.Context, *tracing.Span) {
+	ctx, span := s.tracer.Start(ctx, msg.Subject, tracing.SpanKindServer)
+	span.SetAttribute("rpc.method", request.Method)
+	span.SetAttribute("rpc.request_id", request.ID)
+	span.SetAttribute("instance.key", s.instanceKey)
+	return ctx, span
+}
+
+// remoteSpanContext returns the caller's trace context from the traceparent header
+func remoteSpanContext(msg *nats.Msg) (tracing.SpanContext, bool) {
+	if msg.Header == nil {
+		return tracing.SpanContext{}, false
+	}
+	value := msg.Header.Get(types.HeaderTraceparent)
+	if value == "" {
+		return tracing.SpanContext{}, false
+	}
+	sc, err := tracing.ParseTraceparent(value)
+	if err != nil {
+		return tracing.SpanContext{}, false
+	}
+	return sc, true
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/client"
+	"github.com/LiteHomeLab/light_link/sdk/go/tracing"
+	"github.com/LiteHomeLab/light_link/sdk/go/transport"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+// spanRecorder collects exported spans
+type spanRecorder struct {
+	mu       sync.Mutex
+	spans    []tracing.SpanData
+	shutdown bool
+}
+
+func (r *spanRecorder) ExportSpans(ctx context.Context, spans []tracing.SpanData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+func (r *spanRecorder) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.shutdown = true
+	return nil
+}
+
+func (r *spanRecorder) find(name string) (tracing.SpanData, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, span := range r.spans {
+		if span.Name == name {
+			return span, true
+		}
+	}
+	return tracing.SpanData{}, false
+}
+
+func TestTracePropagationAcrossHops(t *testing.T) {
+	recorder := &spanRecorder{}
+
+	backend, err := NewService("test-trace-backend", nats.DefaultURL,
+		WithServiceTracer(tracing.NewTracer("test-trace-backend", recorder)))
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer backend.Stop()
+	backend.RegisterRPC("add", func(args map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{"sum": args["a"].(float64) + args["b"].(float64)}, nil
+	})
+
+	// The frontend calls the backend with a client that has no tracer of its own;
+	// the handler context alone carries the trace to the next hop
+	inner, err := client.NewClient(nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer inner.Close()
+
+	frontend, err := NewService("test-trace-frontend", nats.DefaultURL,
+		WithServiceTracer(tracing.NewTracer("test-trace-frontend", recorder)))
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer frontend.Stop()
+
+	var handlerTraceID string
+	frontend.RegisterRPCCtx("sum", func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+		handlerTraceID = tracing.SpanFromContext(ctx).TraceID()
+		return inner.CallContext(ctx, "test-trace-backend", "add", args)
+	})
+
+	if err := backend.Start(); err != nil {
+		t.Fatalf("Start backend failed: %v", err)
+	}
+	if err := frontend.Start(); err != nil {
+		t.Fatalf("Start frontend failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	cli, err := client.NewClient(nats.DefaultURL,
+		client.WithTracer(tracing.NewTracer("test-trace-client", recorder)))
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer cli.Close()
+
+	if _, err := cli.Call("test-trace-frontend", "sum", map[string]interface{}{"a": 1.0, "b": 2.0}); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+
+	for _, tracer := range []*tracing.Tracer{cli.Tracer(), frontend.Tracer(), backend.Tracer()} {
+		tracer.Flush(context.Background())
+	}
+
+	clientSpan, ok := recorder.find(types.ServiceRPCSubject("test-trace-frontend", "sum"))
+	if !ok || clientSpan.Kind != tracing.SpanKindClient {
+		t.Fatalf("Expected client span, got %+v", recorder.spans)
+	}
+	var frontendSpan, backendSpan tracing.SpanData
+	for _, span := range recorder.spans {
+		switch {
+		case span.Kind == tracing.SpanKindServer && span.Service == "test-trace-frontend":
+			frontendSpan = span
+		case span.Kind == tracing.SpanKindServer && span.Service == "test-trace-backend":
+			backendSpan = span
+		}
+	}
+
+	if frontendSpan.ParentSpanID != clientSpan.SpanID {
+		t.Errorf("Expected frontend span to be a child of the client span")
+	}
+	if backendSpan.ParentSpanID != frontendSpan.SpanID {
+		t.Errorf("Expected backend span to be a child of the frontend span")
+	}
+	for _, span := range []tracing.SpanData{frontendSpan, backendSpan} {
+		if span.TraceID != clientSpan.TraceID {
+			t.Errorf("Expected span %s in trace %s, got %s", span.Name, clientSpan.TraceID, span.TraceID)
+		}
+		if span.Status != tracing.StatusOK {
+			t.Errorf("Expected span %s to be OK, got %s", span.Name, span.Status)
+		}
+	}
+	if handlerTraceID != clientSpan.TraceID {
+		t.Errorf("Expected handler to see the server span, got trace %q", handlerTraceID)
+	}
+}
+
+func TestTraceReporting(t *testing.T) {
+	nc, err := nats.Connect(nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer nc.Close()
+
+	reported := make(chan tracing.SpanData, 10)
+	sub, err := nc.Subscribe(types.TraceSpansSubject, func(msg *nats.Msg) {
+		var spans []tracing.SpanData
+		if json.Unmarshal(msg.Data, &spans) == nil {
+			for _, span := range spans {
+				if span.Service == "test-trace-reporting" {
+					reported <- span
+				}
+			}
+		}
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer sub.Unsubscribe()
+	nc.Flush()
+
+	svc, err := NewService("test-trace-reporting", nats.DefaultURL, WithServiceTraceReporting())
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	svc.RegisterRPC("fail", func(args map[string]interface{}) (map[string]interface{}, error) {
+		return nil, context.DeadlineExceeded
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	cli, err := client.NewClient(nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer cli.Close()
+	cli.Call("test-trace-reporting", "fail", nil)
+
+	// Stop flushes the remaining spans
+	svc.Stop()
+
+	select {
+	case span := <-reported:
+		if span.Status != tracing.StatusError || span.Attributes["rpc.method"] != "fail" {
+			t.Errorf("Unexpected reported span %+v", span)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected span to be reported over NATS")
+	}
+}
+
+func TestTraceReportingShutsDownOwnTracer(t *testing.T) {
+	bus := transport.NewMemoryBus()
+	svc, err := NewService("test-trace-shutdown", "", WithServiceTransport(bus.Connect()), WithServiceTraceReporting())
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	created := &spanRecorder{}
+	svc.Tracer().AddExporter(created)
+
+	provided := &spanRecorder{}
+	tracer := tracing.NewTracer("test-trace-provided", provided)
+	defer tracer.Shutdown(context.Background())
+	other, err := NewService("test-trace-provided", "", WithServiceTransport(bus.Connect()), WithServiceTracer(tracer), WithServiceTraceReporting())
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+
+	for _, s := range []*Service{svc, other} {
+		if err := s.Start(); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		s.Stop()
+	}
+	if !created.shutdown {
+		t.Error("Expected the tracer created for reporting to be shut down")
+	}
+	if provided.shutdown {
+		t.Error("Expected the caller's tracer to stay running")
+	}
+}
+
+func TestStreamClientSpan(t *testing.T) {
+	recorder := &spanRecorder{}
+	bus := transport.NewMemoryBus()
+	svc, err := NewService("test-trace-stream", "", WithServiceTransport(bus.Connect()),
+		WithServiceTracer(tracing.NewTracer("test-trace-stream", recorder)))
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	svc.RegisterStream("count", func(ctx context.Context, args map[string]interface{}, stream *ServerStream) error {
+		return stream.Send(map[string]interface{}{"i": 1})
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer svc.Stop()
+
+	cli, err := client.NewClient("", client.WithTransport(bus.Connect()),
+		client.WithTracer(tracing.NewTracer("test-trace-stream-client", recorder)))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := cli.CallStream(ctx, "test-trace-stream", "count", nil)
+	if err != nil {
+		t.Fatalf("CallStream failed: %v", err)
+	}
+	for {
+		if _, err := stream.Recv(); err != nil {
+			break
+		}
+	}
+	cli.Tracer().Flush(context.Background())
+	svc.Tracer().Flush(context.Background())
+
+	subject := types.ServiceRPCSubject("test-trace-stream", "count")
+	var clientSpan, serverSpan tracing.SpanData
+	recorder.mu.Lock()
+	for _, span := range recorder.spans {
+		if span.Name != subject {
+			continue
+		}
+		switch span.Kind {
+		case tracing.SpanKindClient:
+			clientSpan = span
+		case tracing.SpanKindServer:
+			serverSpan = span
+		}
+	}
+	recorder.mu.Unlock()
+
+	if clientSpan.SpanID == "" || clientSpan.Status != tracing.StatusOK {
+		t.Fatalf("Expected an OK client span for the stream, got %+v", clientSpan)
+	}
+	if serverSpan.TraceID != clientSpan.TraceID || serverSpan.ParentSpanID != clientSpan.SpanID {
+		t.Errorf("Expected the server span to be a child of the client span, got %+v", serverSpan)
+	}
+}
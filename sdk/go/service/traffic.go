@@ -0,0 +1,166 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+)
+
+const (
+	// RPCQueueGroup is the queue group service instances join for RPC subjects
+	RPCQueueGroup = "lightlink-rpc"
+	// DefaultWeight is the default traffic weight of a service instance
+	DefaultWeight = 1
+	// MaxWeight is the maximum traffic weight of a service instance
+	MaxWeight = 100
+)
+
+// WithBroadcast disables the RPC queue group so every instance receives every request.
+// Only use this for methods without side effects.
+func WithBroadcast() ServiceOption {
+	return func(s *Service) error {
+		s.broadcast = true
+		return nil
+	}
+}
+
+// WithWeight sets the initial traffic weight of this instance
+func WithWeight(weight int) ServiceOption {
+	return func(s *Service) error {
+		if err := validateWeight(weight); err != nil {
+			return err
+		}
+		s.weight = weight
+		return nil
+	}
+}
+
+// validateWeight checks that a weight is in the accepted range
+func validateWeight(weight int) error {
+	if weight < 1 || weight > MaxWeight {
+		return fmt.Errorf("weight must be between 1 and %d, got %d", MaxWeight, weight)
+	}
+	return nil
+}
+
+// subscribeRPC subscribes to the service RPC subject.
+// In queue mode the instance joins RPCQueueGroup with one queue subscription
+// per weight unit. NATS picks a random member of the group for every request,
+// so an instance with weight 3 receives roughly three times the traffic of an
+// instance with weight 1. A draining instance holds no subscriptions.
+// Caller must hold subMu.
+func (s *Service) subscribeRPC() error {
+	if s.draining {
+		return nil
+	}
+
+	subject := types.ServiceRPCSubject(s.name, ">")
+
+	if s.broadcast {
+		sub, err := s.conn.Subscribe(subject, s.handleRPC)
+		if err != nil {
+			return fmt.Errorf("subscribe failed: %w", err)
+		}
+		s.rpcSubs = append(s.rpcSubs, sub)
+		return nil
+	}
+
+	for i := 0; i < s.weight; i++ {
+		sub, err := s.conn.QueueSubscribe(subject, RPCQueueGroup, s.handleRPC)
+		if err != nil {
+			s.unsubscribeRPC()
+			return fmt.Errorf("queue subscribe failed: %w", err)
+		}
+		s.rpcSubs = append(s.rpcSubs, sub)
+	}
+	return nil
+}
+
+// unsubscribeRPC drops all RPC subscriptions, letting in-flight requests finish.
+// Caller must hold subMu.
+func (s *Service) unsubscribeRPC() {
+	for _, sub := range s.rpcSubs {
+		sub.Drain()
+	}
+	s.rpcSubs = nil
+}
+
+// SetWeight changes the traffic weight of this instance and re-advertises it
+func (s *Service) SetWeight(weight int) error {
+	if err := validateWeight(weight); err != nil {
+		return err
+	}
+
+	s.subMu.Lock()
+	s.weight = weight
+	err := s.resubscribeRPC()
+	s.subMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return s.readvertise()
+}
+
+// Drain stops this instance from receiving new RPC requests while keeping it online
+func (s *Service) Drain() error {
+	s.subMu.Lock()
+	s.draining = true
+	s.unsubscribeRPC()
+	s.subMu.Unlock()
+
+	return s.readvertise()
+}
+
+// Resume makes a drained instance receive RPC requests again
+func (s *Service) Resume() error {
+	s.subMu.Lock()
+	s.draining = false
+	err := s.resubscribeRPC()
+	s.subMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return s.readvertise()
+}
+
+// Weight returns the current traffic weight of this instance
+func (s *Service) Weight() int {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	return s.weight
+}
+
+// IsDraining returns whether this instance is draining
+func (s *Service) IsDraining() bool {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	return s.draining
+}
+
+// resubscribeRPC re-creates the RPC subscriptions if the service has started.
+// Caller must hold subMu.
+func (s *Service) resubscribeRPC() error {
+	if !s.rpcStarted {
+		return nil
+	}
+	s.unsubscribeRPC()
+	return s.subscribeRPC()
+}
+
+// readvertise re-sends the registration so the manager sees the new traffic settings
+func (s *Service) readvertise() error {
+	metadata := s.GetMetadata()
+	if metadata == nil {
+		return nil
+	}
+	return s.RegisterMetadata(metadata)
+}
+
+// subscriptionCount returns the number of active RPC subscriptions
+func (s *Service) subscriptionCount() int {
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	return len(s.rpcSubs)
+}
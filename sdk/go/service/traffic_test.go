@@ -0,0 +1,187 @@
+package service
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/client"
+	"github.com/nats-io/nats.go"
+)
+
+// startCountingService starts a service whose "hit" method counts its calls
+func startCountingService(t *testing.T, name string, counter *int32, opts ...ServiceOption) *Service {
+	t.Helper()
+	svc, err := NewService(name, nats.DefaultURL, opts...)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	svc.RegisterRPC("hit", func(args map[string]interface{}) (map[string]interface{}, error) {
+		atomic.AddInt32(counter, 1)
+		return map[string]interface{}{"ok": true}, nil
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { svc.Stop() })
+	return svc
+}
+
+func TestQueueGroupDeliversOnce(t *testing.T) {
+	var first, second int32
+	startCountingService(t, "test-queue-service", &first)
+	startCountingService(t, "test-queue-service", &second)
+
+	cli, err := client.NewClient(nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer cli.Close()
+
+	const calls = 20
+	for i := 0; i < calls; i++ {
+		if _, err := cli.Call("test-queue-service", "hit", nil); err != nil {
+			t.Fatalf("Call failed: %v", err)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if total := atomic.LoadInt32(&first) + atomic.LoadInt32(&second); total != calls {
+		t.Errorf("Expected %d executions, got %d", calls, total)
+	}
+}
+
+func TestBroadcastDeliversToAll(t *testing.T) {
+	var first, second int32
+	startCountingService(t, "test-broadcast-service", &first, WithBroadcast())
+	startCountingService(t, "test-broadcast-service", &second, WithBroadcast())
+
+	cli, err := client.NewClient(nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer cli.Close()
+
+	if _, err := cli.Call("test-broadcast-service", "hit", nil); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if atomic.LoadInt32(&first) != 1 || atomic.LoadInt32(&second) != 1 {
+		t.Errorf("Expected both instances to handle the call, got %d/%d", first, second)
+	}
+}
+
+func TestDrainAndWeight(t *testing.T) {
+	var drained, active int32
+	drainedSvc := startCountingService(t, "test-drain-service", &drained, WithWeight(2))
+	startCountingService(t, "test-drain-service", &active)
+
+	if got := drainedSvc.subscriptionCount(); got != 2 {
+		t.Errorf("Expected 2 queue subscriptions for weight 2, got %d", got)
+	}
+
+	if err := drainedSvc.Drain(); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+	if !drainedSvc.IsDraining() || drainedSvc.subscriptionCount() != 0 {
+		t.Fatal("Expected drained instance to hold no subscriptions")
+	}
+
+	cli, err := client.NewClient(nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer cli.Close()
+
+	for i := 0; i < 10; i++ {
+		if _, err := cli.Call("test-drain-service", "hit", nil); err != nil {
+			t.Fatalf("Call failed: %v", err)
+		}
+	}
+	if atomic.LoadInt32(&drained) != 0 {
+		t.Errorf("Drained instance handled %d calls", drained)
+	}
+
+	if err := drainedSvc.Resume(); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if err := drainedSvc.SetWeight(3); err != nil {
+		t.Fatalf("SetWeight failed: %v", err)
+	}
+	if got := drainedSvc.subscriptionCount(); got != 3 {
+		t.Errorf("Expected 3 queue subscriptions after SetWeight(3), got %d", got)
+	}
+	if err := drainedSvc.SetWeight(0); err == nil {
+		t.Error("Expected error for weight 0")
+	}
+}
+
+func TestCallInstanceWhileDraining(t *testing.T) {
+	var hits int32
+	svc := startCountingService(t, "test-instance-service", &hits)
+
+	if err := svc.Drain(); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+
+	cli, err := client.NewClient(nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer cli.Close()
+
+	result, err := cli.CallInstance(svc.InstanceKey(), "hit", nil)
+	if err != nil {
+		t.Fatalf("CallInstance failed: %v", err)
+	}
+	if result["ok"] != true {
+		t.Errorf("Unexpected result: %v", result)
+	}
+	if atomic.LoadInt32(&hits) != 1 {
+		t.Errorf("Expected 1 execution, got %d", hits)
+	}
+
+	if _, err := cli.CallInstance("10.0.0.1:000000000000:test-instance-service", "hit", nil); err == nil {
+		t.Error("Expected error for unknown instance")
+	}
+}
+
+func TestCallAllCollectsEveryInstance(t *testing.T) {
+	var first, second int32
+	startCountingService(t, "test-callall-service", &first)
+	drained := startCountingService(t, "test-callall-service", &second)
+	if err := drained.Drain(); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	cli, err := client.NewClient(nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer cli.Close()
+
+	start := time.Now()
+	results, err := cli.CallAll("test-callall-service", "hit", nil, &client.CallAllOptions{
+		Timeout:  2 * time.Second,
+		Expected: 2,
+	})
+	if err != nil {
+		t.Fatalf("CallAll failed: %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Expected CallAll to return once all replies arrived, took %v", time.Since(start))
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 replies, got %d", len(results))
+	}
+	for _, r := range results {
+		if r.Err != nil || r.Result["ok"] != true {
+			t.Errorf("Unexpected reply: %+v", r)
+		}
+		if r.InstanceKey != drained.InstanceKey() {
+			t.Errorf("Expected instance key %s, got %s", drained.InstanceKey(), r.InstanceKey)
+		}
+	}
+}
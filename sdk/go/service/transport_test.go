@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/client"
+	"github.com/LiteHomeLab/light_link/sdk/go/transport"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+// TestServiceOnMemoryTransport runs a service and a client without a broker
+func TestServiceOnMemoryTransport(t *testing.T) {
+	bus := transport.NewMemoryBus()
+	observer := bus.Connect()
+	defer observer.Close()
+
+	registered := make(chan types.RegisterMessage, 4)
+	observer.Subscribe("$LL.register.>", func(msg *nats.Msg) {
+		var register types.RegisterMessage
+		if json.Unmarshal(msg.Data, &register) == nil {
+			registered <- register
+		}
+	})
+
+	svc, err := NewService("math", "", WithServiceTransport(bus.Connect()))
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	svc.RegisterMethodWithMetadata("add", func(args map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{"sum": args["a"].(float64) + args["b"].(float64)}, nil
+	}, &types.MethodMetadata{
+		Name: "add",
+		Params: []types.ParameterMetadata{
+			{Name: "a", Type: "number", Required: true},
+			{Name: "b", Type: "number", Required: true},
+		},
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer svc.Stop()
+
+	if err := svc.RegisterMetadata(svc.BuildCurrentMetadata("math", "v1.0.0", "", "", nil)); err != nil {
+		t.Fatalf("RegisterMetadata failed: %v", err)
+	}
+	select {
+	case register := <-registered:
+		if register.Service != "math" || len(register.Metadata.Methods) != 1 {
+			t.Errorf("Unexpected registration %+v", register)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Metadata not registered")
+	}
+
+	cli, err := client.NewClient("", client.WithTransport(bus.Connect()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer cli.Close()
+
+	result, err := cli.Call("math", "add", map[string]interface{}{"a": 2, "b": 3})
+	if err != nil || result["sum"] != 5.0 {
+		t.Fatalf("Expected sum 5, got %v, %v", result, err)
+	}
+
+	_, err = cli.Call("math", "add", map[string]interface{}{"a": 2})
+	var validationErr *types.ValidationError
+	if !errors.Is(err, types.ErrValidation) || !errors.As(err, &validationErr) || validationErr.ParameterName != "b" {
+		t.Errorf("Expected validation error for b, got %v", err)
+	}
+
+	// A drain command stops the service taking new calls by name
+	control, _ := json.Marshal(types.ControlMessage{Service: "math", InstanceKey: svc.InstanceKey(), Command: "drain"})
+	observer.Publish("$LL.control.math.drain", control)
+	deadline := time.Now().Add(time.Second)
+	for !svc.IsDraining() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !svc.IsDraining() {
+		t.Fatal("Drain command not applied")
+	}
+	if _, err := cli.Call("math", "add", map[string]interface{}{"a": 1, "b": 1}); !errors.Is(err, nats.ErrNoResponders) {
+		t.Errorf("Expected no responders while draining, got %v", err)
+	}
+	if result, err := cli.CallInstance(svc.InstanceKey(), "add", map[string]interface{}{"a": 1, "b": 1}); err != nil || result["sum"] != 2.0 {
+		t.Errorf("Expected the instance to answer while draining, got %v, %v", result, err)
+	}
+}
+
+// TestInterceptorSeesBroadcastAndStream checks that CallAll and CallStream go
+// through the client interceptor chain like Call
+func TestInterceptorSeesBroadcastAndStream(t *testing.T) {
+	bus := transport.NewMemoryBus()
+	requireToken := func(ctx context.Context, info *CallInfo, args map[string]interface{}, next RPCHandlerCtx) (map[string]interface{}, error) {
+		if info.Header.Get("Authorization") != "token" {
+			return nil, types.NewRPCError(types.CodeUnauthenticated, "missing token")
+		}
+		return next(ctx, args)
+	}
+	svc, err := NewService("intercepted", "", WithServiceTransport(bus.Connect()), WithMiddleware(requireToken))
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	svc.RegisterRPC("whoami", func(args map[string]interface{}) (map[string]interface{}, error) {
+		return map[string]interface{}{"ok": true}, nil
+	})
+	svc.RegisterStream("count", func(ctx context.Context, args map[string]interface{}, stream *ServerStream) error {
+		return stream.Send(map[string]interface{}{"i": 1})
+	})
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer svc.Stop()
+
+	var seen []client.CallKind
+	cli, err := client.NewClient("", client.WithTransport(bus.Connect()), client.WithInterceptor(
+		func(ctx context.Context, info *client.CallInfo, args map[string]interface{}, invoker client.Invoker) (map[string]interface{}, error) {
+			seen = append(seen, info.Kind)
+			info.Header.Set("Authorization", "token")
+			return invoker(ctx, info, args)
+		}))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer cli.Close()
+
+	results, err := cli.CallAll("intercepted", "whoami", nil, &client.CallAllOptions{Expected: 1})
+	if err != nil || len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("CallAll failed: %v %+v", err, results)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := cli.CallStream(ctx, "intercepted", "count", nil)
+	if err != nil {
+		t.Fatalf("CallStream failed: %v", err)
+	}
+	defer stream.Close()
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Recv failed: %v", err)
+	}
+
+	if len(seen) != 2 || seen[0] != client.CallKindBroadcast || seen[1] != client.CallKindStream {
+		t.Errorf("Expected the interceptor to see the broadcast and the stream, got %v", seen)
+	}
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/client"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+)
+
+// TypedHandler is an RPC handler working on Go structs instead of maps
+type TypedHandler[Req, Resp any] func(ctx context.Context, req Req) (Resp, error)
+
+// RegisterTyped registers a typed RPC method.
+// The method metadata (params, returns, required flags, descriptions) is
+// derived from the struct tags of Req and Resp, see TypedMethodMetadata.
+func RegisterTyped[Req, Resp any](s *Service, method, description string, handler TypedHandler[Req, Resp]) error {
+	meta := TypedMethodMetadata[Req, Resp](method, description)
+	return s.RegisterMethodWithMetadataCtx(method, adaptTyped(handler), meta)
+}
+
+// TypedMethodMetadata builds method metadata from the struct tags of Req and Resp
+func TypedMethodMetadata[Req, Resp any](method, description string) *types.MethodMetadata {
+	return &types.MethodMetadata{
+		Name:        method,
+		Description: description,
+		Params:      types.ParamsFromStruct(reflect.TypeFor[Req]()),
+		Returns:     types.ReturnsFromStruct(reflect.TypeFor[Resp]()),
+	}
+}
+
+// adaptTyped wraps a TypedHandler into an RPCHandlerCtx
+func adaptTyped[Req, Resp any](handler TypedHandler[Req, Resp]) RPCHandlerCtx {
+	return func(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+		var req Req
+		if err := client.ArgsToStruct(args, &req); err != nil {
+			return nil, decodeErrorToValidationError(err)
+		}
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := client.StructToArgs(resp)
+		if err != nil {
+			return nil, fmt.Errorf("encode response: %w", err)
+		}
+		return result, nil
+	}
+}
+
+// decodeErrorToValidationError converts a JSON decode error into a ValidationError
+func decodeErrorToValidationError(err error) *types.ValidationError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &types.ValidationError{
+			ParameterName: typeErr.Field,
+			ExpectedType:  types.JSONTypeOf(typeErr.Type),
+			ActualType:    typeErr.Value,
+			Message: fmt.Sprintf("parameter '%s': expected type %s, got %s",
+				typeErr.Field, types.JSONTypeOf(typeErr.Type), typeErr.Value),
+		}
+	}
+	return &types.ValidationError{
+		Message: fmt.Sprintf("invalid arguments: %v", err),
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/client"
+	"github.com/LiteHomeLab/light_link/sdk/go/transport"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+)
+
+type addRequest struct {
+	A float64 `json:"a" required:"true" desc:"First number"`
+	B float64 `json:"b" required:"true" desc:"Second number"`
+}
+
+type addResponse struct {
+	Sum float64 `json:"sum" desc:"Sum of a and b"`
+}
+
+func TestTypedMethodMetadata(t *testing.T) {
+	meta := TypedMethodMetadata[addRequest, addResponse]("add", "Add two numbers")
+
+	if meta.Name != "add" || meta.Description != "Add two numbers" {
+		t.Errorf("Unexpected name/description: %s / %s", meta.Name, meta.Description)
+	}
+	if len(meta.Params) != 2 || !meta.Params[0].Required || meta.Params[0].Type != "number" {
+		t.Errorf("Unexpected params: %+v", meta.Params)
+	}
+	if len(meta.Returns) != 1 || meta.Returns[0].Name != "sum" {
+		t.Errorf("Unexpected returns: %+v", meta.Returns)
+	}
+}
+
+func TestAdaptTypedDecodeError(t *testing.T) {
+	handler := adaptTyped(func(ctx context.Context, req addRequest) (addResponse, error) {
+		return addResponse{Sum: req.A + req.B}, nil
+	})
+
+	_, err := handler(context.Background(), map[string]interface{}{"a": "one", "b": 2})
+	var validationErr *types.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected ValidationError, got %v", err)
+	}
+	if validationErr.ParameterName != "a" {
+		t.Errorf("Expected parameter 'a', got '%s'", validationErr.ParameterName)
+	}
+}
+
+func TestRegisterTypedRoundTrip(t *testing.T) {
+	svc, err := NewService("test-typed-service", nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer svc.Stop()
+
+	err = RegisterTyped(svc, "add", "Add two numbers", func(ctx context.Context, req addRequest) (addResponse, error) {
+		return addResponse{Sum: req.A + req.B}, nil
+	})
+	if err != nil {
+		t.Fatalf("RegisterTyped failed: %v", err)
+	}
+	if _, ok := svc.GetMethodMetadata("add"); !ok {
+		t.Error("Expected metadata for typed method")
+	}
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	cli, err := client.NewClient(nats.DefaultURL)
+	if err != nil {
+		t.Skip("NATS not available:", err)
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	resp, err := client.CallTyped[addRequest, addResponse](ctx, cli, "test-typed-service", "add", addRequest{A: 2, B: 3})
+	if err != nil {
+		t.Fatalf("CallTyped failed: %v", err)
+	}
+	if resp.Sum != 5 {
+		t.Errorf("Expected sum 5, got %v", resp.Sum)
+	}
+}
+
+type searchRequest struct {
+	Query   string            `json:"query"`
+	Tags    []string          `json:"tags"`
+	Filters map[string]string `json:"filters"`
+	Limit   *int              `json:"limit"`
+	Cursor  interface{}       `json:"cursor"`
+}
+
+type searchResponse struct {
+	Count int `json:"count"`
+}
+
+func TestRegisterTypedZeroValueRoundTrip(t *testing.T) {
+	bus := transport.NewMemoryBus()
+	svc, err := NewService("test-typed-search", "", WithServiceTransport(bus.Connect()))
+	if err != nil {
+		t.Fatalf("NewService failed: %v", err)
+	}
+	err = RegisterTyped(svc, "search", "Search", func(ctx context.Context, req searchRequest) (searchResponse, error) {
+		return searchResponse{Count: len(req.Tags)}, nil
+	})
+	if err != nil {
+		t.Fatalf("RegisterTyped failed: %v", err)
+	}
+	if err := svc.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	defer svc.Stop()
+
+	cli, err := client.NewClient("", client.WithTransport(bus.Connect()))
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
+	}
+	defer cli.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	// Nil slices, maps and pointers are sent as null
+	if _, err := client.CallTyped[searchRequest, searchResponse](ctx, cli, "test-typed-search", "search", searchRequest{}); err != nil {
+		t.Errorf("CallTyped with a zero value failed: %v", err)
+	}
+	// An interface field accepts scalars
+	resp, err := client.CallTyped[searchRequest, searchResponse](ctx, cli, "test-typed-search", "search", searchRequest{Tags: []string{"go"}, Cursor: 42})
+	if err != nil || resp.Count != 1 {
+		t.Errorf("CallTyped with a scalar cursor returned %+v, %v", resp, err)
+	}
+}
@@ -36,7 +36,8 @@ func (v *Validator) Validate(args map[string]interface{}) error {
 			}
 		}
 
-		if !exists {
+		// A nil slice, map or pointer is encoded as null: optional and absent
+		if !exists || (value == nil && !paramMeta.Required) {
 			continue // Optional parameter not provided
 		}
 
@@ -72,6 +73,9 @@ func inferTypeString(value interface{}) string {
 		return "number"
 	case string:
 		return "string"
+	case []byte:
+		// Binary data, decoded by MessagePack and CBOR; a base64 string in JSON
+		return "string"
 	case []interface{}:
 		return "array"
 	case map[string]interface{}:
@@ -102,8 +106,8 @@ func inferTypeString(value interface{}) string {
 
 // isTypeCompatible checks if actual type is compatible with expected type
 func isTypeCompatible(expected, actual string) bool {
-	// Direct match
-	if expected == actual {
+	// Direct match; "any" accepts every type
+	if expected == actual || expected == "any" {
 		return true
 	}
 
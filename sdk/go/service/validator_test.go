@@ -56,6 +56,8 @@ func TestIsTypeCompatible(t *testing.T) {
 		{"number compatible with float", "number", "float", true},
 		{"string not compatible with number", "string", "number", false},
 		{"boolean not compatible with string", "boolean", "string", false},
+		{"any compatible with number", "any", "number", true},
+		{"any compatible with object", "any", "object", true},
 	}
 
 	for _, tt := range tests {
@@ -162,6 +164,27 @@ func TestValidator_Validate(t *testing.T) {
 			args:    map[string]interface{}{"name": "test"},
 			wantErr: false,
 		},
+		{
+			name: "null optional parameter",
+			metadata: &types.MethodMetadata{
+				Params: []types.ParameterMetadata{
+					{Name: "tags", Type: "array", Required: false},
+				},
+			},
+			args:    map[string]interface{}{"tags": nil},
+			wantErr: false,
+		},
+		{
+			name: "null required parameter",
+			metadata: &types.MethodMetadata{
+				Params: []types.ParameterMetadata{
+					{Name: "tags", Type: "array", Required: true},
+				},
+			},
+			args:    map[string]interface{}{"tags": nil},
+			wantErr: true,
+			errType: "*types.ValidationError",
+		},
 		{
 			name: "multiple valid parameters",
 			metadata: &types.MethodMetadata{
@@ -0,0 +1,189 @@
+// Package testutil runs an in-process NATS server with JetStream for tests,
+// so they do not depend on a server running on the developer's machine.
+//
+//	srv := testutil.StartNATS(t, testutil.Options{})
+//	c, err := client.NewClient(srv.URL)
+//
+// The package does not import client or service, so their internal tests
+// can use it. Helpers starting a
+// service.Service live in testutil/servicetest, the manager helper in the
+// managertest package next to the manager.
+package testutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/certs"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// readyTimeout is how long to wait for the server to accept connections
+	readyTimeout = 10 * time.Second
+	// certValidity is the validity of the throwaway certificates
+	certValidity = 24 * time.Hour
+)
+
+// Options configures the test server
+type Options struct {
+	// TLS serves TLS only, with throwaway certificates, and requires client certificates
+	TLS bool
+	// NoJetStream disables JetStream, which is enabled by default
+	NoJetStream bool
+	// Debug logs the server output
+	Debug bool
+}
+
+// Server is a running test server
+type Server struct {
+	// URL is the client URL, nats:// or tls:// on 127.0.0.1 and a random port
+	URL string
+	// ClientTLS holds the client certificate and CA when TLS is enabled;
+	// convert it with client.TLSConfigFrom
+	ClientTLS *types.TLSConfig
+	// Server is the embedded server, e.g. for JetStream or monitoring access
+	Server *server.Server
+
+	dir string
+}
+
+// StartNATS starts a test server that is shut down when the test ends
+func StartNATS(t testing.TB, opts Options) *Server {
+	t.Helper()
+	srv, cleanup, err := RunNATS(opts)
+	if err != nil {
+		t.Fatalf("start NATS server: %v", err)
+	}
+	t.Cleanup(cleanup)
+	return srv
+}
+
+// RunNATS starts a test server with its store and certificates in a new
+// temp dir. cleanup shuts the server down and removes the dir. Use it from
+// TestMain or outside tests; tests use StartNATS.
+func RunNATS(opts Options) (srv *Server, cleanup func(), err error) {
+	dir, err := os.MkdirTemp("", "lightlink-nats-*")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	serverOpts := &server.Options{
+		ServerName: "lightlink-test",
+		Host:       "127.0.0.1",
+		Port:       server.RANDOM_PORT,
+		NoSigs:     true,
+		NoLog:      !opts.Debug,
+		Debug:      opts.Debug,
+		JetStream:  !opts.NoJetStream,
+		StoreDir:   filepath.Join(dir, "jetstream"),
+	}
+
+	srv = &Server{dir: dir}
+	if opts.TLS {
+		if srv.ClientTLS, err = enableTLS(serverOpts, filepath.Join(dir, "certs")); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	ns, err := server.NewServer(serverOpts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create server: %w", err)
+	}
+	if opts.Debug {
+		ns.ConfigureLogger()
+	}
+	go ns.Start()
+	if !ns.ReadyForConnections(readyTimeout) {
+		ns.Shutdown()
+		return nil, nil, fmt.Errorf("server not ready after %s", readyTimeout)
+	}
+
+	srv.Server = ns
+	srv.URL = ns.ClientURL()
+	return srv, srv.Shutdown, nil
+}
+
+// Shutdown stops the server and removes its temp dir
+func (s *Server) Shutdown() {
+	s.Server.Shutdown()
+	s.Server.WaitForShutdown()
+	os.RemoveAll(s.dir)
+}
+
+// Connect returns a plain NATS connection to the server, closed when the test ends
+func (s *Server) Connect(t testing.TB) *nats.Conn {
+	t.Helper()
+	var opts []nats.Option
+	if s.ClientTLS != nil {
+		opts = append(opts,
+			nats.RootCAs(s.ClientTLS.CaFile),
+			nats.ClientCert(s.ClientTLS.CertFile, s.ClientTLS.KeyFile))
+	}
+	nc, err := nats.Connect(s.URL, opts...)
+	if err != nil {
+		t.Fatalf("connect to NATS: %v", err)
+	}
+	t.Cleanup(nc.Close)
+	return nc
+}
+
+// WaitForInterest waits until a subscription matching subject reached the
+// server, e.g. before publishing to a subscriber started on another connection
+func (s *Server) WaitForInterest(t testing.TB, subject string) {
+	t.Helper()
+	deadline := time.Now().Add(readyTimeout)
+	for !s.Server.GlobalAccount().SubscriptionInterest(subject) {
+		if time.Now().After(deadline) {
+			t.Fatalf("no subscription for %s after %s", subject, readyTimeout)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// enableTLS issues throwaway certificates into dir, laid out like a
+// deployment, and configures the server to require client certificates
+func enableTLS(opts *server.Options, dir string) (*types.TLSConfig, error) {
+	ca, err := certs.InitCA(filepath.Join(dir, "ca"), "LightLink Test CA", certValidity)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ca.Issue(certs.Request{Kind: types.ServerCerts, Validity: certValidity, OutDir: dir}); err != nil {
+		return nil, err
+	}
+	if _, err := ca.Issue(certs.Request{Kind: types.ClientCerts, Validity: certValidity, OutDir: dir}); err != nil {
+		return nil, err
+	}
+
+	serverDir := filepath.Join(dir, filepath.Base(types.DefaultServerCertDir))
+	tlsConfig, err := server.GenTLSConfig(&server.TLSConfigOpts{
+		CaFile:   filepath.Join(serverDir, certs.CACertFile),
+		CertFile: filepath.Join(serverDir, "server.crt"),
+		KeyFile:  filepath.Join(serverDir, "server.key"),
+		Verify:   true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("server TLS config: %w", err)
+	}
+	opts.TLSConfig = tlsConfig
+	opts.TLS = true
+	opts.TLSVerify = true
+
+	clientDir := filepath.Join(dir, filepath.Base(types.DefaultClientCertDir))
+	return &types.TLSConfig{
+		CaFile:     filepath.Join(clientDir, certs.CACertFile),
+		CertFile:   filepath.Join(clientDir, "client.crt"),
+		KeyFile:    filepath.Join(clientDir, "client.key"),
+		ServerName: types.DefaultServerName,
+	}, nil
+}
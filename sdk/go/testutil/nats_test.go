@@ -0,0 +1,45 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestStartNATS(t *testing.T) {
+	srv := StartNATS(t, Options{})
+	nc := srv.Connect(t)
+
+	js, err := nc.JetStream()
+	if err != nil {
+		t.Fatalf("JetStream failed: %v", err)
+	}
+	if _, err := js.AccountInfo(); err != nil {
+		t.Errorf("Expected JetStream to be enabled: %v", err)
+	}
+
+	sub, _ := nc.SubscribeSync("test.subject")
+	srv.WaitForInterest(t, "test.subject")
+	nc.Publish("test.subject", []byte("hello"))
+	if msg, err := sub.NextMsg(nats.DefaultTimeout); err != nil || string(msg.Data) != "hello" {
+		t.Errorf("Expected message, got %v", err)
+	}
+}
+
+func TestStartNATSTLS(t *testing.T) {
+	srv := StartNATS(t, Options{TLS: true})
+	if srv.ClientTLS == nil {
+		t.Fatal("Expected client TLS settings")
+	}
+
+	// A client certificate is required
+	if nc, err := nats.Connect(srv.URL, nats.RootCAs(srv.ClientTLS.CaFile)); err == nil {
+		nc.Close()
+		t.Error("Expected connect without client certificate to fail")
+	}
+
+	nc := srv.Connect(t)
+	if !nc.TLSRequired() {
+		t.Error("Expected a TLS connection")
+	}
+}
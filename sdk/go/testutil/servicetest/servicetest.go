@@ -0,0 +1,54 @@
+// Package servicetest starts LightLink services and clients against a
+// testutil server. It is separate from testutil because it imports the
+// service package, whose own tests use testutil.
+package servicetest
+
+import (
+	"testing"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/client"
+	"github.com/LiteHomeLab/light_link/sdk/go/service"
+	"github.com/LiteHomeLab/light_link/sdk/go/testutil"
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+)
+
+// StartService creates the service name connected to srv, calls register to
+// add its methods and starts it. It returns once the service receives calls;
+// the service is stopped when the test ends.
+func StartService(t testing.TB, srv *testutil.Server, name string, register func(*service.Service), opts ...service.ServiceOption) *service.Service {
+	t.Helper()
+	if srv.ClientTLS != nil {
+		opts = append([]service.ServiceOption{service.WithServiceTLS(client.TLSConfigFrom(srv.ClientTLS))}, opts...)
+	}
+
+	svc, err := service.NewService(name, srv.URL, opts...)
+	if err != nil {
+		t.Fatalf("create service %s: %v", name, err)
+	}
+	t.Cleanup(func() { svc.Stop() })
+
+	if register != nil {
+		register(svc)
+	}
+	if err := svc.Start(); err != nil {
+		t.Fatalf("start service %s: %v", name, err)
+	}
+	srv.WaitForInterest(t, types.ServiceRPCSubject(name, "ready"))
+	srv.WaitForInterest(t, types.BroadcastRPCSubject(name, "ready"))
+	return svc
+}
+
+// NewClient connects a client to srv, closed when the test ends
+func NewClient(t testing.TB, srv *testutil.Server, opts ...client.Option) *client.Client {
+	t.Helper()
+	if srv.ClientTLS != nil {
+		opts = append([]client.Option{client.WithTLS(client.TLSConfigFrom(srv.ClientTLS))}, opts...)
+	}
+
+	c, err := client.NewClient(srv.URL, opts...)
+	if err != nil {
+		t.Fatalf("create client: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
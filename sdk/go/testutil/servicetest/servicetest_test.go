@@ -0,0 +1,26 @@
+package servicetest
+
+import (
+	"testing"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/service"
+	"github.com/LiteHomeLab/light_link/sdk/go/testutil"
+)
+
+func TestStartServiceTLS(t *testing.T) {
+	srv := testutil.StartNATS(t, testutil.Options{TLS: true})
+	StartService(t, srv, "echo-service", func(svc *service.Service) {
+		svc.RegisterRPC("echo", func(args map[string]interface{}) (map[string]interface{}, error) {
+			return args, nil
+		})
+	})
+
+	c := NewClient(t, srv)
+	result, err := c.Call("echo-service", "echo", map[string]interface{}{"text": "hi"})
+	if err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if result["text"] != "hi" {
+		t.Errorf("Expected echo, got %v", result)
+	}
+}
@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+)
+
+// FileExporter writes spans to a file as JSON lines, one span per line
+type FileExporter struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewFileExporter opens path for appending and returns an exporter writing to it
+func NewFileExporter(path string) (*FileExporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open trace file: %w", err)
+	}
+	return &FileExporter{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// ExportSpans implements Exporter
+func (e *FileExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for i := range spans {
+		if err := e.enc.Encode(&spans[i]); err != nil {
+			return fmt.Errorf("write span: %w", err)
+		}
+	}
+	return nil
+}
+
+// Shutdown implements Exporter and closes the file
+func (e *FileExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.file.Close()
+}
+
+// Publisher sends messages, e.g. a *nats.Conn or a transport.Conn
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// NATSExporter publishes spans to types.TraceSpansSubject, where the manager
+// collects them to show trace trees
+type NATSExporter struct {
+	nc Publisher
+}
+
+// NewNATSExporter returns an exporter publishing on nc
+func NewNATSExporter(nc Publisher) *NATSExporter {
+	return &NATSExporter{nc: nc}
+}
+
+// ExportSpans implements Exporter
+func (e *NATSExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	data, err := json.Marshal(spans)
+	if err != nil {
+		return fmt.Errorf("marshal spans: %w", err)
+	}
+	return e.nc.Publish(types.TraceSpansSubject, data)
+}
+
+// Shutdown implements Exporter and flushes publishers buffering writes, like
+// a *nats.Conn. The connection is owned by the caller and left open.
+func (e *NATSExporter) Shutdown(ctx context.Context) error {
+	if flusher, ok := e.nc.(interface{ FlushWithContext(context.Context) error }); ok {
+		return flusher.FlushWithContext(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	var received otlpTraceRequest
+	var authHeader string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		authHeader = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	exporter := NewOTLPExporter(collector.URL+"/v1/traces", map[string]string{"Authorization": "Bearer token"})
+	start := time.Unix(1700000000, 0)
+	err := exporter.ExportSpans(context.Background(), []SpanData{{
+		TraceID:      "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:       "00f067aa0ba902b7",
+		ParentSpanID: "1111111111111111",
+		Name:         "$SRV.math-service.add",
+		Kind:         SpanKindServer,
+		Service:      "math-service",
+		StartTime:    start,
+		EndTime:      start.Add(time.Millisecond),
+		Status:       StatusError,
+		Attributes:   map[string]string{"rpc.method": "add"},
+	}})
+	if err != nil {
+		t.Fatalf("ExportSpans failed: %v", err)
+	}
+
+	if authHeader != "Bearer token" {
+		t.Errorf("Expected custom header to be sent, got %q", authHeader)
+	}
+	if len(received.ResourceSpans) != 1 {
+		t.Fatalf("Expected 1 resource, got %d", len(received.ResourceSpans))
+	}
+	resource := received.ResourceSpans[0]
+	if resource.Resource.Attributes[0].Value.StringValue != "math-service" {
+		t.Errorf("Unexpected resource %+v", resource.Resource)
+	}
+	span := resource.ScopeSpans[0].Spans[0]
+	if span.Kind != 2 || span.Status.Code != 2 {
+		t.Errorf("Unexpected kind %d or status %d", span.Kind, span.Status.Code)
+	}
+	if span.StartTimeUnixNano != "1700000000000000000" || span.EndTimeUnixNano != "1700000000001000000" {
+		t.Errorf("Unexpected timestamps %s %s", span.StartTimeUnixNano, span.EndTimeUnixNano)
+	}
+	if span.ParentSpanID != "1111111111111111" || span.Attributes[0].Key != "rpc.method" {
+		t.Errorf("Unexpected span %+v", span)
+	}
+}
+
+func TestOTLPExporterError(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "collector down", http.StatusServiceUnavailable)
+	}))
+	defer collector.Close()
+
+	exporter := NewOTLPExporter(collector.URL, nil)
+	if err := exporter.ExportSpans(context.Background(), []SpanData{{Name: "span"}}); err == nil {
+		t.Error("Expected error for non-2xx response")
+	}
+}
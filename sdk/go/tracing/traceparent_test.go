@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	value := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceparent(value)
+	if err != nil {
+		t.Fatalf("ParseTraceparent failed: %v", err)
+	}
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Unexpected trace id %s", sc.TraceID)
+	}
+	if sc.SpanID.String() != "00f067aa0ba902b7" {
+		t.Errorf("Unexpected span id %s", sc.SpanID)
+	}
+	if !sc.Sampled {
+		t.Error("Expected sampled flag")
+	}
+	if sc.Traceparent() != value {
+		t.Errorf("Round trip mismatch: %s", sc.Traceparent())
+	}
+}
+
+func TestParseTraceparentInvalid(t *testing.T) {
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+	}
+	for _, value := range invalid {
+		if _, err := ParseTraceparent(value); !errors.Is(err, ErrInvalidTraceparent) {
+			t.Errorf("Expected ErrInvalidTraceparent for %q, got %v", value, err)
+		}
+	}
+
+	// Future versions may append fields
+	if _, err := ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); err != nil {
+		t.Errorf("Expected future version to parse, got %v", err)
+	}
+}
@@ -0,0 +1,373 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/WQGroup/logger"
+)
+
+// SpanKind describes the role of a span in a call
+type SpanKind string
+
+const (
+	SpanKindInternal SpanKind = "internal"
+	SpanKindServer   SpanKind = "server"
+	SpanKindClient   SpanKind = "client"
+)
+
+// StatusCode is the outcome of a span
+type StatusCode string
+
+const (
+	StatusUnset StatusCode = "unset"
+	StatusOK    StatusCode = "ok"
+	StatusError StatusCode = "error"
+)
+
+const (
+	// DefaultBatchSize is the number of spans exported together
+	DefaultBatchSize = 256
+	// DefaultExportInterval is how often buffered spans are exported
+	DefaultExportInterval = time.Second
+	// queueSize bounds the spans buffered for export; spans are dropped when it is full
+	queueSize = 4096
+)
+
+// SpanData is a finished span as handed to exporters
+type SpanData struct {
+	TraceID       string            `json:"trace_id"`
+	SpanID        string            `json:"span_id"`
+	ParentSpanID  string            `json:"parent_span_id,omitempty"`
+	Name          string            `json:"name"`
+	Kind          SpanKind          `json:"kind"`
+	Service       string            `json:"service"`
+	StartTime     time.Time         `json:"start_time"`
+	EndTime       time.Time         `json:"end_time"`
+	Status        StatusCode        `json:"status"`
+	StatusMessage string            `json:"status_message,omitempty"`
+	Attributes    map[string]string `json:"attributes,omitempty"`
+}
+
+// Exporter sends finished spans to a tracing backend
+type Exporter interface {
+	ExportSpans(ctx context.Context, spans []SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+// Tracer creates spans for one service and exports them in batches
+type Tracer struct {
+	service string
+
+	mu        sync.RWMutex
+	exporters []Exporter
+
+	queue    chan SpanData
+	flushReq chan chan struct{}
+	stop     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// NewTracer creates a tracer for service exporting to exporters
+func NewTracer(service string, exporters ...Exporter) *Tracer {
+	t := &Tracer{
+		service:   service,
+		exporters: exporters,
+		queue:     make(chan SpanData, queueSize),
+		flushReq:  make(chan chan struct{}),
+		stop:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+// Service returns the service name spans are recorded for
+func (t *Tracer) Service() string {
+	return t.service
+}
+
+// AddExporter adds an exporter receiving all spans ended from now on
+func (t *Tracer) AddExporter(e Exporter) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.exporters = append(t.exporters, e)
+}
+
+// Start starts a span as a child of the span or remote span context carried by
+// ctx, or as the root of a new trace. The returned context carries the span.
+// A nil tracer returns ctx unchanged and a nil span, whose methods are no-ops.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		tracer: t,
+		name:   name,
+		kind:   kind,
+		start:  time.Now(),
+		status: StatusUnset,
+	}
+
+	if parent := SpanContextFromContext(ctx); parent.IsValid() {
+		span.sc.TraceID = parent.TraceID
+		span.sc.Sampled = parent.Sampled
+		span.parent = parent.SpanID
+	} else {
+		span.sc.TraceID = newTraceID()
+		span.sc.Sampled = true
+	}
+	span.sc.SpanID = newSpanID()
+
+	return ContextWithSpan(ctx, span), span
+}
+
+// Flush exports all spans ended so far
+func (t *Tracer) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case t.flushReq <- done:
+	case <-t.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown flushes the remaining spans and shuts the exporters down
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	err := t.Flush(ctx)
+	t.stopOnce.Do(func() { close(t.stop) })
+	<-t.stopped
+
+	t.mu.RLock()
+	exporters := t.exporters
+	t.mu.RUnlock()
+	for _, e := range exporters {
+		if shutdownErr := e.Shutdown(ctx); shutdownErr != nil && err == nil {
+			err = shutdownErr
+		}
+	}
+	return err
+}
+
+// enqueue hands a finished span to the export loop
+func (t *Tracer) enqueue(data SpanData) {
+	select {
+	case t.queue <- data:
+	default:
+		logger.Warnf("Tracing queue full, dropping span %s", data.Name)
+	}
+}
+
+// run batches spans and exports them until the tracer is stopped
+func (t *Tracer) run() {
+	defer close(t.stopped)
+
+	ticker := time.NewTicker(DefaultExportInterval)
+	defer ticker.Stop()
+
+	var batch []SpanData
+	for {
+		select {
+		case data := <-t.queue:
+			batch = append(batch, data)
+			if len(batch) >= DefaultBatchSize {
+				t.export(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				t.export(batch)
+				batch = nil
+			}
+		case done := <-t.flushReq:
+			batch = t.drain(batch)
+			if len(batch) > 0 {
+				t.export(batch)
+				batch = nil
+			}
+			close(done)
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+// drain appends all queued spans to batch
+func (t *Tracer) drain(batch []SpanData) []SpanData {
+	for {
+		select {
+		case data := <-t.queue:
+			batch = append(batch, data)
+		default:
+			return batch
+		}
+	}
+}
+
+// export sends a batch to every exporter
+func (t *Tracer) export(batch []SpanData) {
+	t.mu.RLock()
+	exporters := t.exporters
+	t.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, e := range exporters {
+		if err := e.ExportSpans(ctx, batch); err != nil {
+			logger.Warnf("Export %d spans failed: %v", len(batch), err)
+		}
+	}
+}
+
+// Span records one operation of a trace.
+// All methods are safe to call on a nil span.
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+	parent SpanID
+	name   string
+	kind   SpanKind
+	start  time.Time
+
+	mu         sync.Mutex
+	attributes map[string]string
+	status     StatusCode
+	statusMsg  string
+	ended      bool
+}
+
+// SpanContext returns the IDs propagated to child spans
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// TraceID returns the hex trace ID of the span, "" for a nil span
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.sc.TraceID.String()
+}
+
+// SetAttribute records a key-value attribute
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attributes == nil {
+		s.attributes = make(map[string]string)
+	}
+	s.attributes[key] = value
+}
+
+// SetStatus sets the outcome of the span
+func (s *Span) SetStatus(code StatusCode, message string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = code
+	s.statusMsg = message
+}
+
+// SetError marks the span as failed with err; a nil err marks it OK
+func (s *Span) SetError(err error) {
+	if err != nil {
+		s.SetStatus(StatusError, err.Error())
+	} else {
+		s.SetStatus(StatusOK, "")
+	}
+}
+
+// End finishes the span and queues it for export. Only the first call has an effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	// Exporters get a copy, attributes set after End must not race with them
+	var attributes map[string]string
+	if len(s.attributes) > 0 {
+		attributes = make(map[string]string, len(s.attributes))
+		for key, value := range s.attributes {
+			attributes[key] = value
+		}
+	}
+	data := SpanData{
+		TraceID:       s.sc.TraceID.String(),
+		SpanID:        s.sc.SpanID.String(),
+		Name:          s.name,
+		Kind:          s.kind,
+		Service:       s.tracer.service,
+		StartTime:     s.start,
+		EndTime:       time.Now(),
+		Status:        s.status,
+		StatusMessage: s.statusMsg,
+		Attributes:    attributes,
+	}
+	s.mu.Unlock()
+
+	if s.parent.IsValid() {
+		data.ParentSpanID = s.parent.String()
+	}
+	if s.sc.Sampled {
+		s.tracer.enqueue(data)
+	}
+}
+
+// contextKey is the type of context keys defined by this package
+type contextKey int
+
+const (
+	spanKey contextKey = iota
+	remoteKey
+)
+
+// ContextWithSpan returns a context carrying span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey, span)
+}
+
+// SpanFromContext returns the span carried by ctx, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey).(*Span)
+	return span
+}
+
+// ContextWithRemoteSpanContext returns a context whose spans continue the trace of a remote caller
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey, sc)
+}
+
+// SpanContextFromContext returns the span context of the span carried by ctx,
+// falling back to a remote span context
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.sc
+	}
+	sc, _ := ctx.Value(remoteKey).(SpanContext)
+	return sc
+}
@@ -0,0 +1,175 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryExporter collects exported spans
+type memoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func (e *memoryExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *memoryExporter) Shutdown(ctx context.Context) error { return nil }
+
+func (e *memoryExporter) byName() map[string]SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	spans := make(map[string]SpanData)
+	for _, span := range e.spans {
+		spans[span.Name] = span
+	}
+	return spans
+}
+
+func TestTracerParentChild(t *testing.T) {
+	exporter := &memoryExporter{}
+	tracer := NewTracer("test-service", exporter)
+	defer tracer.Shutdown(context.Background())
+
+	ctx, root := tracer.Start(context.Background(), "root", SpanKindClient)
+	_, child := tracer.Start(ctx, "child", SpanKindInternal)
+	child.SetAttribute("key", "value")
+	child.SetError(errors.New("boom"))
+	child.End()
+	root.End()
+	root.End() // second End is ignored
+
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	spans := exporter.byName()
+	if len(exporter.spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(exporter.spans))
+	}
+	if spans["child"].TraceID != spans["root"].TraceID {
+		t.Error("Expected child to share the trace of its parent")
+	}
+	if spans["child"].ParentSpanID != spans["root"].SpanID {
+		t.Error("Expected child to reference the root span")
+	}
+	if spans["root"].ParentSpanID != "" {
+		t.Error("Expected root span without parent")
+	}
+	if spans["child"].Status != StatusError || spans["child"].StatusMessage != "boom" {
+		t.Errorf("Unexpected child status %s %q", spans["child"].Status, spans["child"].StatusMessage)
+	}
+	if spans["child"].Attributes["key"] != "value" || spans["child"].Service != "test-service" {
+		t.Errorf("Unexpected child span %+v", spans["child"])
+	}
+}
+
+func TestTracerRemoteParent(t *testing.T) {
+	exporter := &memoryExporter{}
+	tracer := NewTracer("test-service", exporter)
+	defer tracer.Shutdown(context.Background())
+
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := ContextWithRemoteSpanContext(context.Background(), remote)
+	_, span := tracer.Start(ctx, "server", SpanKindServer)
+	span.End()
+
+	// Unsampled traces are propagated but not exported
+	remote.Sampled = false
+	ctx = ContextWithRemoteSpanContext(context.Background(), remote)
+	_, unsampled := tracer.Start(ctx, "unsampled", SpanKindServer)
+	if unsampled.SpanContext().Sampled {
+		t.Error("Expected unsampled flag to be inherited")
+	}
+	unsampled.End()
+
+	tracer.Flush(context.Background())
+
+	spans := exporter.byName()
+	if _, ok := spans["unsampled"]; ok {
+		t.Error("Expected unsampled span not to be exported")
+	}
+	if spans["server"].TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || spans["server"].ParentSpanID != "00f067aa0ba902b7" {
+		t.Errorf("Expected server span to continue the remote trace, got %+v", spans["server"])
+	}
+}
+
+func TestNilTracerAndSpan(t *testing.T) {
+	var tracer *Tracer
+	ctx, span := tracer.Start(context.Background(), "noop", SpanKindClient)
+	if span != nil || SpanFromContext(ctx) != nil {
+		t.Fatal("Expected nil tracer to return no span")
+	}
+	span.SetAttribute("k", "v")
+	span.SetError(errors.New("ignored"))
+	span.End()
+	if span.SpanContext().IsValid() || span.TraceID() != "" {
+		t.Error("Expected nil span to have an invalid span context")
+	}
+}
+
+func TestSpanAttributesAfterEnd(t *testing.T) {
+	exporter := &memoryExporter{}
+	tracer := NewTracer("svc", exporter)
+	defer tracer.Shutdown(context.Background())
+
+	_, span := tracer.Start(context.Background(), "op", SpanKindInternal)
+	span.SetAttribute("before", "1")
+	span.End()
+	span.SetAttribute("after", "1")
+
+	tracer.Flush(context.Background())
+	attributes := exporter.byName()["op"].Attributes
+	if attributes["before"] != "1" || attributes["after"] != "" {
+		t.Errorf("Expected the attributes at End, got %v", attributes)
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	exporter, err := NewFileExporter(path)
+	if err != nil {
+		t.Fatalf("NewFileExporter failed: %v", err)
+	}
+
+	tracer := NewTracer("file-service", exporter)
+	for _, name := range []string{"first", "second"} {
+		_, span := tracer.Start(context.Background(), name, SpanKindInternal)
+		span.End()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := tracer.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer file.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var span SpanData
+		if err := json.Unmarshal(scanner.Bytes(), &span); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", scanner.Text(), err)
+		}
+		names = append(names, span.Name)
+	}
+	if len(names) != 2 || names[0] != "first" || names[1] != "second" {
+		t.Errorf("Unexpected spans in file: %v", names)
+	}
+}
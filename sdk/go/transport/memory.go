@@ -0,0 +1,445 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// noRespondersStatus is the Status header of the reply sent for requests nobody receives
+const noRespondersStatus = "503"
+
+// MemoryBus is an in-process message bus with KV buckets and object stores.
+// A subscription receives messages from the moment Subscribe returns, in
+// publish order and one at a time on its own goroutine, as on NATS. Queue
+// groups hand messages to their members in turn rather than at random, so
+// tests behave the same on every run.
+type MemoryBus struct {
+	mu      sync.Mutex
+	subs    []*memorySub
+	queues  map[string]int // next member per subject and queue group
+	inboxes uint64
+	kv      map[string]*memoryKeyValue
+	objects map[string]*memoryObjectStore
+}
+
+// NewMemoryBus returns an empty bus
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		queues:  make(map[string]int),
+		kv:      make(map[string]*memoryKeyValue),
+		objects: make(map[string]*memoryObjectStore),
+	}
+}
+
+// Connect returns a new connection to the bus. Connections share the
+// subjects, KV buckets and object stores of the bus.
+func (b *MemoryBus) Connect() *MemoryConn {
+	return &MemoryConn{bus: b, subs: make(map[*memorySub]struct{})}
+}
+
+// publish delivers msg to the matching subscriptions and returns their number
+func (b *MemoryBus) publish(msg *nats.Msg) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var targets []*memorySub
+	groups := make(map[string][]*memorySub)
+	var groupOrder []string
+	for _, sub := range b.subs {
+		if !types.MatchSubject(sub.subject, msg.Subject) {
+			continue
+		}
+		if sub.queue == "" {
+			targets = append(targets, sub)
+			continue
+		}
+		key := sub.subject + " " + sub.queue
+		if _, ok := groups[key]; !ok {
+			groupOrder = append(groupOrder, key)
+		}
+		groups[key] = append(groups[key], sub)
+	}
+	for _, key := range groupOrder {
+		members := groups[key]
+		targets = append(targets, members[b.queues[key]%len(members)])
+		b.queues[key]++
+	}
+
+	for _, sub := range targets {
+		sub.pending.push(copyMsg(msg))
+	}
+	return len(targets)
+}
+
+// remove drops sub from the bus
+func (b *MemoryBus) remove(sub *memorySub) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, s := range b.subs {
+		if s == sub {
+			b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// MemoryConn is a Conn to a MemoryBus
+type MemoryConn struct {
+	bus *MemoryBus
+
+	mu     sync.Mutex
+	subs   map[*memorySub]struct{}
+	closed bool
+}
+
+// Publish implements Conn
+func (c *MemoryConn) Publish(subject string, data []byte) error {
+	return c.PublishMsg(&nats.Msg{Subject: subject, Data: data})
+}
+
+// PublishMsg implements Conn
+func (c *MemoryConn) PublishMsg(msg *nats.Msg) error {
+	if err := c.check(msg.Subject); err != nil {
+		return err
+	}
+	if c.bus.publish(msg) == 0 && msg.Reply != "" {
+		// Like the NATS server, tell the requester that nobody is listening
+		status := nats.NewMsg(msg.Reply)
+		status.Header.Set("Status", noRespondersStatus)
+		c.bus.publish(status)
+	}
+	return nil
+}
+
+// Subscribe implements Conn
+func (c *MemoryConn) Subscribe(subject string, handler nats.MsgHandler) (Subscription, error) {
+	sub, err := c.subscribe(subject, "", handler)
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// QueueSubscribe implements Conn
+func (c *MemoryConn) QueueSubscribe(subject, queue string, handler nats.MsgHandler) (Subscription, error) {
+	sub, err := c.subscribe(subject, queue, handler)
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// SubscribeSync implements Conn
+func (c *MemoryConn) SubscribeSync(subject string) (SyncSubscription, error) {
+	sub, err := c.subscribe(subject, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// RequestMsgWithContext implements Conn. It fails with nats.ErrNoResponders
+// when no subscription matches the subject.
+func (c *MemoryConn) RequestMsgWithContext(ctx context.Context, msg *nats.Msg) (*nats.Msg, error) {
+	if err := c.check(msg.Subject); err != nil {
+		return nil, err
+	}
+	inbox := c.NewRespInbox()
+	sub, err := c.subscribe(inbox, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer sub.Unsubscribe()
+
+	request := copyMsg(msg)
+	request.Reply = inbox
+	if c.bus.publish(request) == 0 {
+		return nil, nats.ErrNoResponders
+	}
+	return sub.NextMsgWithContext(ctx)
+}
+
+// NewRespInbox implements Conn
+func (c *MemoryConn) NewRespInbox() string {
+	c.bus.mu.Lock()
+	defer c.bus.mu.Unlock()
+	c.bus.inboxes++
+	return nats.InboxPrefix + "mem." + strconv.FormatUint(c.bus.inboxes, 10)
+}
+
+// Close implements Conn
+func (c *MemoryConn) Close() {
+	c.mu.Lock()
+	c.closed = true
+	subs := c.subs
+	c.subs = make(map[*memorySub]struct{})
+	c.mu.Unlock()
+
+	for sub := range subs {
+		sub.Unsubscribe()
+	}
+}
+
+// check fails when the connection is closed or subject is empty
+func (c *MemoryConn) check(subject string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nats.ErrConnectionClosed
+	}
+	if subject == "" {
+		return nats.ErrBadSubject
+	}
+	return nil
+}
+
+// subscribe registers a subscription; a nil handler makes it synchronous
+func (c *MemoryConn) subscribe(subject, queue string, handler nats.MsgHandler) (*memorySub, error) {
+	if err := c.check(subject); err != nil {
+		return nil, err
+	}
+	sub := &memorySub{
+		conn:    c,
+		subject: subject,
+		queue:   queue,
+		pending: newQueue(func(msg *nats.Msg) int { return len(msg.Data) }),
+	}
+
+	c.mu.Lock()
+	c.subs[sub] = struct{}{}
+	c.mu.Unlock()
+	c.bus.mu.Lock()
+	c.bus.subs = append(c.bus.subs, sub)
+	c.bus.mu.Unlock()
+
+	if handler != nil {
+		go sub.run(handler)
+	}
+	return sub, nil
+}
+
+// memorySub is a subscription on a MemoryBus
+type memorySub struct {
+	conn    *MemoryConn
+	subject string
+	queue   string
+	pending *queue[*nats.Msg]
+}
+
+// run calls handler for the pending messages until the subscription ends
+func (s *memorySub) run(handler nats.MsgHandler) {
+	for {
+		msg, err := s.pending.next(context.Background())
+		if err != nil {
+			return
+		}
+		handler(msg)
+	}
+}
+
+// Unsubscribe implements Subscription
+func (s *memorySub) Unsubscribe() error {
+	s.detach()
+	s.pending.close(true)
+	return nil
+}
+
+// Drain implements Subscription
+func (s *memorySub) Drain() error {
+	s.detach()
+	s.pending.close(false)
+	return nil
+}
+
+// SetPendingLimits implements Subscription. Messages are buffered without
+// limit until it is called.
+func (s *memorySub) SetPendingLimits(msgLimit, bytesLimit int) error {
+	if msgLimit == 0 || bytesLimit == 0 {
+		return nats.ErrInvalidArg
+	}
+	s.pending.setLimits(msgLimit, bytesLimit)
+	return nil
+}
+
+// NextMsgWithContext implements SyncSubscription
+func (s *memorySub) NextMsgWithContext(ctx context.Context) (*nats.Msg, error) {
+	msg, err := s.pending.next(ctx)
+	if errors.Is(err, errQueueClosed) {
+		return nil, nats.ErrBadSubscription
+	}
+	return msg, err
+}
+
+// detach removes the subscription from the bus and its connection
+func (s *memorySub) detach() {
+	s.conn.bus.remove(s)
+	s.conn.mu.Lock()
+	delete(s.conn.subs, s)
+	s.conn.mu.Unlock()
+}
+
+// errQueueClosed is returned by queue.next once the queue is closed and empty
+var errQueueClosed = errors.New("queue closed")
+
+// queue is a FIFO with a single consumer, unbounded unless limits are set
+type queue[T any] struct {
+	mu       sync.Mutex
+	items    []T
+	size     func(T) int
+	bytes    int
+	maxItems int // limits of the items and their size, <= 0 for none
+	maxBytes int
+	closed   bool
+	ready    chan struct{}
+}
+
+func newQueue[T any](size func(T) int) *queue[T] {
+	return &queue[T]{size: size, ready: make(chan struct{}, 1)}
+}
+
+// setLimits limits the number and size of the items waiting
+func (q *queue[T]) setLimits(maxItems, maxBytes int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.maxItems, q.maxBytes = maxItems, maxBytes
+}
+
+// push appends item unless the queue is closed or full
+func (q *queue[T]) push(item T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	size := q.itemSize(item)
+	if (q.maxItems > 0 && len(q.items) >= q.maxItems) || (q.maxBytes > 0 && q.bytes+size > q.maxBytes) {
+		return
+	}
+	q.items = append(q.items, item)
+	q.bytes += size
+	q.signal()
+}
+
+// close stops accepting items; discard drops the ones not consumed yet
+func (q *queue[T]) close(discard bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	if discard {
+		q.items = nil
+		q.bytes = 0
+	}
+	q.signal()
+}
+
+// itemSize returns the size counted against maxBytes
+func (q *queue[T]) itemSize(item T) int {
+	if q.size == nil {
+		return 0
+	}
+	return q.size(item)
+}
+
+// next waits for the next item
+func (q *queue[T]) next(ctx context.Context) (T, error) {
+	for {
+		q.mu.Lock()
+		if len(q.items) > 0 {
+			item := q.items[0]
+			var zero T
+			q.items[0] = zero
+			q.items = q.items[1:]
+			q.bytes -= q.itemSize(item)
+			q.mu.Unlock()
+			return item, nil
+		}
+		closed := q.closed
+		q.mu.Unlock()
+
+		var zero T
+		if closed {
+			return zero, errQueueClosed
+		}
+		select {
+		case <-q.ready:
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// signal wakes the consumer; caller must hold mu
+func (q *queue[T]) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// copyMsg returns a copy of msg, so receivers never share data with the sender
+func copyMsg(msg *nats.Msg) *nats.Msg {
+	out := &nats.Msg{
+		Subject: msg.Subject,
+		Reply:   msg.Reply,
+		Data:    append([]byte(nil), msg.Data...),
+	}
+	if msg.Header != nil {
+		out.Header = make(nats.Header, len(msg.Header))
+		for key, values := range msg.Header {
+			out.Header[key] = append([]string(nil), values...)
+		}
+	}
+	return out
+}
+
+// KeyValue implements Conn
+func (c *MemoryConn) KeyValue(ctx context.Context, bucket string) (KeyValue, error) {
+	c.bus.mu.Lock()
+	defer c.bus.mu.Unlock()
+	kv, ok := c.bus.kv[bucket]
+	if !ok {
+		return nil, jetstream.ErrBucketNotFound
+	}
+	return kv, nil
+}
+
+// CreateKeyValue implements Conn
+func (c *MemoryConn) CreateKeyValue(ctx context.Context, bucket string) (KeyValue, error) {
+	c.bus.mu.Lock()
+	defer c.bus.mu.Unlock()
+	kv, ok := c.bus.kv[bucket]
+	if !ok {
+		kv = newMemoryKeyValue(bucket)
+		c.bus.kv[bucket] = kv
+	}
+	return kv, nil
+}
+
+// ObjectStore implements Conn
+func (c *MemoryConn) ObjectStore(ctx context.Context, bucket string) (ObjectStore, error) {
+	c.bus.mu.Lock()
+	defer c.bus.mu.Unlock()
+	store, ok := c.bus.objects[bucket]
+	if !ok {
+		return nil, jetstream.ErrBucketNotFound
+	}
+	return store, nil
+}
+
+// CreateObjectStore implements Conn
+func (c *MemoryConn) CreateObjectStore(ctx context.Context, bucket string) (ObjectStore, error) {
+	c.bus.mu.Lock()
+	defer c.bus.mu.Unlock()
+	store, ok := c.bus.objects[bucket]
+	if !ok {
+		store = &memoryObjectStore{bucket: bucket, objects: make(map[string][]byte)}
+		c.bus.objects[bucket] = store
+	}
+	return store, nil
+}
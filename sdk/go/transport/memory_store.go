@@ -0,0 +1,229 @@
+package transport
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/LiteHomeLab/light_link/sdk/go/types"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// memoryKeyValue is a KV bucket of a MemoryBus
+type memoryKeyValue struct {
+	bucket string
+
+	mu       sync.Mutex
+	revision uint64
+	entries  map[string]*memoryEntry // latest revision per key, deletes included
+	watchers map[*memoryWatcher]struct{}
+}
+
+func newMemoryKeyValue(bucket string) *memoryKeyValue {
+	return &memoryKeyValue{
+		bucket:   bucket,
+		entries:  make(map[string]*memoryEntry),
+		watchers: make(map[*memoryWatcher]struct{}),
+	}
+}
+
+// Get implements KeyValue
+func (kv *memoryKeyValue) Get(ctx context.Context, key string) (jetstream.KeyValueEntry, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	entry, ok := kv.entries[key]
+	if !ok || entry.op != jetstream.KeyValuePut {
+		return nil, jetstream.ErrKeyNotFound
+	}
+	return entry, nil
+}
+
+// Put implements KeyValue
+func (kv *memoryKeyValue) Put(ctx context.Context, key string, value []byte) (uint64, error) {
+	entry := kv.store(key, append([]byte(nil), value...), jetstream.KeyValuePut)
+	return entry.revision, nil
+}
+
+// Delete implements KeyValue
+func (kv *memoryKeyValue) Delete(ctx context.Context, key string) error {
+	kv.store(key, nil, jetstream.KeyValueDelete)
+	return nil
+}
+
+// Keys implements KeyValue
+func (kv *memoryKeyValue) Keys(ctx context.Context) ([]string, error) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	var keys []string
+	for key, entry := range kv.entries {
+		if entry.op == jetstream.KeyValuePut {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, jetstream.ErrNoKeysFound
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Watch implements KeyValue
+func (kv *memoryKeyValue) Watch(ctx context.Context, key string) (jetstream.KeyWatcher, error) {
+	w := &memoryWatcher{
+		kv:      kv,
+		key:     key,
+		pending: newQueue[jetstream.KeyValueEntry](nil),
+		updates: make(chan jetstream.KeyValueEntry),
+		done:    make(chan struct{}),
+	}
+
+	kv.mu.Lock()
+	var current []*memoryEntry
+	for _, entry := range kv.entries {
+		if entry.op == jetstream.KeyValuePut && types.MatchSubject(key, entry.key) {
+			current = append(current, entry)
+		}
+	}
+	sort.Slice(current, func(i, j int) bool { return current[i].revision < current[j].revision })
+	for _, entry := range current {
+		w.pending.push(entry)
+	}
+	w.pending.push(nil) // marks the end of the current values
+	kv.watchers[w] = struct{}{}
+	kv.mu.Unlock()
+
+	go w.run(ctx)
+	return w, nil
+}
+
+// store records a new revision of key and notifies the watchers of puts
+func (kv *memoryKeyValue) store(key string, value []byte, op jetstream.KeyValueOp) *memoryEntry {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.revision++
+	entry := &memoryEntry{
+		bucket:   kv.bucket,
+		key:      key,
+		value:    value,
+		revision: kv.revision,
+		created:  time.Now(),
+		op:       op,
+	}
+	kv.entries[key] = entry
+	if op == jetstream.KeyValuePut {
+		for w := range kv.watchers {
+			if types.MatchSubject(w.key, key) {
+				w.pending.push(entry)
+			}
+		}
+	}
+	return entry
+}
+
+// memoryEntry is a revision of a key
+type memoryEntry struct {
+	bucket   string
+	key      string
+	value    []byte
+	revision uint64
+	created  time.Time
+	op       jetstream.KeyValueOp
+}
+
+func (e *memoryEntry) Bucket() string                  { return e.bucket }
+func (e *memoryEntry) Key() string                     { return e.key }
+func (e *memoryEntry) Value() []byte                   { return e.value }
+func (e *memoryEntry) Revision() uint64                { return e.revision }
+func (e *memoryEntry) Created() time.Time              { return e.created }
+func (e *memoryEntry) Delta() uint64                   { return 0 }
+func (e *memoryEntry) Operation() jetstream.KeyValueOp { return e.op }
+
+// memoryWatcher is a KeyWatcher of a memoryKeyValue
+type memoryWatcher struct {
+	kv      *memoryKeyValue
+	key     string
+	pending *queue[jetstream.KeyValueEntry]
+	updates chan jetstream.KeyValueEntry
+	done    chan struct{}
+	once    sync.Once
+}
+
+// Updates implements jetstream.KeyWatcher
+func (w *memoryWatcher) Updates() <-chan jetstream.KeyValueEntry {
+	return w.updates
+}
+
+// Stop implements jetstream.KeyWatcher
+func (w *memoryWatcher) Stop() error {
+	w.once.Do(func() {
+		w.kv.mu.Lock()
+		delete(w.kv.watchers, w)
+		w.kv.mu.Unlock()
+		w.pending.close(true)
+		close(w.done)
+	})
+	return nil
+}
+
+// run forwards the pending entries to Updates until the watcher stops or ctx ends
+func (w *memoryWatcher) run(ctx context.Context) {
+	defer close(w.updates)
+	defer w.Stop()
+	for {
+		entry, err := w.pending.next(ctx)
+		if err != nil {
+			return
+		}
+		select {
+		case w.updates <- entry:
+		case <-w.done:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// memoryObjectStore is an object store of a MemoryBus
+type memoryObjectStore struct {
+	bucket string
+
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+// PutBytes implements ObjectStore
+func (o *memoryObjectStore) PutBytes(ctx context.Context, name string, data []byte) (*jetstream.ObjectInfo, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.objects[name] = append([]byte(nil), data...)
+	return &jetstream.ObjectInfo{
+		ObjectMeta: jetstream.ObjectMeta{Name: name},
+		Bucket:     o.bucket,
+		Size:       uint64(len(data)),
+		ModTime:    time.Now(),
+	}, nil
+}
+
+// GetBytes implements ObjectStore
+func (o *memoryObjectStore) GetBytes(ctx context.Context, name string) ([]byte, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	data, ok := o.objects[name]
+	if !ok {
+		return nil, jetstream.ErrObjectNotFound
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// Delete implements ObjectStore
+func (o *memoryObjectStore) Delete(ctx context.Context, name string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, ok := o.objects[name]; !ok {
+		return jetstream.ErrObjectNotFound
+	}
+	delete(o.objects, name)
+	return nil
+}
@@ -0,0 +1,270 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func TestMemoryPublishSubscribe(t *testing.T) {
+	bus := NewMemoryBus()
+	pub, sub := bus.Connect(), bus.Connect()
+	defer pub.Close()
+	defer sub.Close()
+
+	wildcard, _ := sub.SubscribeSync("orders.*.created")
+	tail, _ := sub.SubscribeSync("orders.>")
+	for _, subject := range []string{"orders.1.created", "orders.2.created", "orders.1.deleted", "users.1.created"} {
+		if err := pub.Publish(subject, []byte(subject)); err != nil {
+			t.Fatalf("Publish failed: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for _, want := range []string{"orders.1.created", "orders.2.created"} {
+		msg, err := wildcard.NextMsgWithContext(ctx)
+		if err != nil || msg.Subject != want || string(msg.Data) != want {
+			t.Fatalf("Expected %s, got %v, %v", want, msg, err)
+		}
+	}
+	for _, want := range []string{"orders.1.created", "orders.2.created", "orders.1.deleted"} {
+		if msg, err := tail.NextMsgWithContext(ctx); err != nil || msg.Subject != want {
+			t.Fatalf("Expected %s, got %v, %v", want, msg, err)
+		}
+	}
+
+	short, cancelShort := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelShort()
+	if _, err := wildcard.NextMsgWithContext(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected no more messages, got %v", err)
+	}
+
+	sub.Close()
+	if _, err := tail.NextMsgWithContext(ctx); !errors.Is(err, nats.ErrBadSubscription) {
+		t.Errorf("Expected closed subscription, got %v", err)
+	}
+	if err := sub.Publish("orders.3.created", nil); !errors.Is(err, nats.ErrConnectionClosed) {
+		t.Errorf("Expected closed connection, got %v", err)
+	}
+}
+
+func TestMemoryQueueGroupTakesTurns(t *testing.T) {
+	bus := NewMemoryBus()
+	conn := bus.Connect()
+	defer conn.Close()
+
+	received := make(chan int, 6)
+	for member := 0; member < 2; member++ {
+		member := member
+		conn.QueueSubscribe("jobs", "workers", func(msg *nats.Msg) { received <- member })
+	}
+	for i := 0; i < 4; i++ {
+		conn.Publish("jobs", nil)
+	}
+
+	counts := map[int]int{}
+	for i := 0; i < 4; i++ {
+		select {
+		case member := <-received:
+			counts[member]++
+		case <-time.After(time.Second):
+			t.Fatal("Message not delivered")
+		}
+	}
+	if counts[0] != 2 || counts[1] != 2 {
+		t.Errorf("Expected two messages per member, got %v", counts)
+	}
+}
+
+func TestMemoryRequest(t *testing.T) {
+	bus := NewMemoryBus()
+	conn := bus.Connect()
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := conn.RequestMsgWithContext(ctx, nats.NewMsg("echo")); !errors.Is(err, nats.ErrNoResponders) {
+		t.Fatalf("Expected no responders, got %v", err)
+	}
+
+	conn.Subscribe("echo", func(msg *nats.Msg) {
+		reply := nats.NewMsg(msg.Reply)
+		reply.Header.Set("X-Echo", msg.Header.Get("X-Echo"))
+		reply.Data = msg.Data
+		conn.PublishMsg(reply)
+	})
+	request := nats.NewMsg("echo")
+	request.Header.Set("X-Echo", "header")
+	request.Data = []byte("hello")
+	reply, err := conn.RequestMsgWithContext(ctx, request)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	if string(reply.Data) != "hello" || reply.Header.Get("X-Echo") != "header" {
+		t.Errorf("Unexpected reply %q, %v", reply.Data, reply.Header)
+	}
+
+	// A plain publish with a reply subject nobody receives gets a 503 status, as on NATS
+	inbox := conn.NewRespInbox()
+	status, _ := conn.SubscribeSync(inbox)
+	conn.PublishMsg(&nats.Msg{Subject: "nobody", Reply: inbox})
+	if msg, err := status.NextMsgWithContext(ctx); err != nil || msg.Header.Get("Status") != "503" {
+		t.Errorf("Expected a no responders status, got %v, %v", msg, err)
+	}
+}
+
+func TestMemoryDrainDeliversPending(t *testing.T) {
+	bus := NewMemoryBus()
+	conn := bus.Connect()
+	defer conn.Close()
+
+	release := make(chan struct{})
+	handled := make(chan string, 3)
+	sub, _ := conn.Subscribe("work", func(msg *nats.Msg) {
+		<-release
+		handled <- string(msg.Data)
+	})
+	conn.Publish("work", []byte("1"))
+	conn.Publish("work", []byte("2"))
+	sub.Drain()
+	conn.Publish("work", []byte("3"))
+	close(release)
+
+	for _, want := range []string{"1", "2"} {
+		select {
+		case got := <-handled:
+			if got != want {
+				t.Errorf("Expected %s, got %s", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Pending message not handled")
+		}
+	}
+	select {
+	case got := <-handled:
+		t.Errorf("Unexpected message %s after drain", got)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestMemoryKeyValue(t *testing.T) {
+	bus := NewMemoryBus()
+	conn := bus.Connect()
+	defer conn.Close()
+	ctx := context.Background()
+
+	if _, err := conn.KeyValue(ctx, "state"); !errors.Is(err, jetstream.ErrBucketNotFound) {
+		t.Fatalf("Expected missing bucket, got %v", err)
+	}
+	kv, _ := conn.CreateKeyValue(ctx, "state")
+	if _, err := kv.Keys(ctx); !errors.Is(err, jetstream.ErrNoKeysFound) {
+		t.Errorf("Expected no keys, got %v", err)
+	}
+
+	kv.Put(ctx, "service.math", []byte("v1"))
+	watcher, err := kv.Watch(ctx, "service.*")
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	defer watcher.Stop()
+
+	// Shared between connections
+	other, _ := bus.Connect().KeyValue(ctx, "state")
+	other.Put(ctx, "service.math", []byte("v2"))
+	other.Put(ctx, "config", []byte("ignored"))
+	other.Delete(ctx, "service.math")
+
+	var updates []string
+	for len(updates) < 3 {
+		select {
+		case entry := <-watcher.Updates():
+			if entry == nil {
+				updates = append(updates, "<nil>")
+			} else {
+				updates = append(updates, string(entry.Value()))
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Missing updates, got %v", updates)
+		}
+	}
+	if updates[0] != "v1" || updates[1] != "<nil>" || updates[2] != "v2" {
+		t.Errorf("Unexpected updates %v", updates)
+	}
+
+	if _, err := kv.Get(ctx, "service.math"); !errors.Is(err, jetstream.ErrKeyNotFound) {
+		t.Errorf("Expected deleted key, got %v", err)
+	}
+	if keys, err := kv.Keys(ctx); err != nil || len(keys) != 1 || keys[0] != "config" {
+		t.Errorf("Expected [config], got %v, %v", keys, err)
+	}
+}
+
+func TestMemoryObjectStore(t *testing.T) {
+	conn := NewMemoryBus().Connect()
+	defer conn.Close()
+	ctx := context.Background()
+
+	if _, err := conn.ObjectStore(ctx, "files"); !errors.Is(err, jetstream.ErrBucketNotFound) {
+		t.Fatalf("Expected missing store, got %v", err)
+	}
+	store, _ := conn.CreateObjectStore(ctx, "files")
+	if info, err := store.PutBytes(ctx, "a.txt", []byte("content")); err != nil || info.Size != 7 {
+		t.Fatalf("PutBytes failed: %v, %v", info, err)
+	}
+	if data, err := store.GetBytes(ctx, "a.txt"); err != nil || string(data) != "content" {
+		t.Errorf("Unexpected object %q, %v", data, err)
+	}
+	store.Delete(ctx, "a.txt")
+	if _, err := store.GetBytes(ctx, "a.txt"); !errors.Is(err, jetstream.ErrObjectNotFound) {
+		t.Errorf("Expected missing object, got %v", err)
+	}
+}
+
+func TestMemoryPendingLimits(t *testing.T) {
+	conn := NewMemoryBus().Connect()
+	defer conn.Close()
+
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	handled := make(chan string, 5)
+	sub, _ := conn.Subscribe("work", func(msg *nats.Msg) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		handled <- string(msg.Data)
+	})
+	if err := sub.SetPendingLimits(0, 0); !errors.Is(err, nats.ErrInvalidArg) {
+		t.Errorf("Expected invalid limits, got %v", err)
+	}
+	sub.SetPendingLimits(2, -1)
+
+	// The handler holds the first message, two wait and the last is dropped
+	conn.Publish("work", []byte("1"))
+	<-started
+	for _, data := range []string{"2", "3", "4"} {
+		conn.Publish("work", []byte(data))
+	}
+	close(release)
+
+	var got []string
+	for len(got) < 3 {
+		select {
+		case data := <-handled:
+			got = append(got, data)
+		case <-time.After(time.Second):
+			t.Fatalf("Expected 3 messages, got %v", got)
+		}
+	}
+	select {
+	case data := <-handled:
+		t.Errorf("Expected message %s to be dropped", data)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
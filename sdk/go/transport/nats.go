@@ -0,0 +1,174 @@
+package transport
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// NATS is the Conn of a NATS connection, using JetStream for KV and object stores
+type NATS struct {
+	nc *nats.Conn
+}
+
+// NewNATS returns the Conn of nc
+func NewNATS(nc *nats.Conn) *NATS {
+	return &NATS{nc: nc}
+}
+
+// Conn returns the NATS connection
+func (n *NATS) Conn() *nats.Conn {
+	return n.nc
+}
+
+// Publish implements Conn
+func (n *NATS) Publish(subject string, data []byte) error {
+	return n.nc.Publish(subject, data)
+}
+
+// PublishMsg implements Conn
+func (n *NATS) PublishMsg(msg *nats.Msg) error {
+	return n.nc.PublishMsg(msg)
+}
+
+// Subscribe implements Conn
+func (n *NATS) Subscribe(subject string, handler nats.MsgHandler) (Subscription, error) {
+	sub, err := n.nc.Subscribe(subject, handler)
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// QueueSubscribe implements Conn
+func (n *NATS) QueueSubscribe(subject, queue string, handler nats.MsgHandler) (Subscription, error) {
+	sub, err := n.nc.QueueSubscribe(subject, queue, handler)
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// SubscribeSync implements Conn
+func (n *NATS) SubscribeSync(subject string) (SyncSubscription, error) {
+	sub, err := n.nc.SubscribeSync(subject)
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// RequestMsgWithContext implements Conn
+func (n *NATS) RequestMsgWithContext(ctx context.Context, msg *nats.Msg) (*nats.Msg, error) {
+	return n.nc.RequestMsgWithContext(ctx, msg)
+}
+
+// NewRespInbox implements Conn
+func (n *NATS) NewRespInbox() string {
+	return n.nc.NewRespInbox()
+}
+
+// KeyValue implements Conn
+func (n *NATS) KeyValue(ctx context.Context, bucket string) (KeyValue, error) {
+	js, err := jetstream.New(n.nc)
+	if err != nil {
+		return nil, err
+	}
+	kv, err := js.KeyValue(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	return natsKeyValue{kv}, nil
+}
+
+// CreateKeyValue implements Conn
+func (n *NATS) CreateKeyValue(ctx context.Context, bucket string) (KeyValue, error) {
+	js, err := jetstream.New(n.nc)
+	if err != nil {
+		return nil, err
+	}
+	kv, err := js.CreateKeyValue(ctx, jetstream.KeyValueConfig{Bucket: bucket})
+	if err != nil {
+		return nil, err
+	}
+	return natsKeyValue{kv}, nil
+}
+
+// ObjectStore implements Conn
+func (n *NATS) ObjectStore(ctx context.Context, bucket string) (ObjectStore, error) {
+	js, err := jetstream.New(n.nc)
+	if err != nil {
+		return nil, err
+	}
+	store, err := js.ObjectStore(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	return natsObjectStore{store}, nil
+}
+
+// CreateObjectStore implements Conn
+func (n *NATS) CreateObjectStore(ctx context.Context, bucket string) (ObjectStore, error) {
+	js, err := jetstream.New(n.nc)
+	if err != nil {
+		return nil, err
+	}
+	store, err := js.CreateObjectStore(ctx, jetstream.ObjectStoreConfig{Bucket: bucket})
+	if err != nil {
+		return nil, err
+	}
+	return natsObjectStore{store}, nil
+}
+
+// FlushWithContext waits until the server processed the buffered messages
+func (n *NATS) FlushWithContext(ctx context.Context) error {
+	return n.nc.FlushWithContext(ctx)
+}
+
+// Close implements Conn
+func (n *NATS) Close() {
+	n.nc.Close()
+}
+
+// natsKeyValue adapts a JetStream KV bucket
+type natsKeyValue struct {
+	kv jetstream.KeyValue
+}
+
+func (k natsKeyValue) Get(ctx context.Context, key string) (jetstream.KeyValueEntry, error) {
+	return k.kv.Get(ctx, key)
+}
+
+func (k natsKeyValue) Put(ctx context.Context, key string, value []byte) (uint64, error) {
+	return k.kv.Put(ctx, key, value)
+}
+
+func (k natsKeyValue) Delete(ctx context.Context, key string) error {
+	return k.kv.Delete(ctx, key)
+}
+
+func (k natsKeyValue) Keys(ctx context.Context) ([]string, error) {
+	return k.kv.Keys(ctx)
+}
+
+func (k natsKeyValue) Watch(ctx context.Context, key string) (jetstream.KeyWatcher, error) {
+	return k.kv.Watch(ctx, key, jetstream.IgnoreDeletes())
+}
+
+// natsObjectStore adapts a JetStream object store
+type natsObjectStore struct {
+	store jetstream.ObjectStore
+}
+
+func (o natsObjectStore) PutBytes(ctx context.Context, name string, data []byte) (*jetstream.ObjectInfo, error) {
+	return o.store.PutBytes(ctx, name, data)
+}
+
+func (o natsObjectStore) GetBytes(ctx context.Context, name string) ([]byte, error) {
+	return o.store.GetBytes(ctx, name)
+}
+
+func (o natsObjectStore) Delete(ctx context.Context, name string) error {
+	return o.store.Delete(ctx, name)
+}
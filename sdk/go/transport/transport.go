@@ -0,0 +1,91 @@
+// Package transport abstracts the messaging connection used by client.Client
+// and service.Service. NewNATS adapts a NATS connection; NewMemoryBus connects
+// clients and services in process, so handlers can be tested without a broker:
+//
+//	bus := transport.NewMemoryBus()
+//	svc, _ := service.NewService("math", "", service.WithServiceTransport(bus.Connect()))
+//	cli, _ := client.NewClient("", client.WithTransport(bus.Connect()))
+//
+// Messages are *nats.Msg on both transports and errors are the nats and
+// jetstream sentinels, e.g. nats.ErrNoResponders and jetstream.ErrKeyNotFound.
+package transport
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Conn is a connection to the message bus
+type Conn interface {
+	// Publish sends data to subject
+	Publish(subject string, data []byte) error
+	// PublishMsg sends msg with its headers and reply subject
+	PublishMsg(msg *nats.Msg) error
+	// Subscribe calls handler for the messages matching subject, one at a time
+	Subscribe(subject string, handler nats.MsgHandler) (Subscription, error)
+	// QueueSubscribe is Subscribe where each message goes to one member of the queue group
+	QueueSubscribe(subject, queue string, handler nats.MsgHandler) (Subscription, error)
+	// SubscribeSync subscribes to subject, messages are read with NextMsgWithContext
+	SubscribeSync(subject string) (SyncSubscription, error)
+	// RequestMsgWithContext sends msg and waits for the first reply
+	RequestMsgWithContext(ctx context.Context, msg *nats.Msg) (*nats.Msg, error)
+	// NewRespInbox returns a unique reply subject
+	NewRespInbox() string
+
+	// KeyValue returns an existing KV bucket, or jetstream.ErrBucketNotFound
+	KeyValue(ctx context.Context, bucket string) (KeyValue, error)
+	// CreateKeyValue returns the KV bucket, creating it if missing
+	CreateKeyValue(ctx context.Context, bucket string) (KeyValue, error)
+	// ObjectStore returns an existing object store, or jetstream.ErrBucketNotFound
+	ObjectStore(ctx context.Context, bucket string) (ObjectStore, error)
+	// CreateObjectStore returns the object store, creating it if missing
+	CreateObjectStore(ctx context.Context, bucket string) (ObjectStore, error)
+
+	// Close closes the connection and drops its subscriptions
+	Close()
+}
+
+// Subscription is an asynchronous subscription
+type Subscription interface {
+	// Unsubscribe stops the delivery immediately
+	Unsubscribe() error
+	// Drain stops receiving new messages and unsubscribes once the pending ones are handled
+	Drain() error
+	// SetPendingLimits limits the messages and bytes buffered for a slow
+	// handler; messages beyond them are dropped. A negative value removes the limit.
+	SetPendingLimits(msgLimit, bytesLimit int) error
+}
+
+// SyncSubscription is a subscription read by the caller
+type SyncSubscription interface {
+	Subscription
+	// NextMsgWithContext waits for the next message
+	NextMsgWithContext(ctx context.Context) (*nats.Msg, error)
+}
+
+// KeyValue is a key-value bucket
+type KeyValue interface {
+	// Get returns the latest value of key, or jetstream.ErrKeyNotFound
+	Get(ctx context.Context, key string) (jetstream.KeyValueEntry, error)
+	// Put stores value and returns its revision
+	Put(ctx context.Context, key string, value []byte) (uint64, error)
+	// Delete removes key
+	Delete(ctx context.Context, key string) error
+	// Keys returns the keys holding a value, or jetstream.ErrNoKeysFound
+	Keys(ctx context.Context) ([]string, error)
+	// Watch sends the current values of the keys matching key, then a nil
+	// entry, then every update. Deletes are not sent.
+	Watch(ctx context.Context, key string) (jetstream.KeyWatcher, error)
+}
+
+// ObjectStore stores named blobs
+type ObjectStore interface {
+	// PutBytes stores data under name, replacing an existing object
+	PutBytes(ctx context.Context, name string, data []byte) (*jetstream.ObjectInfo, error)
+	// GetBytes returns the object, or jetstream.ErrObjectNotFound
+	GetBytes(ctx context.Context, name string) ([]byte, error)
+	// Delete removes the object
+	Delete(ctx context.Context, name string) error
+}
@@ -0,0 +1,299 @@
+package types
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// ========================================
+// Certificate Auto-Discovery
+// ========================================
+
+// 证书发现相关常量
+const (
+	// DefaultClientCertDir 默认客户端证书目录
+	DefaultClientCertDir = "./client"
+	// DefaultServerCertDir 默认服务器证书目录
+	DefaultServerCertDir = "./nats-server"
+	// DefaultServerName 默认服务器名称
+	DefaultServerName = "nats-server"
+
+	// SystemCertDir is the system wide certificate directory searched last
+	SystemCertDir = "/etc/lightlink"
+)
+
+// Environment variables read by certificate discovery
+const (
+	// EnvCertDir is a directory searched before all others
+	EnvCertDir = "LIGHTLINK_CERT_DIR"
+	// EnvCAFile is the CA file used instead of the one next to the certificate
+	EnvCAFile = "LIGHTLINK_CA_FILE"
+	// EnvCertPassword is the password of PKCS#12 bundles
+	EnvCertPassword = "LIGHTLINK_CERT_PASSWORD"
+)
+
+// CertKind selects the certificates to discover
+type CertKind string
+
+const (
+	// ClientCerts are the certificates of clients and services connecting to NATS
+	ClientCerts CertKind = "client"
+	// ServerCerts are the certificates of the NATS server
+	ServerCerts CertKind = "server"
+)
+
+// dirName returns the directory the certificates of the kind are deployed in
+func (k CertKind) dirName() string {
+	if k == ServerCerts {
+		return filepath.Base(DefaultServerCertDir)
+	}
+	return filepath.Base(DefaultClientCertDir)
+}
+
+// CertDiscoveryOptions customizes where certificates are searched and how the
+// files are named. The zero value searches the default locations for the
+// default file names.
+type CertDiscoveryOptions struct {
+	// Dirs are searched after LIGHTLINK_CERT_DIR and before the default locations
+	Dirs []string
+	// CAFile, CertFile and KeyFile are the file names looked for in each
+	// directory; they default to ca.crt, <kind>.crt and <kind>.key
+	CAFile   string
+	CertFile string
+	KeyFile  string
+	// BundleFiles are the PKCS#12 file names tried when no certificate and key
+	// are found; they default to <kind>.p12 and <kind>.pfx
+	BundleFiles []string
+	// BundlePassword decrypts the bundle; it defaults to LIGHTLINK_CERT_PASSWORD
+	BundlePassword string
+	// ServerName is the expected server name; it defaults to DefaultServerName
+	ServerName string
+}
+
+// CertDiscoveryResult 证书发现结果
+type CertDiscoveryResult struct {
+	CaFile     string
+	CertFile   string
+	KeyFile    string
+	ServerName string
+	Found      bool
+
+	// PKCS12File is set instead of CertFile and KeyFile when a bundle was found
+	PKCS12File     string
+	PKCS12Password string
+	// Searched lists the directories searched, in order
+	Searched []string
+}
+
+// CertNotFoundError is returned when no directory holds the certificates
+type CertNotFoundError struct {
+	Kind  CertKind
+	Tried []string // every CA, certificate, key and bundle file tried, in order
+}
+
+func (e *CertNotFoundError) Error() string {
+	return fmt.Sprintf("%s certificates not found in search paths: %s", e.Kind, strings.Join(e.Tried, ", "))
+}
+
+// DiscoverClientCerts 在默认位置自动发现客户端证书
+// 搜索顺序: $LIGHTLINK_CERT_DIR -> ./client -> ../client ... ../../../../../client
+// -> <用户配置目录>/lightlink/client -> /etc/lightlink/client
+func DiscoverClientCerts() (*CertDiscoveryResult, error) {
+	return DiscoverCerts(ClientCerts, CertDiscoveryOptions{})
+}
+
+// DiscoverServerCerts 在默认位置自动发现服务器证书
+// 搜索顺序: $LIGHTLINK_CERT_DIR -> ./nats-server -> ../nats-server ... ../../../../../nats-server
+// -> <用户配置目录>/lightlink/nats-server -> /etc/lightlink/nats-server
+func DiscoverServerCerts() (*CertDiscoveryResult, error) {
+	return DiscoverCerts(ServerCerts, CertDiscoveryOptions{})
+}
+
+// DiscoverCerts searches the certificates of kind. Each directory is checked
+// for a certificate and key, then for a PKCS#12 bundle. The CA file must sit
+// next to a certificate and key; bundles may carry the CA themselves. When
+// LIGHTLINK_CA_FILE is set it replaces the CA file of every directory.
+// A *CertNotFoundError listing every path tried is returned when nothing is found.
+func DiscoverCerts(kind CertKind, opts CertDiscoveryOptions) (*CertDiscoveryResult, error) {
+	opts = opts.withDefaults(kind)
+	dirs := certSearchDirs(kind, opts.Dirs)
+
+	caFile := os.Getenv(EnvCAFile)
+	if caFile != "" && !fileExists(caFile) {
+		return nil, &CertNotFoundError{Kind: kind, Tried: []string{caFile}}
+	}
+
+	var tried []string
+	if caFile != "" {
+		tried = append(tried, caFile)
+	}
+	for _, dir := range dirs {
+		result, files := checkCertDirectory(dir, caFile, opts)
+		if result.Found {
+			result.Searched = dirs
+			return result, nil
+		}
+		tried = append(tried, files...)
+	}
+	return nil, &CertNotFoundError{Kind: kind, Tried: tried}
+}
+
+// withDefaults fills in the default file names of kind
+func (o CertDiscoveryOptions) withDefaults(kind CertKind) CertDiscoveryOptions {
+	if o.CAFile == "" {
+		o.CAFile = "ca.crt"
+	}
+	if o.CertFile == "" {
+		o.CertFile = string(kind) + ".crt"
+	}
+	if o.KeyFile == "" {
+		o.KeyFile = string(kind) + ".key"
+	}
+	if len(o.BundleFiles) == 0 {
+		o.BundleFiles = []string{string(kind) + ".p12", string(kind) + ".pfx"}
+	}
+	if o.BundlePassword == "" {
+		o.BundlePassword = os.Getenv(EnvCertPassword)
+	}
+	if o.ServerName == "" {
+		o.ServerName = DefaultServerName
+	}
+	return o
+}
+
+// certSearchDirs returns the directories searched for kind, in order
+func certSearchDirs(kind CertKind, extra []string) []string {
+	sub := kind.dirName()
+
+	var dirs []string
+	if dir := os.Getenv(EnvCertDir); dir != "" {
+		dirs = append(dirs, dir, filepath.Join(dir, sub))
+	}
+	dirs = append(dirs, extra...)
+
+	// The working directory and its parents, for checkouts and examples
+	dirs = append(dirs, "./"+sub)
+	parent := ""
+	for i := 0; i < 5; i++ {
+		parent += "../"
+		dirs = append(dirs, parent+sub)
+	}
+
+	if configDir, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(configDir, "lightlink", sub))
+	}
+	return append(dirs, filepath.Join(SystemCertDir, sub))
+}
+
+// checkCertDirectory 检查目录中的证书文件是否存在, 并返回检查过的文件
+func checkCertDirectory(dir, caFile string, opts CertDiscoveryOptions) (*CertDiscoveryResult, []string) {
+	var tried []string
+	if caFile == "" {
+		dirCA := filepath.Join(dir, opts.CAFile)
+		if filepath.IsAbs(opts.CAFile) {
+			dirCA = opts.CAFile
+		}
+		tried = append(tried, dirCA)
+		if fileExists(dirCA) {
+			caFile = dirCA
+		}
+	}
+
+	certFile := filepath.Join(dir, opts.CertFile)
+	keyFile := filepath.Join(dir, opts.KeyFile)
+	tried = append(tried, certFile, keyFile)
+	if caFile != "" && fileExists(certFile) && fileExists(keyFile) {
+		return &CertDiscoveryResult{
+			CaFile:     caFile,
+			CertFile:   certFile,
+			KeyFile:    keyFile,
+			ServerName: opts.ServerName,
+			Found:      true,
+		}, tried
+	}
+
+	for _, name := range opts.BundleFiles {
+		bundle := filepath.Join(dir, name)
+		tried = append(tried, bundle)
+		if fileExists(bundle) {
+			return &CertDiscoveryResult{
+				CaFile:         caFile,
+				PKCS12File:     bundle,
+				PKCS12Password: opts.BundlePassword,
+				ServerName:     opts.ServerName,
+				Found:          true,
+			}, tried
+		}
+	}
+
+	return &CertDiscoveryResult{Found: false}, tried
+}
+
+// fileExists 检查文件是否存在
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return !info.IsDir()
+}
+
+// CertDiscoveryResultToTLSConfig 将发现结果转换为 TLSConfig
+func CertDiscoveryResultToTLSConfig(result *CertDiscoveryResult) *TLSConfig {
+	return &TLSConfig{
+		CaFile:         result.CaFile,
+		CertFile:       result.CertFile,
+		KeyFile:        result.KeyFile,
+		ServerName:     result.ServerName,
+		PKCS12File:     result.PKCS12File,
+		PKCS12Password: result.PKCS12Password,
+	}
+}
+
+// LoadPKCS12 loads the certificate and private key of a .p12/.pfx bundle.
+// Certificates in the bundle other than the leaf are returned as CA
+// certificates. Both legacy 3DES/RC2 and AES encrypted bundles are supported.
+func LoadPKCS12(path, password string) (*tls.Certificate, []*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read PKCS#12 file: %w", err)
+	}
+	privateKey, first, rest, err := pkcs12.DecodeChain(data, password)
+	if err != nil {
+		return nil, nil, fmt.Errorf("decode PKCS#12 file %s: %w", path, err)
+	}
+	key, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported private key type %T in %s", privateKey, path)
+	}
+
+	// The leaf is the certificate matching the key; the others are CAs
+	var leaf *x509.Certificate
+	var cas []*x509.Certificate
+	for _, cert := range append([]*x509.Certificate{first}, rest...) {
+		if pub, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool }); ok && leaf == nil && pub.Equal(key.Public()) {
+			leaf = cert
+			continue
+		}
+		cas = append(cas, cert)
+	}
+	if leaf == nil {
+		return nil, nil, fmt.Errorf("no certificate matching the private key found in %s", path)
+	}
+
+	tlsCert := &tls.Certificate{Certificate: [][]byte{leaf.Raw}, PrivateKey: key, Leaf: leaf}
+	for _, ca := range cas {
+		// Send intermediates along; self-signed roots stay with the server
+		if ca.CheckSignatureFrom(ca) != nil {
+			tlsCert.Certificate = append(tlsCert.Certificate, ca.Raw)
+		}
+	}
+	return tlsCert, cas, nil
+}
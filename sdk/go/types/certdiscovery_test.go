@@ -0,0 +1,162 @@
+package types
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// isolateCertSearch points every default search location at empty temp dirs
+func isolateCertSearch(t *testing.T) string {
+	tempDir := t.TempDir()
+	t.Setenv(EnvCertDir, "")
+	t.Setenv(EnvCAFile, "")
+	t.Setenv(EnvCertPassword, "")
+	t.Setenv("HOME", tempDir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(tempDir, "config"))
+	t.Setenv("APPDATA", filepath.Join(tempDir, "config"))
+
+	originalWd, _ := os.Getwd()
+	t.Cleanup(func() { os.Chdir(originalWd) })
+	os.Chdir(tempDir)
+	return tempDir
+}
+
+func TestDiscoverCertsEnvDirCustomNames(t *testing.T) {
+	isolateCertSearch(t)
+	certDir := t.TempDir()
+	os.WriteFile(filepath.Join(certDir, "root.pem"), []byte("ca content"), 0644)
+	os.WriteFile(filepath.Join(certDir, "svc.pem"), []byte("cert content"), 0644)
+	os.WriteFile(filepath.Join(certDir, "svc-key.pem"), []byte("key content"), 0644)
+	t.Setenv(EnvCertDir, certDir)
+
+	result, err := DiscoverCerts(ClientCerts, CertDiscoveryOptions{
+		CAFile:     "root.pem",
+		CertFile:   "svc.pem",
+		KeyFile:    "svc-key.pem",
+		ServerName: "nats.internal",
+	})
+	if err != nil {
+		t.Fatalf("DiscoverCerts failed: %v", err)
+	}
+	if result.CertFile != filepath.Join(certDir, "svc.pem") || result.CaFile != filepath.Join(certDir, "root.pem") {
+		t.Errorf("Unexpected files %+v", result)
+	}
+	if result.ServerName != "nats.internal" {
+		t.Errorf("Expected ServerName=nats.internal, got=%s", result.ServerName)
+	}
+}
+
+func TestDiscoverCertsUserConfigDir(t *testing.T) {
+	tempDir := isolateCertSearch(t)
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		t.Skip("No user config dir:", err)
+	}
+	clientDir := filepath.Join(configDir, "lightlink", "client")
+	os.MkdirAll(clientDir, 0755)
+	os.WriteFile(filepath.Join(clientDir, "client.crt"), []byte("cert content"), 0644)
+	os.WriteFile(filepath.Join(clientDir, "client.key"), []byte("key content"), 0644)
+
+	// The CA comes from the environment, so the directory needs none
+	caFile := filepath.Join(tempDir, "shared-ca.crt")
+	os.WriteFile(caFile, []byte("ca content"), 0644)
+	t.Setenv(EnvCAFile, caFile)
+
+	result, err := DiscoverClientCerts()
+	if err != nil {
+		t.Fatalf("DiscoverClientCerts failed: %v", err)
+	}
+	if result.CaFile != caFile || result.KeyFile != filepath.Join(clientDir, "client.key") {
+		t.Errorf("Unexpected files %+v", result)
+	}
+}
+
+func TestDiscoverCertsPKCS12(t *testing.T) {
+	bundle, err := os.ReadFile("testdata/client.p12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tempDir := isolateCertSearch(t)
+	serverDir := filepath.Join(tempDir, "bundles")
+	os.MkdirAll(serverDir, 0755)
+	os.WriteFile(filepath.Join(serverDir, "identity.pfx"), bundle, 0600)
+	t.Setenv(EnvCertPassword, "secret")
+
+	result, err := DiscoverCerts(ServerCerts, CertDiscoveryOptions{
+		Dirs:        []string{serverDir},
+		BundleFiles: []string{"identity.pfx"},
+	})
+	if err != nil {
+		t.Fatalf("DiscoverCerts failed: %v", err)
+	}
+	if result.PKCS12File != filepath.Join(serverDir, "identity.pfx") || result.PKCS12Password != "secret" || result.CertFile != "" {
+		t.Errorf("Unexpected result %+v", result)
+	}
+
+	cert, cas, err := LoadPKCS12(result.PKCS12File, result.PKCS12Password)
+	if err != nil {
+		t.Fatalf("LoadPKCS12 failed: %v", err)
+	}
+	if cert.Leaf.Subject.CommonName != "test-client" || cert.PrivateKey == nil {
+		t.Errorf("Unexpected certificate %s", cert.Leaf.Subject)
+	}
+	if len(cas) != 1 || cas[0].Subject.CommonName != "LightLink Test CA" {
+		t.Errorf("Expected the bundled CA, got %d certificates", len(cas))
+	}
+
+	if _, _, err := LoadPKCS12(result.PKCS12File, "wrong"); err == nil {
+		t.Error("Expected error for a wrong password")
+	}
+
+	// Bundles exported with AES, the OpenSSL 3 default, load as well
+	modern, err := pkcs12.Modern.Encode(cert.PrivateKey, cert.Leaf, cas, "secret")
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	modernFile := filepath.Join(tempDir, "modern.p12")
+	os.WriteFile(modernFile, modern, 0600)
+	if cert, _, err := LoadPKCS12(modernFile, "secret"); err != nil || cert.Leaf.Subject.CommonName != "test-client" {
+		t.Errorf("LoadPKCS12 of an AES bundle failed: %v", err)
+	}
+}
+
+func TestDiscoverCertsNotFoundListsPaths(t *testing.T) {
+	tempDir := isolateCertSearch(t)
+	envDir := filepath.Join(tempDir, "certs")
+	t.Setenv(EnvCertDir, envDir)
+
+	configDir, _ := os.UserConfigDir()
+	_, err := DiscoverClientCerts()
+	var notFound *CertNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("Expected CertNotFoundError, got %v", err)
+	}
+
+	want := []string{
+		filepath.Join(envDir, "ca.crt"),
+		filepath.Join(envDir, "client", "client.crt"),
+		filepath.Join("client", "client.key"),
+		filepath.Join("../../../../../client", "client.p12"),
+		filepath.Join(configDir, "lightlink", "client", "client.pfx"),
+		filepath.Join(SystemCertDir, "client", "ca.crt"),
+	}
+	for _, path := range want {
+		found := false
+		for _, tried := range notFound.Tried {
+			found = found || tried == path
+		}
+		if !found {
+			t.Errorf("Expected %s in tried paths %v", path, notFound.Tried)
+		}
+	}
+
+	// A missing CA override is reported instead of silently ignored
+	t.Setenv(EnvCAFile, filepath.Join(tempDir, "missing-ca.crt"))
+	if _, err := DiscoverClientCerts(); !errors.As(err, &notFound) || notFound.Tried[0] != filepath.Join(tempDir, "missing-ca.crt") {
+		t.Errorf("Expected the CA file in the error, got %v", err)
+	}
+}
@@ -0,0 +1,446 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix is the prefix of the environment variables overriding config files
+const EnvPrefix = "LIGHTLINK_"
+
+// Config 配置
+//
+// Config is read from YAML or JSON files by LoadConfig. Both formats use the
+// json field names, e.g.
+//
+//	nats_url: tls://nats.example.com:4222
+//	service_name: math-service
+//	reconnect:
+//	  wait: 2s
+//	  max_reconnects: -1
+//	tls:
+//	  ca_file: client/ca.crt
+//	  cert_file: client/client.crt
+//	  key_file: client/client.key
+//	heartbeat:
+//	  interval: 15s
+type Config struct {
+	NATSURL     string     `json:"nats_url"`
+	ServiceName string     `json:"service_name"`
+	TLS         *TLSConfig `json:"tls,omitempty"`
+
+	// Servers are additional seed URLs
+	Servers []string `json:"servers,omitempty"`
+	// Name is the connection name shown by the NATS server
+	Name string `json:"name,omitempty"`
+	// AutoTLS discovers the client certificates instead of using TLS
+	AutoTLS     bool            `json:"auto_tls,omitempty"`
+	Reconnect   ReconnectConfig `json:"reconnect,omitempty"`
+	Auth        AuthConfig      `json:"auth,omitempty"`
+	InboxPrefix string          `json:"inbox_prefix,omitempty"`
+	Heartbeat   HeartbeatConfig `json:"heartbeat,omitempty"`
+	// Metadata is registered by services when they start
+	Metadata *MetadataConfig `json:"metadata,omitempty"`
+}
+
+// ReconnectConfig configures reconnecting after the connection is lost
+type ReconnectConfig struct {
+	Wait Duration `json:"wait,omitempty"`
+	// MaxReconnects caps reconnect attempts, -1 reconnects forever; unset keeps the default
+	MaxReconnects *int `json:"max_reconnects,omitempty"`
+}
+
+// AuthConfig holds the credentials; use only one way of authentication
+type AuthConfig struct {
+	User      string `json:"user,omitempty"`
+	Password  string `json:"password,omitempty"`
+	Token     string `json:"token,omitempty"`
+	NKeyFile  string `json:"nkey_file,omitempty"`
+	CredsFile string `json:"creds_file,omitempty"`
+}
+
+// HeartbeatConfig configures the heartbeat of services
+type HeartbeatConfig struct {
+	Interval Duration `json:"interval,omitempty"`
+}
+
+// MetadataConfig is the service metadata registered on start; methods are
+// taken from the methods registered with metadata
+type MetadataConfig struct {
+	Version     string   `json:"version,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Author      string   `json:"author,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as "2s" or "1m30s".
+// Plain numbers are seconds.
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string or a number of seconds
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case float64:
+		*d = Duration(v * float64(time.Second))
+		return nil
+	case string:
+		if parsed, err := time.ParseDuration(v); err == nil {
+			*d = Duration(parsed)
+			return nil
+		}
+	case nil:
+		*d = 0
+		return nil
+	}
+	return fmt.Errorf("invalid duration %s, use e.g. \"30s\"", data)
+}
+
+// MarshalJSON writes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// ConfigError reports an invalid config field
+type ConfigError struct {
+	Field   string // json path of the field, e.g. "tls.cert_file" or an environment variable
+	Message string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid config field %q: %s", e.Field, e.Message)
+}
+
+// LoadConfig reads a YAML (.yaml, .yml) or JSON (.json) config file, applies the
+// LIGHTLINK_* environment overrides and validates the result. An empty path
+// builds the config from the environment alone. Relative file paths in the
+// file are relative to its directory; those from the environment stay
+// relative to the working directory.
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read config: %w", err)
+		}
+		if err := parseConfig(path, data, cfg); err != nil {
+			return nil, fmt.Errorf("parse config %s: %w", path, err)
+		}
+		cfg.resolvePaths(filepath.Dir(path))
+	}
+
+	if err := cfg.ApplyEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// parseConfig decodes data in the format named by the extension of path.
+// YAML is converted to JSON first so both formats share the json field names.
+func parseConfig(path string, data []byte, cfg *Config) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+	case ".yaml", ".yml":
+		var value interface{}
+		if err := yaml.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		if value == nil {
+			return nil
+		}
+		converted, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		data = converted
+	default:
+		return fmt.Errorf("unsupported config format %q, use .yaml, .yml or .json", filepath.Ext(path))
+	}
+
+	if err := checkDurations(data); err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(cfg)
+
+	// Name the bad field in the error
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return &ConfigError{Field: typeErr.Field, Message: fmt.Sprintf("cannot use %s as %s", typeErr.Value, typeErr.Type)}
+	}
+	if err != nil && strings.HasPrefix(err.Error(), "json: unknown field ") {
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		return &ConfigError{Field: field, Message: "unknown field"}
+	}
+	return err
+}
+
+// resolvePaths makes the relative file paths of the config relative to dir
+func (c *Config) resolvePaths(dir string) {
+	files := []*string{&c.Auth.NKeyFile, &c.Auth.CredsFile}
+	if c.TLS != nil {
+		files = append(files, &c.TLS.CaFile, &c.TLS.CertFile, &c.TLS.KeyFile, &c.TLS.PKCS12File)
+	}
+	for _, file := range files {
+		if *file != "" && !filepath.IsAbs(*file) {
+			*file = filepath.Join(dir, *file)
+		}
+	}
+}
+
+// durationFields are the Duration fields of Config, checked before decoding
+// because encoding/json does not name the field of a failed UnmarshalJSON
+var durationFields = [][]string{{"reconnect", "wait"}, {"heartbeat", "interval"}}
+
+// checkDurations returns a ConfigError for the first invalid duration in data
+func checkDurations(data []byte) error {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	for _, path := range durationFields {
+		value, ok := interface{}(doc), true
+		for _, key := range path {
+			section, isMap := value.(map[string]interface{})
+			if !isMap {
+				ok = false
+				break
+			}
+			value, ok = section[key]
+			if !ok {
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		raw, _ := json.Marshal(value)
+		var d Duration
+		if err := d.UnmarshalJSON(raw); err != nil {
+			return &ConfigError{Field: strings.Join(path, "."), Message: err.Error()}
+		}
+	}
+	return nil
+}
+
+// envOverrides maps the LIGHTLINK_* variables, without prefix, to the fields they set
+var envOverrides = []struct {
+	name  string
+	apply func(cfg *Config, value string) error
+}{
+	{"NATS_URL", func(c *Config, v string) error { c.NATSURL = v; return nil }},
+	{"SERVERS", func(c *Config, v string) error { c.Servers = splitList(v); return nil }},
+	{"SERVICE_NAME", func(c *Config, v string) error { c.ServiceName = v; return nil }},
+	{"NAME", func(c *Config, v string) error { c.Name = v; return nil }},
+	{"AUTO_TLS", func(c *Config, v string) error { return parseBool(v, &c.AutoTLS) }},
+	{"TLS_CA_FILE", func(c *Config, v string) error { c.tls().CaFile = v; return nil }},
+	{"TLS_CERT_FILE", func(c *Config, v string) error { c.tls().CertFile = v; return nil }},
+	{"TLS_KEY_FILE", func(c *Config, v string) error { c.tls().KeyFile = v; return nil }},
+	{"TLS_SERVER_NAME", func(c *Config, v string) error { c.tls().ServerName = v; return nil }},
+	{"TLS_INSECURE_SKIP_VERIFY", func(c *Config, v string) error { return parseBool(v, &c.tls().InsecureSkipVerify) }},
+	{"TLS_PKCS12_FILE", func(c *Config, v string) error { c.tls().PKCS12File = v; return nil }},
+	{"TLS_PKCS12_PASSWORD", func(c *Config, v string) error { c.tls().PKCS12Password = v; return nil }},
+	{"RECONNECT_WAIT", func(c *Config, v string) error { return parseDuration(v, &c.Reconnect.Wait) }},
+	{"MAX_RECONNECTS", func(c *Config, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("not an integer: %q", v)
+		}
+		c.Reconnect.MaxReconnects = &n
+		return nil
+	}},
+	{"USER", func(c *Config, v string) error { c.Auth.User = v; return nil }},
+	{"PASSWORD", func(c *Config, v string) error { c.Auth.Password = v; return nil }},
+	{"TOKEN", func(c *Config, v string) error { c.Auth.Token = v; return nil }},
+	{"NKEY_FILE", func(c *Config, v string) error { c.Auth.NKeyFile = v; return nil }},
+	{"CREDS_FILE", func(c *Config, v string) error { c.Auth.CredsFile = v; return nil }},
+	{"INBOX_PREFIX", func(c *Config, v string) error { c.InboxPrefix = v; return nil }},
+	{"HEARTBEAT_INTERVAL", func(c *Config, v string) error { return parseDuration(v, &c.Heartbeat.Interval) }},
+}
+
+// ApplyEnv overrides fields with the LIGHTLINK_* environment variables that are set,
+// e.g. LIGHTLINK_NATS_URL, LIGHTLINK_TLS_CERT_FILE or LIGHTLINK_HEARTBEAT_INTERVAL
+func (c *Config) ApplyEnv() error {
+	for _, o := range envOverrides {
+		value, ok := os.LookupEnv(EnvPrefix + o.name)
+		if !ok {
+			continue
+		}
+		if err := o.apply(c, value); err != nil {
+			return &ConfigError{Field: EnvPrefix + o.name, Message: err.Error()}
+		}
+	}
+	return nil
+}
+
+// Validate checks the connection settings. The service name is checked by
+// service.NewServiceFromConfig since clients do not need one.
+func (c *Config) Validate() error {
+	if c.NATSURL == "" && len(c.Servers) == 0 {
+		return &ConfigError{Field: "nats_url", Message: "required when no servers are given"}
+	}
+	if c.NATSURL != "" {
+		if err := validateURL(c.NATSURL); err != nil {
+			return &ConfigError{Field: "nats_url", Message: err.Error()}
+		}
+	}
+	for i, server := range c.Servers {
+		if err := validateURL(server); err != nil {
+			return &ConfigError{Field: fmt.Sprintf("servers[%d]", i), Message: err.Error()}
+		}
+	}
+
+	if c.Reconnect.Wait < 0 {
+		return &ConfigError{Field: "reconnect.wait", Message: "must not be negative"}
+	}
+	if c.Reconnect.MaxReconnects != nil && *c.Reconnect.MaxReconnects < -1 {
+		return &ConfigError{Field: "reconnect.max_reconnects", Message: "must be -1 (forever) or more"}
+	}
+	if c.Heartbeat.Interval < 0 {
+		return &ConfigError{Field: "heartbeat.interval", Message: "must not be negative"}
+	}
+	if c.Heartbeat.Interval > 0 && time.Duration(c.Heartbeat.Interval) < time.Second {
+		return &ConfigError{Field: "heartbeat.interval", Message: "must be at least 1s"}
+	}
+
+	if err := c.validateAuth(); err != nil {
+		return err
+	}
+
+	if c.TLS != nil {
+		if c.AutoTLS {
+			return &ConfigError{Field: "auto_tls", Message: "cannot be combined with tls"}
+		}
+		if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+			return &ConfigError{Field: "tls.key_file", Message: "cert_file and key_file must be set together"}
+		}
+		if c.TLS.PKCS12File != "" && c.TLS.CertFile != "" {
+			return &ConfigError{Field: "tls.pkcs12_file", Message: "cannot be combined with cert_file and key_file"}
+		}
+		if err := checkFile("tls.ca_file", c.TLS.CaFile); err != nil {
+			return err
+		}
+		if err := checkFile("tls.cert_file", c.TLS.CertFile); err != nil {
+			return err
+		}
+		if err := checkFile("tls.key_file", c.TLS.KeyFile); err != nil {
+			return err
+		}
+		if err := checkFile("tls.pkcs12_file", c.TLS.PKCS12File); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateAuth checks that at most one way of authentication is configured
+func (c *Config) validateAuth() error {
+	var methods []string
+	if c.Auth.User != "" {
+		methods = append(methods, "user")
+	}
+	if c.Auth.Token != "" {
+		methods = append(methods, "token")
+	}
+	if c.Auth.NKeyFile != "" {
+		methods = append(methods, "nkey_file")
+	}
+	if c.Auth.CredsFile != "" {
+		methods = append(methods, "creds_file")
+	}
+	if len(methods) > 1 {
+		return &ConfigError{Field: "auth." + methods[1], Message: "use only one of user, token, nkey_file and creds_file"}
+	}
+	if c.Auth.Password != "" && c.Auth.User == "" {
+		return &ConfigError{Field: "auth.user", Message: "required with password"}
+	}
+	if err := checkFile("auth.nkey_file", c.Auth.NKeyFile); err != nil {
+		return err
+	}
+	return checkFile("auth.creds_file", c.Auth.CredsFile)
+}
+
+// tls returns the TLS settings, creating them for environment overrides
+func (c *Config) tls() *TLSConfig {
+	if c.TLS == nil {
+		c.TLS = &TLSConfig{}
+	}
+	return c.TLS
+}
+
+// validateURL checks that u is a NATS server URL
+func validateURL(u string) error {
+	for _, part := range splitList(u) {
+		parsed, err := url.Parse(part)
+		if err != nil {
+			return fmt.Errorf("invalid URL %q: %v", part, err)
+		}
+		switch parsed.Scheme {
+		case "nats", "tls", "ws", "wss":
+		default:
+			return fmt.Errorf("invalid URL %q: scheme must be nats, tls, ws or wss", part)
+		}
+		if parsed.Host == "" {
+			return fmt.Errorf("invalid URL %q: missing host", part)
+		}
+	}
+	return nil
+}
+
+// checkFile returns a ConfigError for field if file is set but missing
+func checkFile(field, file string) error {
+	if file == "" {
+		return nil
+	}
+	if _, err := os.Stat(file); err != nil {
+		return &ConfigError{Field: field, Message: fmt.Sprintf("file %s not found", file)}
+	}
+	return nil
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseBool(value string, dst *bool) error {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("not a boolean: %q", value)
+	}
+	*dst = b
+	return nil
+}
+
+func parseDuration(value string, dst *Duration) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("not a duration: %q", value)
+	}
+	*dst = Duration(d)
+	return nil
+}
@@ -0,0 +1,140 @@
+package types
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigYAML(t *testing.T) {
+	path := writeConfig(t, "lightlink.yaml", `
+nats_url: nats://localhost:4222
+servers: [nats://localhost:4223]
+service_name: math-service
+reconnect:
+  wait: 500ms
+  max_reconnects: -1
+heartbeat:
+  interval: 10s
+metadata:
+  version: v1.2.0
+  tags: [math]
+`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.NATSURL != "nats://localhost:4222" || cfg.ServiceName != "math-service" || len(cfg.Servers) != 1 {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+	if time.Duration(cfg.Reconnect.Wait) != 500*time.Millisecond || *cfg.Reconnect.MaxReconnects != -1 {
+		t.Errorf("Unexpected reconnect config %+v", cfg.Reconnect)
+	}
+	if time.Duration(cfg.Heartbeat.Interval) != 10*time.Second {
+		t.Errorf("Unexpected heartbeat interval %v", cfg.Heartbeat.Interval)
+	}
+	if cfg.Metadata == nil || cfg.Metadata.Version != "v1.2.0" || cfg.Metadata.Tags[0] != "math" {
+		t.Errorf("Unexpected metadata %+v", cfg.Metadata)
+	}
+}
+
+func TestLoadConfigJSONWithEnv(t *testing.T) {
+	path := writeConfig(t, "lightlink.json", `{"nats_url": "nats://localhost:4222", "heartbeat": {"interval": 20}}`)
+	t.Setenv("LIGHTLINK_NATS_URL", "tls://nats.example.com:4222")
+	t.Setenv("LIGHTLINK_SERVICE_NAME", "env-service")
+	t.Setenv("LIGHTLINK_MAX_RECONNECTS", "5")
+	t.Setenv("LIGHTLINK_TLS_SERVER_NAME", "nats.example.com")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.NATSURL != "tls://nats.example.com:4222" || cfg.ServiceName != "env-service" {
+		t.Errorf("Expected environment overrides, got %+v", cfg)
+	}
+	if *cfg.Reconnect.MaxReconnects != 5 || cfg.TLS == nil || cfg.TLS.ServerName != "nats.example.com" {
+		t.Errorf("Unexpected overrides %+v %+v", cfg.Reconnect, cfg.TLS)
+	}
+	if time.Duration(cfg.Heartbeat.Interval) != 20*time.Second {
+		t.Errorf("Expected plain numbers as seconds, got %v", cfg.Heartbeat.Interval)
+	}
+}
+
+func TestLoadConfigRelativePaths(t *testing.T) {
+	path := writeConfig(t, "lightlink.yaml", "nats_url: tls://localhost:4222\ntls:\n  ca_file: certs/ca.crt\n  cert_file: certs/client.crt\n  key_file: certs/client.key\n")
+	dir := filepath.Dir(path)
+	os.MkdirAll(filepath.Join(dir, "certs"), 0755)
+	for _, name := range []string{"ca.crt", "client.crt", "client.key"} {
+		os.WriteFile(filepath.Join(dir, "certs", name), []byte("content"), 0644)
+	}
+	caFile := filepath.Join(t.TempDir(), "env-ca.crt")
+	os.WriteFile(caFile, []byte("content"), 0644)
+	t.Setenv("LIGHTLINK_TLS_CA_FILE", caFile)
+
+	// Loaded from another working directory
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.TLS.CertFile != filepath.Join(dir, "certs", "client.crt") || cfg.TLS.KeyFile != filepath.Join(dir, "certs", "client.key") {
+		t.Errorf("Expected paths relative to the config file, got %+v", cfg.TLS)
+	}
+	if cfg.TLS.CaFile != caFile {
+		t.Errorf("Expected the environment CA file, got %s", cfg.TLS.CaFile)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		env     map[string]string
+		field   string
+	}{
+		{"missing url", "c.yaml", "service_name: x", nil, "nats_url"},
+		{"bad scheme", "c.yaml", "nats_url: http://localhost:4222", nil, "nats_url"},
+		{"bad seed", "c.yaml", "nats_url: nats://a:4222\nservers: [nats://b:4222, 'b:4222']", nil, "servers[1]"},
+		{"unknown field", "c.yaml", "nats_url: nats://a:4222\nnats_ulr: x", nil, "nats_ulr"},
+		{"bad duration", "c.yaml", "nats_url: nats://a:4222\nheartbeat:\n  interval: soon", nil, "heartbeat.interval"},
+		{"bad type", "c.json", `{"nats_url": "nats://a:4222", "reconnect": {"max_reconnects": "many"}}`, nil, "reconnect.max_reconnects"},
+		{"bad max reconnects", "c.yaml", "nats_url: nats://a:4222\nreconnect:\n  max_reconnects: -2", nil, "reconnect.max_reconnects"},
+		{"short heartbeat", "c.yaml", "nats_url: nats://a:4222\nheartbeat:\n  interval: 10ms", nil, "heartbeat.interval"},
+		{"cert without key", "c.yaml", "nats_url: nats://a:4222\ntls:\n  cert_file: client.crt", nil, "tls.key_file"},
+		{"missing CA", "c.yaml", "nats_url: nats://a:4222\ntls:\n  ca_file: /nonexistent/ca.crt", nil, "tls.ca_file"},
+		{"bundle with cert", "c.yaml", "nats_url: nats://a:4222\ntls:\n  cert_file: client.crt\n  key_file: client.key\n  pkcs12_file: client.p12", nil, "tls.pkcs12_file"},
+		{"two auth methods", "c.yaml", "nats_url: nats://a:4222\nauth:\n  user: a\n  token: b", nil, "auth.token"},
+		{"bad env", "c.yaml", "nats_url: nats://a:4222", map[string]string{"LIGHTLINK_HEARTBEAT_INTERVAL": "often"}, "LIGHTLINK_HEARTBEAT_INTERVAL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			_, err := LoadConfig(writeConfig(t, tt.file, tt.content))
+			var cfgErr *ConfigError
+			if !errors.As(err, &cfgErr) {
+				t.Fatalf("Expected ConfigError, got %v", err)
+			}
+			if cfgErr.Field != tt.field {
+				t.Errorf("Expected field %q, got %q (%v)", tt.field, cfgErr.Field, err)
+			}
+		})
+	}
+
+	if _, err := LoadConfig(writeConfig(t, "c.toml", "")); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
@@ -0,0 +1,185 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RPC 错误码，所有 SDK 使用相同的取值
+const (
+	// CodeUnknown is used for errors returned without a code, e.g. plain Go errors of handlers
+	CodeUnknown = "unknown"
+	// CodeInvalidRequest means the request could not be decoded
+	CodeInvalidRequest = "invalid_request"
+	// CodeMethodNotFound means the service has no such method
+	CodeMethodNotFound = "method_not_found"
+	// CodeValidation means the arguments failed parameter validation
+	CodeValidation = "validation_failed"
+	// CodeDeadlineExceeded means the caller's deadline passed before the handler finished
+	CodeDeadlineExceeded = "deadline_exceeded"
+	// CodeNotFound means a requested entity does not exist
+	CodeNotFound = "not_found"
+	// CodeAlreadyExists means the entity to create already exists
+	CodeAlreadyExists = "already_exists"
+	// CodePermissionDenied means the caller may not perform the call
+	CodePermissionDenied = "permission_denied"
+	// CodeUnauthenticated means the caller did not authenticate
+	CodeUnauthenticated = "unauthenticated"
+	// CodeFailedPrecondition means the system is not in a state the call requires
+	CodeFailedPrecondition = "failed_precondition"
+	// CodeResourceExhausted means a quota or rate limit was hit; retryable
+	CodeResourceExhausted = "resource_exhausted"
+	// CodeUnavailable means the service is temporarily unable to handle the call; retryable
+	CodeUnavailable = "unavailable"
+	// CodeInternal means an unexpected failure inside the service
+	CodeInternal = "internal"
+)
+
+// Sentinel errors matching every RPCError of their code with errors.Is
+var (
+	ErrInvalidRequest     = &RPCError{Code: CodeInvalidRequest, Message: "invalid request"}
+	ErrMethodNotFound     = &RPCError{Code: CodeMethodNotFound, Message: "method not found"}
+	ErrValidation         = &RPCError{Code: CodeValidation, Message: "validation failed"}
+	ErrDeadlineExceeded   = &RPCError{Code: CodeDeadlineExceeded, Message: "deadline exceeded"}
+	ErrNotFound           = &RPCError{Code: CodeNotFound, Message: "not found"}
+	ErrAlreadyExists      = &RPCError{Code: CodeAlreadyExists, Message: "already exists"}
+	ErrPermissionDenied   = &RPCError{Code: CodePermissionDenied, Message: "permission denied"}
+	ErrUnauthenticated    = &RPCError{Code: CodeUnauthenticated, Message: "unauthenticated"}
+	ErrFailedPrecondition = &RPCError{Code: CodeFailedPrecondition, Message: "failed precondition"}
+	ErrResourceExhausted  = &RPCError{Code: CodeResourceExhausted, Message: "resource exhausted", Retryable: true}
+	ErrUnavailable        = &RPCError{Code: CodeUnavailable, Message: "unavailable", Retryable: true}
+	ErrInternal           = &RPCError{Code: CodeInternal, Message: "internal error"}
+)
+
+// RPCError RPC 调用的结构化错误
+type RPCError struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+	// Retryable tells callers the same request may succeed when sent again
+	Retryable bool `json:"retryable,omitempty"`
+
+	cause error
+}
+
+// NewRPCError returns an error with code and message. Codes that denote
+// temporary conditions are marked retryable.
+func NewRPCError(code, message string) *RPCError {
+	return &RPCError{Code: code, Message: message, Retryable: IsRetryableCode(code)}
+}
+
+// IsRetryableCode reports whether errors with code are retryable by default
+func IsRetryableCode(code string) bool {
+	return code == CodeUnavailable || code == CodeResourceExhausted
+}
+
+// Error returns the message prefixed with the code
+func (e *RPCError) Error() string {
+	if e.Code == "" || e.Code == CodeUnknown {
+		return e.Message
+	}
+	return e.Code + ": " + e.Message
+}
+
+// Is matches any RPCError with the same code, so errors.Is(err, ErrMethodNotFound) works
+func (e *RPCError) Is(target error) bool {
+	t, ok := target.(*RPCError)
+	return ok && t.Code == e.Code
+}
+
+// Unwrap returns the typed error the RPCError was decoded into, e.g. a *ValidationError
+func (e *RPCError) Unwrap() error {
+	return e.cause
+}
+
+// ToRPCError converts an error returned by a handler into an RPCError.
+// RPCErrors anywhere in the chain are kept, validation errors get
+// CodeValidation and everything else becomes CodeUnknown.
+func ToRPCError(err error) *RPCError {
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return &RPCError{
+			Code:    CodeValidation,
+			Message: validationErr.Error(),
+			Details: validationDetails(validationErr),
+			cause:   validationErr,
+		}
+	}
+	return &RPCError{Code: CodeUnknown, Message: err.Error()}
+}
+
+// NewErrorResponse builds the failure response of a request from err
+func NewErrorResponse(requestID string, err error) RPCResponse {
+	rpcErr := ToRPCError(err)
+	response := RPCResponse{
+		ID:        requestID,
+		Success:   false,
+		Error:     rpcErr.Message,
+		ErrorInfo: rpcErr,
+	}
+	// Validation details also go into Result, where older callers look for them
+	if rpcErr.Code == CodeValidation && rpcErr.Details != nil {
+		response.Result = map[string]interface{}{"type": ValidationErrorType}
+		for k, v := range rpcErr.Details {
+			response.Result[k] = v
+		}
+	}
+	return response
+}
+
+// Err returns the error of a failed response, nil on success.
+// Validation errors unwrap to a *ValidationError. Responses of services that
+// predate error codes are converted from their Error and Result fields.
+func (r *RPCResponse) Err() error {
+	if r.Success {
+		return nil
+	}
+
+	rpcErr := &RPCError{Code: CodeUnknown, Message: r.Error}
+	if r.ErrorInfo != nil {
+		copied := *r.ErrorInfo
+		rpcErr = &copied
+	} else if errType, _ := r.Result["type"].(string); errType == ValidationErrorType {
+		rpcErr.Code = CodeValidation
+		rpcErr.Details = r.Result
+	}
+	if rpcErr.Code == CodeValidation {
+		rpcErr.cause = validationFromDetails(rpcErr.Message, rpcErr.Details)
+	}
+	return rpcErr
+}
+
+// validationDetails returns the details of a validation error
+func validationDetails(err *ValidationError) map[string]interface{} {
+	details := map[string]interface{}{
+		"parameter_name": err.ParameterName,
+		"expected_type":  err.ExpectedType,
+		"actual_type":    err.ActualType,
+		"message":        err.Message,
+	}
+	if err.ActualValue != nil {
+		details["actual_value"] = err.ActualValue
+	}
+	return details
+}
+
+// validationFromDetails rebuilds a validation error from its details
+func validationFromDetails(message string, details map[string]interface{}) *ValidationError {
+	err := &ValidationError{Message: message}
+	err.ParameterName, _ = details["parameter_name"].(string)
+	err.ExpectedType, _ = details["expected_type"].(string)
+	err.ActualType, _ = details["actual_type"].(string)
+	err.ActualValue = details["actual_value"]
+	if msg, ok := details["message"].(string); ok && msg != "" {
+		err.Message = msg
+	}
+	if err.Message == "" {
+		err.Message = fmt.Sprintf("parameter '%s': expected type %s, got %s",
+			err.ParameterName, err.ExpectedType, err.ActualType)
+	}
+	return err
+}
@@ -0,0 +1,105 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestRPCErrorIs(t *testing.T) {
+	err := fmt.Errorf("RPC error: %w", NewRPCError(CodeMethodNotFound, "method not found: add"))
+	if !errors.Is(err, ErrMethodNotFound) {
+		t.Error("Expected error to match ErrMethodNotFound")
+	}
+	if errors.Is(err, ErrValidation) {
+		t.Error("Expected error not to match ErrValidation")
+	}
+
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != CodeMethodNotFound {
+		t.Errorf("Expected errors.As to find the RPCError, got %v", rpcErr)
+	}
+	if rpcErr.Error() != "method_not_found: method not found: add" {
+		t.Errorf("Unexpected message %q", rpcErr.Error())
+	}
+	if !NewRPCError(CodeUnavailable, "draining").Retryable || NewRPCError(CodeInternal, "bug").Retryable {
+		t.Error("Expected only temporary codes to be retryable by default")
+	}
+}
+
+func TestErrorResponseRoundTrip(t *testing.T) {
+	tests := []struct {
+		err      error
+		code     string
+		sentinel error
+	}{
+		{NewRPCError(CodeNotFound, "user 42 not found"), CodeNotFound, ErrNotFound},
+		{fmt.Errorf("load user: %w", NewRPCError(CodePermissionDenied, "admin only")), CodePermissionDenied, ErrPermissionDenied},
+		{errors.New("plain failure"), CodeUnknown, nil},
+		{&ValidationError{ParameterName: "a", ExpectedType: "number", ActualType: "string", Message: "bad a"}, CodeValidation, ErrValidation},
+	}
+
+	for _, tt := range tests {
+		data, err := json.Marshal(NewErrorResponse("req-1", tt.err))
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		var response RPCResponse
+		if err := json.Unmarshal(data, &response); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+
+		got := response.Err()
+		var rpcErr *RPCError
+		if !errors.As(got, &rpcErr) || rpcErr.Code != tt.code {
+			t.Errorf("%v: expected code %s, got %v", tt.err, tt.code, got)
+			continue
+		}
+		if tt.sentinel != nil && !errors.Is(got, tt.sentinel) {
+			t.Errorf("%v: expected to match sentinel %v", tt.err, tt.sentinel)
+		}
+	}
+}
+
+func TestValidationErrorDecoded(t *testing.T) {
+	original := &ValidationError{ParameterName: "a", ExpectedType: "number", ActualType: "string", ActualValue: "x", Message: "bad a"}
+	data, _ := json.Marshal(NewErrorResponse("req-1", original))
+
+	var response RPCResponse
+	json.Unmarshal(data, &response)
+
+	var validationErr *ValidationError
+	if !errors.As(response.Err(), &validationErr) {
+		t.Fatal("Expected errors.As to find a *ValidationError")
+	}
+	if validationErr.ParameterName != "a" || validationErr.ExpectedType != "number" || validationErr.ActualValue != "x" {
+		t.Errorf("Unexpected validation error %+v", validationErr)
+	}
+	// Older callers read the details from Result
+	if response.Result["type"] != ValidationErrorType || response.Result["parameter_name"] != "a" {
+		t.Errorf("Expected validation details in result, got %v", response.Result)
+	}
+}
+
+func TestLegacyResponseErr(t *testing.T) {
+	legacy := RPCResponse{Success: false, Error: "division by zero"}
+	var rpcErr *RPCError
+	if !errors.As(legacy.Err(), &rpcErr) || rpcErr.Code != CodeUnknown || rpcErr.Message != "division by zero" {
+		t.Errorf("Unexpected legacy error %+v", rpcErr)
+	}
+
+	legacyValidation := RPCResponse{
+		Success: false,
+		Error:   "bad a",
+		Result:  map[string]interface{}{"type": ValidationErrorType, "parameter_name": "a"},
+	}
+	var validationErr *ValidationError
+	if !errors.As(legacyValidation.Err(), &validationErr) || validationErr.ParameterName != "a" {
+		t.Errorf("Expected legacy validation error to decode, got %v", legacyValidation.Err())
+	}
+
+	if (&RPCResponse{Success: true}).Err() != nil {
+		t.Error("Expected nil error for successful response")
+	}
+}
@@ -0,0 +1,43 @@
+package types
+
+// RPC 协议使用的 NATS 消息头
+const (
+	// HeaderRequestID carries the request ID of an RPC call
+	HeaderRequestID = "LL-Request-Id"
+	// HeaderTimeout carries the caller's remaining time budget in milliseconds
+	HeaderTimeout = "LL-Timeout"
+	// HeaderIdempotencyKey identifies retries of the same logical request
+	HeaderIdempotencyKey = "LL-Idempotency-Key"
+	// HeaderInstanceKey carries the key of the instance that sent an RPC response
+	HeaderInstanceKey = "LL-Instance-Key"
+	// HeaderContentType names the codec of the payload; missing means JSON
+	HeaderContentType = "Content-Type"
+	// HeaderContentEncoding names the compression of the payload, e.g. gzip or zstd
+	HeaderContentEncoding = "LL-Content-Encoding"
+	// HeaderAcceptEncoding lists the compressions the sender can decompress
+	HeaderAcceptEncoding = "LL-Accept-Encoding"
+	// HeaderTraceparent carries the W3C trace context of the calling span
+	HeaderTraceparent = "traceparent"
+
+	// HeaderStream marks a streaming call and carries the caller's initial credit window
+	HeaderStream = "LL-Stream"
+	// HeaderStreamSeq carries the sequence number of a stream frame, starting at 0 for the open frame
+	HeaderStreamSeq = "LL-Stream-Seq"
+	// HeaderStreamEnd marks the last frame of a stream
+	HeaderStreamEnd = "LL-Stream-End"
+	// HeaderStreamAck carries the subject the caller sends credits and cancellation to
+	HeaderStreamAck = "LL-Stream-Ack"
+	// HeaderStreamCredit carries the number of frames the caller is ready to receive
+	HeaderStreamCredit = "LL-Stream-Credit"
+	// HeaderStreamCancel tells the service the caller has closed the stream
+	HeaderStreamCancel = "LL-Stream-Cancel"
+
+	// HeaderDeadLetterSubject carries the subject a dead-lettered message was published on
+	HeaderDeadLetterSubject = "LL-Dead-Letter-Subject"
+	// HeaderDeadLetterSeq carries the stream sequence of a dead-lettered durable message
+	HeaderDeadLetterSeq = "LL-Dead-Letter-Seq"
+	// HeaderDeadLetterDeliveries carries how often the message was delivered before it was given up
+	HeaderDeadLetterDeliveries = "LL-Dead-Letter-Deliveries"
+	// HeaderDeadLetterReason tells why the message was dead-lettered
+	HeaderDeadLetterReason = "LL-Dead-Letter-Reason"
+)
@@ -28,7 +28,7 @@ type MethodMetadata struct {
 // ParameterMetadata 参数元数据
 type ParameterMetadata struct {
 	Name        string `json:"name"`
-	Type        string `json:"type"` // string, number, boolean, array, object
+	Type        string `json:"type"` // string, number, boolean, array, object, any
 	Required    bool   `json:"required"`
 	Description string `json:"description"`
 	Default     any    `json:"default,omitempty"`
@@ -59,9 +59,10 @@ type RegisterMessage struct {
 
 // HeartbeatMessage 心跳消息
 type HeartbeatMessage struct {
-	Service   string `json:"service"`
-	Version   string `json:"version"`
-	Timestamp int64  `json:"timestamp"`
+	Service   string           `json:"service"`
+	Version   string           `json:"version"`
+	Timestamp int64            `json:"timestamp"`
+	Metrics   *MetricsSnapshot `json:"metrics,omitempty"` // 启用 WithHeartbeatMetrics 时上报
 }
 
 // InstanceInfo 实例信息
@@ -70,13 +71,18 @@ type InstanceInfo struct {
 	HostIP     string `json:"host_ip"`
 	HostMAC    string `json:"host_mac"`
 	WorkingDir string `json:"working_dir"`
+	Weight     int    `json:"weight,omitempty"`      // 流量权重，越大分到的请求越多
+	Draining   bool   `json:"draining,omitempty"`    // 正在排空，不再接收新请求
+	Broadcast  bool   `json:"broadcast,omitempty"`   // 广播模式，每个实例都处理每个请求
+	CertExpiry int64  `json:"cert_expiry,omitempty"` // TLS 客户端证书到期时间（Unix 秒），未使用 TLS 时为 0
 }
 
 // ControlMessage 控制消息
 type ControlMessage struct {
 	Service     string `json:"service"`
 	InstanceKey string `json:"instance_key"`
-	Command     string `json:"command"` // stop, restart
+	Command     string `json:"command"` // stop, restart, drain, resume, set_weight
+	Weight      int    `json:"weight,omitempty"`
 	Timestamp   int64  `json:"timestamp"`
 }
 
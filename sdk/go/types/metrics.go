@@ -0,0 +1,35 @@
+package types
+
+// MetricsSnapshot 服务运行指标快照，可随心跳上报
+type MetricsSnapshot struct {
+	// Methods maps method names to their RPC metrics
+	Methods           map[string]*MethodMetrics `json:"methods"`
+	Heartbeats        uint64                    `json:"heartbeats"`
+	HeartbeatFailures uint64                    `json:"heartbeat_failures"`
+	Disconnects       uint64                    `json:"disconnects"`
+	Reconnects        uint64                    `json:"reconnects"`
+}
+
+// MethodMetrics RPC 方法指标
+type MethodMetrics struct {
+	Requests uint64 `json:"requests"`
+	// Errors counts failed requests by error type, e.g. validation or handler
+	Errors   map[string]uint64 `json:"errors,omitempty"`
+	InFlight int64             `json:"in_flight"`
+	// Latency is the latency histogram of completed requests
+	Latency Histogram `json:"latency"`
+}
+
+// Histogram 延迟直方图，单位为秒
+type Histogram struct {
+	// Buckets holds cumulative counts of observations <= UpperBound, ascending
+	Buckets []HistogramBucket `json:"buckets"`
+	Count   uint64            `json:"count"`
+	Sum     float64           `json:"sum"`
+}
+
+// HistogramBucket 直方图桶
+type HistogramBucket struct {
+	UpperBound float64 `json:"le"`
+	Count      uint64  `json:"count"`
+}
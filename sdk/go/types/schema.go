@@ -0,0 +1,107 @@
+package types
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// 结构体标签约定:
+//   json:"name"      参数/返回值名称 (与 JSON 编码一致)
+//   desc:"..."       描述
+//   required:"true"  必需参数
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// ParamsFromStruct derives parameter metadata from the fields of a struct type
+func ParamsFromStruct(t reflect.Type) []ParameterMetadata {
+	var params []ParameterMetadata
+	walkStructFields(t, func(name string, field reflect.StructField) {
+		params = append(params, ParameterMetadata{
+			Name:        name,
+			Type:        JSONTypeOf(field.Type),
+			Required:    field.Tag.Get("required") == "true",
+			Description: field.Tag.Get("desc"),
+		})
+	})
+	return params
+}
+
+// ReturnsFromStruct derives return value metadata from the fields of a struct type
+func ReturnsFromStruct(t reflect.Type) []ReturnMetadata {
+	var returns []ReturnMetadata
+	walkStructFields(t, func(name string, field reflect.StructField) {
+		returns = append(returns, ReturnMetadata{
+			Name:        name,
+			Type:        JSONTypeOf(field.Type),
+			Description: field.Tag.Get("desc"),
+		})
+	})
+	return returns
+}
+
+// JSONTypeOf returns the metadata type name (string, number, boolean, array,
+// object) of a Go type; "any" for interface types, which accept every value
+func JSONTypeOf(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return "string"
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		// []byte is encoded as a base64 string by encoding/json
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "string"
+		}
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Interface:
+		return "any"
+	default:
+		return "unknown"
+	}
+}
+
+// walkStructFields calls fn for every JSON-visible field of a struct type,
+// flattening embedded structs the same way encoding/json does
+func walkStructFields(t reflect.Type, fn func(name string, field reflect.StructField)) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			walkStructFields(field.Type, fn)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fn(name, field)
+	}
+}
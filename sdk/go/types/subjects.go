@@ -0,0 +1,73 @@
+package types
+
+import "strings"
+
+// NATS 主题约定
+const (
+	// ServiceRPCPrefix is the subject prefix of load-balanced RPC calls: $SRV.<service>.<method>
+	ServiceRPCPrefix = "$SRV"
+	// InstanceRPCPrefix is the subject prefix of instance-addressed RPC calls: $LL.instance.<token>.<method>
+	InstanceRPCPrefix = "$LL.instance"
+	// BroadcastRPCPrefix is the subject prefix of scatter-gather RPC calls: $LL.broadcast.<service>.<method>
+	BroadcastRPCPrefix = "$LL.broadcast"
+	// CancelSubjectPrefix is the subject prefix of cancel notices for running RPC calls: $LL.cancel.<request id>
+	CancelSubjectPrefix = "$LL.cancel"
+	// TraceSpansSubject is where services report finished trace spans to the manager
+	TraceSpansSubject = "$LL.trace.spans"
+	// DurableSubjectPrefix is the subject prefix of durable messages stored in JetStream: $LL.durable.<subject>
+	DurableSubjectPrefix = "$LL.durable"
+)
+
+// ServiceRPCSubject returns the subject of a load-balanced RPC call
+func ServiceRPCSubject(service, method string) string {
+	return ServiceRPCPrefix + "." + service + "." + method
+}
+
+// InstanceRPCSubject returns the subject addressing one service instance
+func InstanceRPCSubject(instanceKey, method string) string {
+	return InstanceRPCPrefix + "." + InstanceSubjectToken(instanceKey) + "." + method
+}
+
+// BroadcastRPCSubject returns the subject every instance of a service answers
+func BroadcastRPCSubject(service, method string) string {
+	return BroadcastRPCPrefix + "." + service + "." + method
+}
+
+// CancelSubject returns the subject a caller publishes to when it gives up on
+// the RPC request with requestID before the response arrived
+func CancelSubject(requestID string) string {
+	return CancelSubjectPrefix + "." + InstanceSubjectToken(requestID)
+}
+
+// DurableSubject returns the subject a durable message published on subject is stored under
+func DurableSubject(subject string) string {
+	return DurableSubjectPrefix + "." + subject
+}
+
+// MatchSubject reports whether subject matches pattern, which may hold the
+// NATS wildcards * (one token) and > (one or more trailing tokens)
+func MatchSubject(pattern, subject string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	subjectTokens := strings.Split(subject, ".")
+	for i, token := range patternTokens {
+		if token == ">" {
+			return len(subjectTokens) > i
+		}
+		if i >= len(subjectTokens) || (token != "*" && token != subjectTokens[i]) {
+			return false
+		}
+	}
+	return len(patternTokens) == len(subjectTokens)
+}
+
+// InstanceSubjectToken converts an instance key (ip:mac:service) into a single
+// NATS subject token by replacing separators and wildcards with underscores
+func InstanceSubjectToken(instanceKey string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '.', ':', '*', '>', ' ', '\t', '\r', '\n':
+			return '_'
+		}
+		return r
+	}, instanceKey)
+}
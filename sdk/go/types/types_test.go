@@ -3,6 +3,7 @@ package types
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -156,3 +157,98 @@ func TestFileExists(t *testing.T) {
 		t.Error("Expected directory to return false")
 	}
 }
+
+func TestParamsFromStruct(t *testing.T) {
+	type base struct {
+		TraceID string `json:"trace_id" desc:"Trace identifier"`
+	}
+	type request struct {
+		base
+		A       float64           `json:"a" required:"true" desc:"First number"`
+		Tags    []string          `json:"tags,omitempty"`
+		Options map[string]string `json:"options"`
+		Enabled *bool             `json:"enabled"`
+		Extra   interface{}       `json:"extra"`
+		Ignored string            `json:"-"`
+		hidden  string
+	}
+
+	params := ParamsFromStruct(reflect.TypeOf(request{}))
+	want := []ParameterMetadata{
+		{Name: "trace_id", Type: "string", Description: "Trace identifier"},
+		{Name: "a", Type: "number", Required: true, Description: "First number"},
+		{Name: "tags", Type: "array"},
+		{Name: "options", Type: "object"},
+		{Name: "enabled", Type: "boolean"},
+		{Name: "extra", Type: "any"},
+	}
+	if !reflect.DeepEqual(params, want) {
+		t.Errorf("ParamsFromStruct() = %+v, want %+v", params, want)
+	}
+}
+
+func TestReturnsFromStruct(t *testing.T) {
+	type response struct {
+		Sum  int    `json:"sum" desc:"The sum"`
+		Note string `json:"note,omitempty"`
+	}
+
+	returns := ReturnsFromStruct(reflect.TypeOf(&response{}))
+	want := []ReturnMetadata{
+		{Name: "sum", Type: "number", Description: "The sum"},
+		{Name: "note", Type: "string"},
+	}
+	if !reflect.DeepEqual(returns, want) {
+		t.Errorf("ReturnsFromStruct() = %+v, want %+v", returns, want)
+	}
+
+	if got := ReturnsFromStruct(reflect.TypeOf(42)); got != nil {
+		t.Errorf("Expected nil returns for non-struct type, got %+v", got)
+	}
+}
+
+func TestInstanceRPCSubject(t *testing.T) {
+	key := "192.168.1.100:aabbccddeeff:math-service"
+
+	if got := InstanceSubjectToken(key); got != "192_168_1_100_aabbccddeeff_math-service" {
+		t.Errorf("InstanceSubjectToken(%q) = %q", key, got)
+	}
+	if got := InstanceRPCSubject(key, "add"); got != "$LL.instance.192_168_1_100_aabbccddeeff_math-service.add" {
+		t.Errorf("InstanceRPCSubject() = %q", got)
+	}
+	if got := ServiceRPCSubject("math-service", "add"); got != "$SRV.math-service.add" {
+		t.Errorf("ServiceRPCSubject() = %q", got)
+	}
+}
+
+func TestBroadcastRPCSubject(t *testing.T) {
+	if got := BroadcastRPCSubject("cache", "stats"); got != "$LL.broadcast.cache.stats" {
+		t.Errorf("Unexpected broadcast subject: %s", got)
+	}
+}
+
+func TestDurableSubject(t *testing.T) {
+	if got := DurableSubject("orders.created"); got != "$LL.durable.orders.created" {
+		t.Errorf("Unexpected durable subject: %s", got)
+	}
+}
+
+func TestMatchSubject(t *testing.T) {
+	tests := []struct {
+		pattern, subject string
+		want             bool
+	}{
+		{"orders.created", "orders.created", true},
+		{"orders.*", "orders.created", true},
+		{"orders.*", "orders.created.eu", false},
+		{"orders.>", "orders.created.eu", true},
+		{"orders.>", "orders", false},
+		{"*.dead", "orders.dead", true},
+		{"orders.created", "orders.paid", false},
+	}
+	for _, tt := range tests {
+		if got := MatchSubject(tt.pattern, tt.subject); got != tt.want {
+			t.Errorf("MatchSubject(%q, %q) = %v, want %v", tt.pattern, tt.subject, got, tt.want)
+		}
+	}
+}